@@ -0,0 +1,193 @@
+package commander
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/eagleql/xray-core/common/platform/filesystem"
+)
+
+const (
+	scopeAdmin = "admin"
+	scopeRead  = "read"
+)
+
+// Build builds the TLS config of the gRPC server.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	cert, err := filesystem.ReadFile(c.CertificateFile)
+	if err != nil {
+		return nil, newError("failed to read certificate file ", c.CertificateFile).Base(err)
+	}
+	key, err := filesystem.ReadFile(c.KeyFile)
+	if err != nil {
+		return nil, newError("failed to read key file ", c.KeyFile).Base(err)
+	}
+	keyPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, newError("failed to parse key pair").Base(err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCaFile != "" {
+		ca, err := filesystem.ReadFile(c.ClientCaFile)
+		if err != nil {
+			return nil, newError("failed to read client CA file ", c.ClientCaFile).Base(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, newError("failed to parse client CA file ", c.ClientCaFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// authenticator checks the credential and the permission of each call to the gRPC server.
+type authenticator struct {
+	credentials []*Credential
+}
+
+func newAuthenticator(credentials []*Credential) (*authenticator, error) {
+	for _, c := range credentials {
+		if c.Token == "" && c.CommonName == "" {
+			return nil, newError("credential has neither token nor common name")
+		}
+		if len(c.Scope) == 0 {
+			return nil, newError("credential has no scope")
+		}
+	}
+	return &authenticator{credentials: credentials}, nil
+}
+
+func (a *authenticator) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if fromGateway(ctx) {
+		return handler(ctx, req)
+	}
+	if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if fromGateway(ss.Context()) {
+		return handler(srv, ss)
+	}
+	if err := a.authorize(ss.Context(), info.FullMethod, nil); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize returns an error if the caller of the method is not authenticated or is not permitted to call it.
+func (a *authenticator) authorize(ctx context.Context, fullMethod string, req interface{}) error {
+	var scopes []string
+	authenticated := false
+	token := tokenFromContext(ctx)
+	commonName := commonNameFromContext(ctx)
+	for _, c := range a.credentials {
+		if c.Token != "" && (token == "" || subtle.ConstantTimeCompare([]byte(c.Token), []byte(token)) != 1) {
+			continue
+		}
+		if c.CommonName != "" && c.CommonName != commonName {
+			continue
+		}
+		authenticated = true
+		scopes = append(scopes, c.Scope...)
+	}
+	if !authenticated {
+		return status.Error(codes.Unauthenticated, "invalid credential")
+	}
+
+	service, method := splitMethod(fullMethod)
+	readOnly := isReadOnly(method, req)
+	for _, scope := range scopes {
+		if permits(scope, service, readOnly) {
+			return nil
+		}
+	}
+	newError("permission denied to call ", fullMethod).AtWarning().WriteToLog()
+	return status.Error(codes.PermissionDenied, "permission denied")
+}
+
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			return v[7:]
+		}
+	}
+	return ""
+}
+
+func commonNameFromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// splitMethod returns the short name of the service in lower case and the method of a full gRPC method name,
+// e.g. "statsservice" and "GetStats" for "/xray.app.stats.command.StatsService/GetStats".
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	idx := strings.LastIndexByte(fullMethod, '/')
+	if idx == -1 {
+		return "", fullMethod
+	}
+	service := fullMethod[:idx]
+	if i := strings.LastIndexByte(service, '.'); i != -1 {
+		service = service[i+1:]
+	}
+	return strings.ToLower(service), fullMethod[idx+1:]
+}
+
+// isReadOnly returns whether the method doesn't change the state of Xray.
+func isReadOnly(method string, req interface{}) bool {
+	if r, ok := req.(interface{ GetReset_() bool }); ok && r.GetReset_() {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(method, "Get"), strings.HasPrefix(method, "List"), strings.HasPrefix(method, "Query"):
+		return true
+	case method == "ServerReflectionInfo":
+		return true
+	default:
+		return false
+	}
+}
+
+func permits(scope string, service string, readOnly bool) bool {
+	scope = strings.ToLower(scope)
+	switch scope {
+	case scopeAdmin:
+		return true
+	case scopeRead:
+		return readOnly
+	}
+	if s := strings.TrimSuffix(scope, ":"+scopeRead); s != scope {
+		return s == service && readOnly
+	}
+	return scope == service
+}
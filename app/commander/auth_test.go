@@ -0,0 +1,59 @@
+package commander
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	stats "github.com/eagleql/xray-core/app/stats/command"
+)
+
+func TestAuthenticator(t *testing.T) {
+	auth, err := newAuthenticator([]*Credential{
+		{Token: "admin-token", Scope: []string{"admin"}},
+		{Token: "stats-token", Scope: []string{"StatsService:read"}},
+		{Token: "reader-token", Scope: []string{"read"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	cases := []struct {
+		token  string
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"admin-token", "/xray.app.proxyman.command.HandlerService/AddInbound", nil, codes.OK},
+		{"stats-token", "/xray.app.stats.command.StatsService/GetStats", &stats.GetStatsRequest{}, codes.OK},
+		{"stats-token", "/v2ray.core.app.stats.command.StatsService/QueryStats", &stats.QueryStatsRequest{}, codes.OK},
+		{"stats-token", "/xray.app.stats.command.StatsService/QueryStats", &stats.QueryStatsRequest{Reset_: true}, codes.PermissionDenied},
+		{"stats-token", "/xray.app.proxyman.command.HandlerService/ListInbounds", nil, codes.PermissionDenied},
+		{"reader-token", "/xray.app.proxyman.command.HandlerService/ListInbounds", nil, codes.OK},
+		{"reader-token", "/xray.app.proxyman.command.HandlerService/RemoveInbound", nil, codes.PermissionDenied},
+		{"wrong-token", "/xray.app.stats.command.StatsService/GetStats", nil, codes.Unauthenticated},
+		{"", "/xray.app.stats.command.StatsService/GetStats", nil, codes.Unauthenticated},
+	}
+	for _, c := range cases {
+		err := auth.authorize(withToken(c.token), c.method, c.req)
+		if code := status.Code(err); code != c.code {
+			t.Errorf("%s calling %s: expect %s, but got %s", c.token, c.method, c.code, code)
+		}
+	}
+}
+
+func TestAuthenticatorInvalidCredential(t *testing.T) {
+	if _, err := newAuthenticator([]*Credential{{Scope: []string{"admin"}}}); err == nil {
+		t.Error("expect error for credential without token or common name")
+	}
+	if _, err := newAuthenticator([]*Credential{{Token: "token"}}); err == nil {
+		t.Error("expect error for credential without scope")
+	}
+}
@@ -4,13 +4,17 @@ package commander
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
+	"net/http"
 	"sync"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/eagleql/xray-core/common"
-	"github.com/eagleql/xray-core/common/signal/done"
 	core "github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/outbound"
 )
@@ -22,18 +26,49 @@ type Commander struct {
 	services []Service
 	ohm      outbound.Manager
 	tag      string
+	options  []grpc.ServerOption
+
+	tlsConfig     *tls.Config
+	auth          *authenticator
+	gatewayConfig *GatewayConfig
+	gateway       *gateway
+	httpServers   []*http.Server
 }
 
 // NewCommander creates a new Commander based on the given config.
 func NewCommander(ctx context.Context, config *Config) (*Commander, error) {
 	c := &Commander{
-		tag: config.Tag,
+		tag:           config.Tag,
+		gatewayConfig: config.Gateway,
 	}
 
 	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager) {
 		c.ohm = om
 	}))
 
+	if config.Tls != nil {
+		tlsConfig, err := config.Tls.Build()
+		if err != nil {
+			return nil, newError("failed to build TLS config").Base(err)
+		}
+		c.tlsConfig = tlsConfig
+		c.options = append(c.options, grpc.Creds(serverCredentials{TransportCredentials: credentials.NewTLS(tlsConfig)}))
+	}
+	if len(config.Credential) > 0 {
+		auth, err := newAuthenticator(config.Credential)
+		if err != nil {
+			return nil, err
+		}
+		c.auth = auth
+		c.options = append(c.options, grpc.UnaryInterceptor(auth.UnaryInterceptor), grpc.StreamInterceptor(auth.StreamInterceptor))
+	}
+	if config.Gateway != nil && config.Gateway.Listen == "" && config.Tls != nil {
+		return nil, newError("gateway on the API inbound is not supported with TLS, set a listen address for it")
+	}
+	if config.Gateway != nil && config.Gateway.Listen != "" && c.auth == nil && !isLoopback(config.Gateway.Listen) {
+		return nil, newError("gateway listening on ", config.Gateway.Listen, " must have credentials, or listen on a loopback address")
+	}
+
 	for _, rawConfig := range config.Service {
 		config, err := rawConfig.GetInstance()
 		if err != nil {
@@ -61,19 +96,22 @@ func (c *Commander) Type() interface{} {
 // Start implements common.Runnable.
 func (c *Commander) Start() error {
 	c.Lock()
-	c.server = grpc.NewServer()
+	c.server = grpc.NewServer(c.options...)
 	for _, service := range c.services {
 		service.Register(c.server)
 	}
 	c.Unlock()
 
-	listener := &OutboundListener{
-		buffer: make(chan net.Conn, 4),
-		done:   done.New(),
+	listener := newOutboundListener()
+	grpcListener := listener
+	if c.gatewayConfig != nil {
+		if err := c.startGateway(listener, &grpcListener); err != nil {
+			return err
+		}
 	}
 
 	go func() {
-		if err := c.server.Serve(listener); err != nil {
+		if err := c.server.Serve(grpcListener); err != nil {
 			newError("failed to start grpc server").Base(err).AtError().WriteToLog()
 		}
 	}()
@@ -88,6 +126,61 @@ func (c *Commander) Start() error {
 	})
 }
 
+// startGateway starts the HTTP gateway. If the gateway shares the API inbound, the gRPC connections from the listener
+// are passed to grpcListener.
+func (c *Commander) startGateway(listener *OutboundListener, grpcListener **OutboundListener) error {
+	g, err := newGateway(c.server, c.auth)
+	if err != nil {
+		return err
+	}
+	g.Start()
+
+	server := &http.Server{Handler: g}
+	if c.gatewayConfig.Listen == "" {
+		httpListener := newOutboundListener()
+		*grpcListener = newOutboundListener()
+		go splitListener(listener, *grpcListener, httpListener)
+		go server.Serve(httpListener)
+	} else {
+		l, err := net.Listen("tcp", c.gatewayConfig.Listen)
+		if err != nil {
+			g.Close()
+			return newError("failed to listen on ", c.gatewayConfig.Listen).Base(err)
+		}
+		if c.tlsConfig != nil {
+			tlsConfig := c.tlsConfig.Clone()
+			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+			l = tls.NewListener(l, tlsConfig)
+			if err := http2.ConfigureServer(server, nil); err != nil {
+				newError("failed to enable HTTP/2 for gateway").Base(err).AtWarning().WriteToLog()
+			}
+		} else {
+			server.Handler = h2c.NewHandler(g, &http2.Server{})
+		}
+		go server.Serve(l)
+		newError("API gateway listening on ", c.gatewayConfig.Listen).AtInfo().WriteToLog()
+	}
+
+	c.Lock()
+	c.gateway = g
+	c.httpServers = append(c.httpServers, server)
+	c.Unlock()
+	return nil
+}
+
+// isLoopback returns whether the host of the address only accepts local connections.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // Close implements common.Closable.
 func (c *Commander) Close() error {
 	c.Lock()
@@ -97,6 +190,14 @@ func (c *Commander) Close() error {
 		c.server.Stop()
 		c.server = nil
 	}
+	for _, server := range c.httpServers {
+		server.Close()
+	}
+	c.httpServers = nil
+	if c.gateway != nil {
+		c.gateway.Close()
+		c.gateway = nil
+	}
 
 	return nil
 }
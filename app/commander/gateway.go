@@ -0,0 +1,274 @@
+package commander
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const maxGatewayRequestSize = 4 << 20
+
+// http2Preface is the first bytes of HTTP/2 connections, which are used by gRPC.
+const http2Preface = "PRI * HTTP/2.0"
+
+// gateway serves the unary methods of the gRPC services as JSON over HTTP.
+// A request is a POST to the full name of the method, e.g. /xray.app.stats.command.StatsService/QueryStats,
+// with the request message encoded by protojson as its body. The response message is encoded in the same way.
+type gateway struct {
+	auth     *authenticator
+	server   *grpc.Server
+	listener *OutboundListener
+	conn     *grpc.ClientConn
+}
+
+// newGateway creates a gateway calling the services of the gRPC server through connections only made by itself.
+// Authentication is done by the gateway, as the TLS state of the HTTP request is not passed to the gRPC server, so
+// the server must skip TLS and authentication on these connections, see gatewayConn.
+func newGateway(server *grpc.Server, auth *authenticator) (*gateway, error) {
+	g := &gateway{
+		auth:     auth,
+		server:   server,
+		listener: newOutboundListener(),
+	}
+
+	conn, err := grpc.Dial("gateway", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		local, remote := net.Pipe()
+		g.listener.add(&gatewayConn{Conn: remote})
+		return local, nil
+	}))
+	if err != nil {
+		return nil, newError("failed to dial gateway server").Base(err)
+	}
+	g.conn = conn
+	return g, nil
+}
+
+func (g *gateway) Start() {
+	go func() {
+		if err := g.server.Serve(g.listener); err != nil {
+			newError("failed to serve gateway connections").Base(err).AtError().WriteToLog()
+		}
+	}()
+}
+
+func (g *gateway) Close() error {
+	g.conn.Close()
+	return g.listener.Close()
+}
+
+// gatewayConn is a connection from the gateway to the gRPC server. Its calls have been authorized by the gateway.
+type gatewayConn struct {
+	net.Conn
+}
+
+// gatewayAddr is the remote address of gatewayConn, by which the gRPC server recognizes the calls of the gateway.
+type gatewayAddr struct{}
+
+func (gatewayAddr) Network() string { return "pipe" }
+func (gatewayAddr) String() string  { return "gateway" }
+
+func (c *gatewayConn) RemoteAddr() net.Addr {
+	return gatewayAddr{}
+}
+
+// fromGateway returns whether the gRPC call of ctx is made by the gateway.
+func fromGateway(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = p.Addr.(gatewayAddr)
+	return ok
+}
+
+// serverCredentials are the TLS credentials of the gRPC server, which skip the handshake on gatewayConn.
+type serverCredentials struct {
+	credentials.TransportCredentials
+}
+
+func (c serverCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if _, ok := conn.(*gatewayConn); ok {
+		return conn, nil, nil
+	}
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+func (c serverCredentials) Clone() credentials.TransportCredentials {
+	return serverCredentials{TransportCredentials: c.TransportCredentials.Clone()}
+}
+
+// ServeHTTP implements http.Handler.
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeGatewayError(w, status.Error(codes.Unimplemented, "method must be POST"))
+		return
+	}
+
+	fullMethod := r.URL.Path
+	method, err := findMethod(fullMethod)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	inType, err := protoregistry.GlobalTypes.FindMessageByName(method.Input().FullName())
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	outType, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxGatewayRequestSize))
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	in := inType.New().Interface()
+	if len(body) > 0 {
+		if err := protojson.Unmarshal(body, in); err != nil {
+			writeGatewayError(w, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if g.auth != nil {
+		authCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", r.Header.Get("Authorization")))
+		if r.TLS != nil {
+			authCtx = peer.NewContext(authCtx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+		}
+		if err := g.auth.authorize(authCtx, fullMethod, in); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+	}
+
+	out := outType.New().Interface()
+	if err := g.conn.Invoke(ctx, fullMethod, in, out); err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	b, err := protojson.Marshal(out)
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// findMethod finds the descriptor of a unary method by its full name, e.g. /xray.app.stats.command.StatsService/QueryStats.
+func findMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	idx := strings.LastIndexByte(name, '/')
+	if idx == -1 {
+		return nil, status.Error(codes.NotFound, "invalid method "+fullMethod)
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name[:idx]))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "unknown service "+name[:idx])
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service "+name[:idx])
+	}
+	method := service.Methods().ByName(protoreflect.Name(name[idx+1:]))
+	if method == nil {
+		return nil, status.Error(codes.NotFound, "unknown method "+fullMethod)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, status.Error(codes.Unimplemented, "streaming method "+fullMethod+" is not supported")
+	}
+	return method, nil
+}
+
+func writeGatewayError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(s.Code()))
+	json.NewEncoder(w).Encode(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{
+		Code:    s.Code().String(),
+		Message: s.Message(),
+	})
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// bufferedConn is a net.Conn whose first bytes have been peeked.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// splitListener passes the gRPC connections accepted by the listener to grpcListener, and other ones to httpListener.
+func splitListener(listener, grpcListener, httpListener *OutboundListener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			reader := bufio.NewReader(conn)
+			prefix, err := reader.Peek(len(http2Preface))
+			if err != nil {
+				conn.Close()
+				return
+			}
+			c := &bufferedConn{Conn: conn, reader: reader}
+			if string(prefix) == http2Preface {
+				grpcListener.add(c)
+			} else {
+				httpListener.add(c)
+			}
+		}()
+	}
+}
@@ -0,0 +1,109 @@
+package commander
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/eagleql/xray-core/app/stats"
+	statscmd "github.com/eagleql/xray-core/app/stats/command"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/protocol/tls/cert"
+)
+
+type statsService struct {
+	manager *stats.Manager
+}
+
+func (s *statsService) Register(server *grpc.Server) {
+	statscmd.RegisterStatsServiceServer(server, statscmd.NewStatsServer(s.manager))
+}
+
+func TestGateway(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	c, err := m.RegisterCounter("inbound>>>api>>>traffic>>>uplink")
+	common.Must(err)
+	c.Set(10)
+
+	auth, err := newAuthenticator([]*Credential{{Token: "stats-token", Scope: []string{"StatsService:read"}}})
+	common.Must(err)
+	certificate, err := tls.X509KeyPair(cert.MustGenerate(nil).ToPEM())
+	common.Must(err)
+	server := grpc.NewServer(
+		grpc.Creds(serverCredentials{TransportCredentials: credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{certificate}})}),
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor),
+	)
+	defer server.Stop()
+	(&statsService{manager: m}).Register(server)
+
+	g, err := newGateway(server, auth)
+	common.Must(err)
+	g.Start()
+	defer g.Close()
+
+	call := func(method, path, token, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		common.Must(json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	code, resp := call(http.MethodPost, "/xray.app.stats.command.StatsService/GetStats", "stats-token", `{"name":"inbound>>>api>>>traffic>>>uplink"}`)
+	if code != http.StatusOK {
+		t.Fatal("expect status 200, but got ", code, " ", resp)
+	}
+	if stat, ok := resp["stat"].(map[string]interface{}); !ok || stat["value"] != "10" {
+		t.Error("unexpected response ", resp)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		code   int
+	}{
+		{http.MethodGet, "/xray.app.stats.command.StatsService/GetStats", "stats-token", "", http.StatusNotImplemented},
+		{http.MethodPost, "/xray.app.stats.command.StatsService/GetStats", "", "{}", http.StatusUnauthorized},
+		{http.MethodPost, "/xray.app.stats.command.StatsService/QueryStats", "stats-token", `{"reset":true}`, http.StatusForbidden},
+		{http.MethodPost, "/xray.app.stats.command.StatsService/Unknown", "stats-token", "{}", http.StatusNotFound},
+		{http.MethodPost, "/xray.app.stats.command.StatsService/GetStats", "stats-token", "{", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if code, resp := call(c.method, c.path, c.token, c.body); code != c.code {
+			t.Errorf("%s %s: expect status %d, but got %d %v", c.method, c.path, c.code, code, resp)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1:8081": true,
+		"[::1]:8081":     true,
+		"localhost:8081": true,
+		"0.0.0.0:8081":   false,
+		":8081":          false,
+		"[::]:8081":      false,
+		"10.0.0.1:8081":  false,
+		"example.com:80": false,
+	}
+	for address, expected := range cases {
+		if r := isLoopback(address); r != expected {
+			t.Error("expect ", expected, " for ", address, ", but got ", r)
+		}
+	}
+}
@@ -17,6 +17,13 @@ type OutboundListener struct {
 	done   *done.Instance
 }
 
+func newOutboundListener() *OutboundListener {
+	return &OutboundListener{
+		buffer: make(chan net.Conn, 4),
+		done:   done.New(),
+	}
+}
+
 func (l *OutboundListener) add(conn net.Conn) {
 	select {
 	case l.buffer <- conn:
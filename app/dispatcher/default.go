@@ -4,6 +4,7 @@ package dispatcher
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/eagleql/xray-core/common/log"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/ratelimit"
 	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/dns"
@@ -94,6 +96,10 @@ type DefaultDispatcher struct {
 	router routing.Router
 	policy policy.Manager
 	stats  stats.Manager
+
+	access   sync.Mutex
+	limiters map[string]*sharedLimiter
+	conns    *connectionTracker
 }
 
 func init() {
@@ -114,6 +120,8 @@ func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router rou
 	d.router = router
 	d.policy = pm
 	d.stats = sm
+	d.limiters = make(map[string]*sharedLimiter)
+	d.conns = newConnectionTracker()
 	return nil
 }
 
@@ -130,11 +138,47 @@ func (*DefaultDispatcher) Start() error {
 // Close implements common.Closable.
 func (*DefaultDispatcher) Close() error { return nil }
 
-func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *transport.Link) {
+func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *transport.Link, error) {
+	sessionInbound := session.InboundFromContext(ctx)
+	var user *protocol.MemoryUser
+	// Limits and stats of the user are applied to the traffic of a loopback by its first dispatch.
+	if sessionInbound != nil && !session.LoopbackFromContext(ctx) {
+		user = sessionInbound.User
+	}
+
+	var p policy.Session
+	if user != nil {
+		p = d.policy.ForUser(user.Level, user.Email)
+	}
+
+	var sourceIP string
+	limited := user != nil && len(user.Email) > 0 && (p.Limit.Connections > 0 || p.Limit.SourceIPs > 0)
+	if limited {
+		if sessionInbound.Source.IsValid() {
+			sourceIP = sessionInbound.Source.Address.String()
+		}
+		// Dispatches sharing the inbound session, e.g. mux streams, are counted as the connection that carries them.
+		if err := d.conns.Acquire(user.Email, sourceIP, sessionInbound, p.Limit.Connections, p.Limit.SourceIPs); err != nil {
+			newError("rejected connection of user ", user.Email, " from ", sourceIP).Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+			if c, _ := stats.GetOrRegisterCounter(d.stats, "user>>>"+user.Email+">>>connection>>>rejected"); c != nil {
+				c.Add(1)
+			}
+			return nil, nil, newError("user ", user.Email, " exceeds connection limits").Base(err)
+		}
+	}
+
 	opt := pipe.OptionsFromContext(ctx)
 	uplinkReader, uplinkWriter := pipe.New(opt...)
 	downlinkReader, downlinkWriter := pipe.New(opt...)
 
+	if limited {
+		go func() {
+			<-uplinkWriter.Done()
+			<-downlinkWriter.Done()
+			d.conns.Release(user.Email, sourceIP, sessionInbound)
+		}()
+	}
+
 	inboundLink := &transport.Link{
 		Reader: downlinkReader,
 		Writer: uplinkWriter,
@@ -145,35 +189,143 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 		Writer: downlinkWriter,
 	}
 
-	sessionInbound := session.InboundFromContext(ctx)
-	var user *protocol.MemoryUser
-	if sessionInbound != nil {
-		user = sessionInbound.User
+	if user == nil {
+		return inboundLink, outboundLink, nil
 	}
 
-	if user != nil && len(user.Email) > 0 {
-		p := d.policy.ForLevel(user.Level)
+	if p.Bandwidth.UplinkRate > 0 || p.Bandwidth.DownlinkRate > 0 {
+		var release []string
+		if p.Bandwidth.UplinkRate > 0 {
+			var key string
+			inboundLink.Writer, key = d.getRateLimitWriter(ctx, user, "uplink", p.Bandwidth.UplinkRate, p.Bandwidth.UplinkBurst, inboundLink.Writer)
+			release = append(release, key)
+		}
+		if p.Bandwidth.DownlinkRate > 0 {
+			var key string
+			outboundLink.Writer, key = d.getRateLimitWriter(ctx, user, "downlink", p.Bandwidth.DownlinkRate, p.Bandwidth.DownlinkBurst, outboundLink.Writer)
+			release = append(release, key)
+		}
+		if len(user.Email) > 0 {
+			go func() {
+				<-uplinkWriter.Done()
+				<-downlinkWriter.Done()
+				for _, key := range release {
+					d.releaseLimiter(key)
+				}
+			}()
+		}
+	}
+
+	if len(user.Email) > 0 {
+		var writers []*SizeStatWriter
+		var counters []stats.Counter
 		if p.Stats.UserUplink {
 			name := "user>>>" + user.Email + ">>>traffic>>>uplink"
 			if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
-				inboundLink.Writer = &SizeStatWriter{
+				w := &SizeStatWriter{
 					Counter: c,
 					Writer:  inboundLink.Writer,
 				}
+				inboundLink.Writer = w
+				writers = append(writers, w)
+				counters = append(counters, c)
 			}
 		}
 		if p.Stats.UserDownlink {
 			name := "user>>>" + user.Email + ">>>traffic>>>downlink"
 			if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
-				outboundLink.Writer = &SizeStatWriter{
+				w := &SizeStatWriter{
 					Counter: c,
 					Writer:  outboundLink.Writer,
 				}
+				outboundLink.Writer = w
+				writers = append(writers, w)
+				counters = append(counters, c)
 			}
 		}
+		if user.ExpiryTime > 0 || user.TotalTrafficLimit > 0 {
+			if len(writers) == 0 {
+				w := &SizeStatWriter{
+					Writer: inboundLink.Writer,
+				}
+				inboundLink.Writer = w
+				writers = append(writers, w)
+			}
+			check := userLimitCheck(user, counters)
+			for _, w := range writers {
+				w.Check = check
+			}
+		}
+	}
+
+	return inboundLink, outboundLink, nil
+}
+
+// userLimitCheck returns a function that fails once the user expires or its traffic, as measured by counters, reaches
+// its quota.
+func userLimitCheck(user *protocol.MemoryUser, counters []stats.Counter) func() error {
+	return func() error {
+		if user.IsExpired(time.Now()) {
+			return newError("user ", user.Email, " expired")
+		}
+		if user.TotalTrafficLimit > 0 && len(counters) > 0 {
+			var traffic int64
+			for _, c := range counters {
+				traffic += c.Value()
+			}
+			if user.IsOverQuota(traffic) {
+				return newError("user ", user.Email, " exceeds traffic limit: ", traffic, "/", user.TotalTrafficLimit)
+			}
+		}
+		return nil
+	}
+}
+
+// sharedLimiter is a limiter shared by the connections of a user, which is dropped when the last of them ends.
+type sharedLimiter struct {
+	limiter *ratelimit.Limiter
+	refs    int
+}
+
+// getRateLimitWriter wraps the writer with a limiter. Connections of the same user under the same rate and burst share
+// one limiter per direction, so a connection under another policy level neither takes nor changes their limit, while
+// anonymous connections get a limiter of their own. The key of a shared limiter is returned, and the limiter must be
+// released by releaseLimiter with it once the connection ends.
+func (d *DefaultDispatcher) getRateLimitWriter(ctx context.Context, user *protocol.MemoryUser, direction string, rate int64, burst int64, writer buf.Writer) (buf.Writer, string) {
+	if len(user.Email) == 0 {
+		return NewRateLimitWriter(ctx, ratelimit.New(rate, burst), nil, writer), ""
 	}
 
-	return inboundLink, outboundLink
+	d.access.Lock()
+	key := user.Email + ">>>" + direction + ">>>" + strconv.FormatInt(rate, 10) + ">>>" + strconv.FormatInt(burst, 10)
+	l, found := d.limiters[key]
+	if !found {
+		l = &sharedLimiter{
+			limiter: ratelimit.New(rate, burst),
+		}
+		d.limiters[key] = l
+	}
+	l.refs++
+	d.access.Unlock()
+
+	name := "user>>>" + user.Email + ">>>throttle>>>" + direction
+	var counter stats.Counter
+	if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
+		counter = c
+	}
+	return NewRateLimitWriter(ctx, l.limiter, counter, writer), key
+}
+
+// releaseLimiter drops a reference to the shared limiter with the key, removing it when it is no longer used.
+func (d *DefaultDispatcher) releaseLimiter(key string) {
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	if l, found := d.limiters[key]; found {
+		if l.refs--; l.refs <= 0 {
+			delete(d.limiters, key)
+		}
+	}
 }
 
 func shouldOverride(ctx context.Context, result SniffResult, request session.SniffingRequest, destination net.Destination) bool {
@@ -215,7 +367,10 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 	}
 	ctx = session.ContextWithOutbound(ctx, ob)
 
-	inbound, outbound := d.getLink(ctx)
+	inbound, outbound, err := d.getLink(ctx)
+	if err != nil {
+		return nil, err
+	}
 	content := session.ContentFromContext(ctx)
 	if content == nil {
 		content = new(session.Content)
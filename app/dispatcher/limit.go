@@ -0,0 +1,96 @@
+package dispatcher
+
+import (
+	"sync"
+)
+
+// userConnections tracks the active connections of a user and the source IPs they come from.
+type userConnections struct {
+	total int
+	ips   map[string]int
+	// conns counts the dispatches made for each inbound connection, so that sub-connections, e.g. mux streams, share
+	// the slot of their inbound connection.
+	conns map[interface{}]int
+}
+
+// connectionTracker enforces the connection limits of users.
+type connectionTracker struct {
+	access sync.Mutex
+	users  map[string]*userConnections
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		users: make(map[string]*userConnections),
+	}
+}
+
+// Acquire registers a dispatch of the user for the inbound connection conn from the given source IP. It returns an
+// error if a new connection exceeds maxConnections or maxIPs, which are ignored if 0. Further dispatches for a
+// connection already registered are always accepted.
+func (t *connectionTracker) Acquire(email string, ip string, conn interface{}, maxConnections uint32, maxIPs uint32) error {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	u, found := t.users[email]
+	if !found {
+		u = &userConnections{
+			ips:   make(map[string]int),
+			conns: make(map[interface{}]int),
+		}
+		t.users[email] = u
+	}
+
+	if u.conns[conn] > 0 {
+		u.conns[conn]++
+		return nil
+	}
+
+	if maxConnections > 0 && u.total >= int(maxConnections) {
+		return newError("too many connections: ", u.total, "/", maxConnections)
+	}
+	if _, found := u.ips[ip]; !found && maxIPs > 0 && len(u.ips) >= int(maxIPs) {
+		return newError("too many source IPs: ", len(u.ips), "/", maxIPs)
+	}
+
+	u.total++
+	u.ips[ip]++
+	u.conns[conn] = 1
+	return nil
+}
+
+// Release unregisters a dispatch previously registered by Acquire. The connection is released with its last dispatch.
+func (t *connectionTracker) Release(email string, ip string, conn interface{}) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	u, found := t.users[email]
+	if !found {
+		return
+	}
+
+	if u.conns[conn]--; u.conns[conn] > 0 {
+		return
+	}
+	delete(u.conns, conn)
+
+	u.total--
+	if u.ips[ip]--; u.ips[ip] <= 0 {
+		delete(u.ips, ip)
+	}
+	if u.total <= 0 {
+		delete(t.users, email)
+	}
+}
+
+// Connections returns the number of active connections and distinct source IPs of the user.
+func (t *connectionTracker) Connections(email string) (int, int) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	u, found := t.users[email]
+	if !found {
+		return 0, 0
+	}
+	return u.total, len(u.ips)
+}
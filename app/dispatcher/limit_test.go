@@ -0,0 +1,149 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/ratelimit"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/features/policy"
+	"github.com/eagleql/xray-core/features/stats"
+)
+
+func TestConnectionTrackerConnections(t *testing.T) {
+	tracker := newConnectionTracker()
+	conn1, conn2, conn3 := new(int), new(int), new(int)
+
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn1, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn2, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn3, 2, 0); err == nil {
+		t.Error("expect error on the third connection")
+	}
+	if err := tracker.Acquire("test@example.com", "127.0.0.1", conn3, 2, 0); err != nil {
+		t.Error("expect other users not to be affected, but got ", err)
+	}
+
+	tracker.Release("love@example.com", "127.0.0.1", conn1)
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn3, 2, 0); err != nil {
+		t.Error("expect connection to be accepted after release, but got ", err)
+	}
+}
+
+func TestConnectionTrackerSubConnections(t *testing.T) {
+	tracker := newConnectionTracker()
+	conn1, conn2 := new(int), new(int)
+
+	for i := 0; i < 3; i++ {
+		if err := tracker.Acquire("love@example.com", "127.0.0.1", conn1, 1, 0); err != nil {
+			t.Fatal("expect dispatches of one connection to share its slot, but got ", err)
+		}
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn2, 1, 0); err == nil {
+		t.Error("expect error on the second connection")
+	}
+
+	tracker.Release("love@example.com", "127.0.0.1", conn1)
+	tracker.Release("love@example.com", "127.0.0.1", conn1)
+	if total, _ := tracker.Connections("love@example.com"); total != 1 {
+		t.Error("expect connection to be kept until its last dispatch ends, but got ", total)
+	}
+	tracker.Release("love@example.com", "127.0.0.1", conn1)
+	if total, _ := tracker.Connections("love@example.com"); total != 0 {
+		t.Error("expect no connection, but got ", total)
+	}
+}
+
+func TestConnectionTrackerSourceIPs(t *testing.T) {
+	tracker := newConnectionTracker()
+	conn1, conn2, conn3 := new(int), new(int), new(int)
+
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn1, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.1", conn2, 0, 1); err != nil {
+		t.Error("expect connections from the same IP to be accepted, but got ", err)
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.2", conn3, 0, 1); err == nil {
+		t.Error("expect error on a second source IP")
+	}
+
+	tracker.Release("love@example.com", "127.0.0.1", conn1)
+	tracker.Release("love@example.com", "127.0.0.1", conn2)
+	if total, ips := tracker.Connections("love@example.com"); total != 0 || ips != 0 {
+		t.Error("unexpected connections: ", total, " ", ips)
+	}
+	if err := tracker.Acquire("love@example.com", "127.0.0.2", conn3, 0, 1); err != nil {
+		t.Error("expect new source IP to be accepted after release, but got ", err)
+	}
+}
+
+// connectionLimitPolicy limits every user to a single connection.
+type connectionLimitPolicy struct {
+	policy.DefaultManager
+}
+
+func (m connectionLimitPolicy) ForUser(level uint32, email string) policy.Session {
+	p := m.ForLevel(level)
+	p.Limit.Connections = 1
+	return p
+}
+
+func TestGetLinkLoopback(t *testing.T) {
+	d := &DefaultDispatcher{
+		policy:   connectionLimitPolicy{},
+		stats:    stats.NoopManager{},
+		limiters: make(map[string]*sharedLimiter),
+		conns:    newConnectionTracker(),
+	}
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		User: &protocol.MemoryUser{Email: "test@example.com"},
+	})
+	if _, _, err := d.getLink(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The loopback dispatches the connection again under a copy of the inbound.
+	inbound := *session.InboundFromContext(ctx)
+	loopbackCtx := session.ContextWithInbound(ctx, &inbound)
+	if _, _, err := d.getLink(loopbackCtx); err == nil {
+		t.Error("expect a second connection of the user to be rejected")
+	}
+	if _, _, err := d.getLink(session.ContextWithLoopback(loopbackCtx)); err != nil {
+		t.Error("expect the loopback not to take another connection of the user, but got ", err)
+	}
+}
+
+func TestGetRateLimitWriterShared(t *testing.T) {
+	d := &DefaultDispatcher{
+		stats:    stats.NoopManager{},
+		limiters: make(map[string]*sharedLimiter),
+	}
+	user := &protocol.MemoryUser{Email: "test@example.com"}
+	limiter := func(rate int64, burst int64) (*ratelimit.Limiter, string) {
+		w, key := d.getRateLimitWriter(context.Background(), user, "uplink", rate, burst, buf.Discard)
+		return w.(*RateLimitWriter).limiter, key
+	}
+
+	a, keyA := limiter(1000, 100)
+	b, keyB := limiter(1000, 100)
+	if a != b {
+		t.Error("expect connections of the user under the same limit to share a limiter")
+	}
+	c, keyC := limiter(2000, 100)
+	if c == a {
+		t.Error("expect a connection of the user under another limit to get another limiter")
+	}
+
+	for _, key := range []string{keyA, keyB, keyC} {
+		d.releaseLimiter(key)
+	}
+	if len(d.limiters) != 0 {
+		t.Error("expect all limiters to be removed, but got ", len(d.limiters))
+	}
+}
@@ -0,0 +1,55 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/ratelimit"
+	"github.com/eagleql/xray-core/features/stats"
+)
+
+// RateLimitWriter delays writes so that the traffic passing through it does not exceed the rate of its Limiter.
+type RateLimitWriter struct {
+	limiter *ratelimit.Limiter
+	// counter, if not nil, accumulates the time spent waiting for the limiter, in milliseconds.
+	counter stats.Counter
+	writer  buf.Writer
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewRateLimitWriter creates a RateLimitWriter. Pending writes are aborted when ctx is done or the writer is closed.
+func NewRateLimitWriter(ctx context.Context, limiter *ratelimit.Limiter, counter stats.Counter, writer buf.Writer) *RateLimitWriter {
+	ctx, cancel := context.WithCancel(ctx)
+	return &RateLimitWriter{
+		limiter: limiter,
+		counter: counter,
+		writer:  writer,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (w *RateLimitWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	d, err := w.limiter.Wait(w.ctx, int64(mb.Len()))
+	if d > 0 && w.counter != nil {
+		w.counter.Add(int64(d / time.Millisecond))
+	}
+	if err != nil {
+		buf.ReleaseMulti(mb)
+		return err
+	}
+	return w.writer.WriteMultiBuffer(mb)
+}
+
+func (w *RateLimitWriter) Close() error {
+	w.cancel()
+	return common.Close(w.writer)
+}
+
+func (w *RateLimitWriter) Interrupt() {
+	w.cancel()
+	common.Interrupt(w.writer)
+}
@@ -0,0 +1,46 @@
+package dispatcher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/eagleql/xray-core/app/dispatcher"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/ratelimit"
+)
+
+func TestRateLimitWriter(t *testing.T) {
+	var c TestCounter
+	writer := NewRateLimitWriter(context.Background(), ratelimit.New(1000, 100), &c, buf.Discard)
+
+	start := time.Now()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 100))))
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 200))))
+
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Error("expect writes to be throttled, but took ", d)
+	}
+	if c.Value() < 150 {
+		t.Error("expect throttled time to be counted, but got ", c.Value())
+	}
+}
+
+func TestRateLimitWriterInterrupt(t *testing.T) {
+	writer := NewRateLimitWriter(context.Background(), ratelimit.New(1000, 100), nil, buf.Discard)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		writer.Interrupt()
+	}()
+
+	start := time.Now()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 100))))
+	if err := writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 10000))); err == nil {
+		t.Error("expect error after interrupt")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Error("expect pending write to be interrupted, but took ", d)
+	}
+}
@@ -7,12 +7,24 @@ import (
 )
 
 type SizeStatWriter struct {
+	// Counter, if not nil, accumulates the size of the traffic passing through.
 	Counter stats.Counter
 	Writer  buf.Writer
+	// Check, if not nil, is called before each write, which fails with the error it returns, e.g. once the user is
+	// over quota.
+	Check func() error
 }
 
 func (w *SizeStatWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
-	w.Counter.Add(int64(mb.Len()))
+	if w.Check != nil {
+		if err := w.Check(); err != nil {
+			buf.ReleaseMulti(mb)
+			return err
+		}
+	}
+	if w.Counter != nil {
+		w.Counter.Add(int64(mb.Len()))
+	}
 	return w.Writer.WriteMultiBuffer(mb)
 }
 
@@ -1,6 +1,7 @@
 package dispatcher_test
 
 import (
+	"errors"
 	"testing"
 
 	. "github.com/eagleql/xray-core/app/dispatcher"
@@ -42,3 +43,25 @@ func TestStatsWriter(t *testing.T) {
 		t.Fatal("unexpected counter value. want 7, but got ", c.Value())
 	}
 }
+
+func TestStatsWriterCheck(t *testing.T) {
+	var c TestCounter
+	writer := &SizeStatWriter{
+		Counter: &c,
+		Writer:  buf.Discard,
+		Check: func() error {
+			if c.Value() >= 4 {
+				return errors.New("over quota")
+			}
+			return nil
+		},
+	}
+
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("abcd"))))
+	if err := writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("efg"))); err == nil {
+		t.Error("expect error once over quota")
+	}
+	if c.Value() != 4 {
+		t.Error("expect rejected write not to be counted, but got ", c.Value())
+	}
+}
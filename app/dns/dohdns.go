@@ -157,6 +157,13 @@ func (s *DoHNameServer) Name() string {
 	return s.name
 }
 
+// Close implements common.Closable.
+func (s *DoHNameServer) Close() error {
+	s.cleanup.Close()
+	s.httpClient.CloseIdleConnections()
+	return nil
+}
+
 // Cleanup clears expired items from cache
 func (s *DoHNameServer) Cleanup() error {
 	now := time.Now()
@@ -5,7 +5,6 @@ package dns
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/url"
 	"strings"
 	"sync"
@@ -28,6 +27,7 @@ import (
 // Server is a DNS rely server.
 type Server struct {
 	sync.Mutex
+	access        sync.RWMutex
 	hosts         *StaticHosts
 	clientIP      net.IP
 	clients       []Client // clientIdx -> Client
@@ -52,6 +52,9 @@ type MultiGeoIPMatcher struct {
 
 var errExpectedIPNonMatch = errors.New("expectIPs not match")
 
+// queryTimeout is the timeout of a query to a DNS client.
+const queryTimeout = time.Second * 4
+
 // Match check ip match
 func (c *MultiGeoIPMatcher) Match(ip net.IP) bool {
 	for _, matcher := range c.matchers {
@@ -95,7 +98,7 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 	}
 	server.hosts = hosts
 
-	addNameServer := func(ns *NameServer) int {
+	addNameServer := func(ns *NameServer) (int, error) {
 		endpoint := ns.Address
 		address := endpoint.Address.AsAddress()
 
@@ -124,7 +127,7 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 			// DOH Local mode
 			u, err := url.Parse(address.Domain())
 			if err != nil {
-				log.Fatalln(newError("DNS config error").Base(err))
+				return 0, newError("DNS config error").Base(err)
 			}
 			server.clients = append(server.clients, NewDoHLocalNameServer(u, server.clientIP))
 
@@ -132,19 +135,22 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 			// DOH Remote mode
 			u, err := url.Parse(address.Domain())
 			if err != nil {
-				log.Fatalln(newError("DNS config error").Base(err))
+				return 0, newError("DNS config error").Base(err)
 			}
 			idx := len(server.clients)
 			server.clients = append(server.clients, nil)
 
 			// need the core dispatcher, register DOHClient at callback
-			common.Must(core.RequireFeatures(ctx, func(d routing.Dispatcher) {
+			if err := core.RequireFeatures(ctx, func(d routing.Dispatcher) error {
 				c, err := NewDoHNameServer(u, d, server.clientIP)
 				if err != nil {
-					log.Fatalln(newError("DNS config error").Base(err))
+					return newError("DNS config error").Base(err)
 				}
 				server.clients[idx] = c
-			}))
+				return nil
+			}); err != nil {
+				return 0, err
+			}
 
 		case address.Family().IsDomain() && address.Domain() == "fakedns":
 			server.clients = append(server.clients, NewFakeDNSServer())
@@ -165,13 +171,15 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 			}
 		}
 		server.ipIndexMap = append(server.ipIndexMap, nil)
-		return len(server.clients) - 1
+		return len(server.clients) - 1, nil
 	}
 
 	if len(config.NameServers) > 0 {
 		features.PrintDeprecatedFeatureWarning("simple DNS server")
 		for _, destPB := range config.NameServers {
-			addNameServer(&NameServer{Address: destPB})
+			if _, err := addNameServer(&NameServer{Address: destPB}); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -179,7 +187,10 @@ func New(ctx context.Context, config *Config) (*Server, error) {
 		clientIndices := []int{}
 		domainRuleCount := 0
 		for _, ns := range config.NameServer {
-			idx := addNameServer(ns)
+			idx, err := addNameServer(ns)
+			if err != nil {
+				return nil, err
+			}
 			clientIndices = append(clientIndices, idx)
 			domainRuleCount += len(ns.PrioritizedDomain)
 		}
@@ -264,10 +275,74 @@ func (s *Server) Start() error {
 
 // Close implements common.Closable.
 func (s *Server) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	closeClients(s.clients)
+	return nil
+}
+
+func closeClients(clients []Client) {
+	for _, client := range clients {
+		if err := common.Close(client); err != nil {
+			newError("failed to close DNS client ", client.Name()).Base(err).WriteToLog()
+		}
+	}
+}
+
+// Reload implements features.Reloadable.
+func (s *Server) Reload(config interface{}) error {
+	c, ok := config.(*Config)
+	if !ok {
+		return common.ErrNoClue
+	}
+	ns, err := New(s.ctx, c)
+	if err != nil {
+		return newError("failed to reload DNS").Base(err)
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	// Lookups in progress may still use the old clients, which are closed once their queries time out.
+	old := s.clients
+	time.AfterFunc(queryTimeout, func() {
+		closeClients(old)
+	})
+
+	s.hosts = ns.hosts
+	s.clientIP = ns.clientIP
+	s.clients = ns.clients
+	s.ipIndexMap = ns.ipIndexMap
+	s.domainRules = ns.domainRules
+	s.domainMatcher = ns.domainMatcher
+	s.matcherInfos = ns.matcherInfos
+	s.tag = ns.tag
 	return nil
 }
 
+// snapshot returns a copy of the state of the server, so that lookups don't block reloading.
+func (s *Server) snapshot() *Server {
+	s.access.RLock()
+	defer s.access.RUnlock()
+
+	return &Server{
+		hosts:         s.hosts,
+		clientIP:      s.clientIP,
+		clients:       s.clients,
+		ctx:           s.ctx,
+		ipIndexMap:    s.ipIndexMap,
+		domainRules:   s.domainRules,
+		domainMatcher: s.domainMatcher,
+		matcherInfos:  s.matcherInfos,
+		tag:           s.tag,
+	}
+}
+
 func (s *Server) IsOwnLink(ctx context.Context) bool {
+	s.access.RLock()
+	defer s.access.RUnlock()
+
 	inbound := session.InboundFromContext(ctx)
 	return inbound != nil && inbound.Tag == s.tag
 }
@@ -301,7 +376,7 @@ func (s *Server) Match(idx int, client Client, domain string, ips []net.IP) ([]n
 }
 
 func (s *Server) queryIPTimeout(idx int, client Client, domain string, option dns.IPOption) ([]net.IP, error) {
-	ctx, cancel := context.WithTimeout(s.ctx, time.Second*4)
+	ctx, cancel := context.WithTimeout(s.ctx, queryTimeout)
 	if len(s.tag) > 0 {
 		ctx = session.ContextWithInbound(ctx, &session.Inbound{
 			Tag: s.tag,
@@ -348,7 +423,10 @@ func (s *Server) LookupIP(domain string, option dns.IPOption) ([]net.IP, error)
 	if domain == "" {
 		return nil, newError("empty domain name")
 	}
+	return s.snapshot().lookupIP(domain, option)
+}
 
+func (s *Server) lookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
 	// normalize the FQDN form query
 	if strings.HasSuffix(domain, ".") {
 		domain = domain[:len(domain)-1]
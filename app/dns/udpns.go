@@ -62,6 +62,12 @@ func (s *ClassicNameServer) Name() string {
 	return s.name
 }
 
+// Close implements common.Closable.
+func (s *ClassicNameServer) Close() error {
+	s.cleanup.Close()
+	return s.udpServer.Close()
+}
+
 func (s *ClassicNameServer) Cleanup() error {
 	now := time.Now()
 	s.Lock()
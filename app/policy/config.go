@@ -58,6 +58,47 @@ func (p *Policy) overrideWith(another *Policy) {
 			Connection: another.Buffer.Connection,
 		}
 	}
+	if another.Bandwidth != nil {
+		p.Bandwidth = &Policy_Bandwidth{
+			UplinkRate:    another.Bandwidth.UplinkRate,
+			DownlinkRate:  another.Bandwidth.DownlinkRate,
+			UplinkBurst:   another.Bandwidth.UplinkBurst,
+			DownlinkBurst: another.Bandwidth.DownlinkBurst,
+		}
+	}
+	if another.Limit != nil {
+		p.Limit = &Policy_Limit{
+			Connections: another.Limit.Connections,
+			SourceIps:   another.Limit.SourceIps,
+		}
+	}
+}
+
+// ToCoreBandwidth converts this Policy_Bandwidth to policy.Bandwidth.
+func (b *Policy_Bandwidth) ToCoreBandwidth() policy.Bandwidth {
+	return policy.Bandwidth{
+		UplinkRate:    int64(b.UplinkRate),
+		DownlinkRate:  int64(b.DownlinkRate),
+		UplinkBurst:   int64(b.UplinkBurst),
+		DownlinkBurst: int64(b.DownlinkBurst),
+	}
+}
+
+// mergeInto overrides the settings in base with the non-zero settings of this Policy_Bandwidth.
+func (b *Policy_Bandwidth) mergeInto(base policy.Bandwidth) policy.Bandwidth {
+	if b.UplinkRate > 0 {
+		base.UplinkRate = int64(b.UplinkRate)
+	}
+	if b.DownlinkRate > 0 {
+		base.DownlinkRate = int64(b.DownlinkRate)
+	}
+	if b.UplinkBurst > 0 {
+		base.UplinkBurst = int64(b.UplinkBurst)
+	}
+	if b.DownlinkBurst > 0 {
+		base.DownlinkBurst = int64(b.DownlinkBurst)
+	}
+	return base
 }
 
 // ToCorePolicy converts this Policy to policy.Session.
@@ -77,6 +118,13 @@ func (p *Policy) ToCorePolicy() policy.Session {
 	if p.Buffer != nil {
 		cp.Buffer.PerConnection = p.Buffer.Connection
 	}
+	if p.Bandwidth != nil {
+		cp.Bandwidth = p.Bandwidth.ToCoreBandwidth()
+	}
+	if p.Limit != nil {
+		cp.Limit.Connections = p.Limit.Connections
+		cp.Limit.SourceIPs = p.Limit.SourceIps
+	}
 	return cp
 }
 
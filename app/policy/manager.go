@@ -10,6 +10,7 @@ import (
 // Instance is an instance of Policy manager.
 type Instance struct {
 	levels map[uint32]*Policy
+	users  map[string]*Policy_Bandwidth
 	system *SystemPolicy
 }
 
@@ -17,6 +18,7 @@ type Instance struct {
 func New(ctx context.Context, config *Config) (*Instance, error) {
 	m := &Instance{
 		levels: make(map[uint32]*Policy),
+		users:  config.UserBandwidth,
 		system: config.System,
 	}
 	if len(config.Level) > 0 {
@@ -43,6 +45,15 @@ func (m *Instance) ForLevel(level uint32) policy.Session {
 	return policy.SessionDefault()
 }
 
+// ForUser implements policy.Manager.
+func (m *Instance) ForUser(level uint32, email string) policy.Session {
+	p := m.ForLevel(level)
+	if b, ok := m.users[email]; ok && b != nil {
+		p.Bandwidth = b.mergeInto(p.Bandwidth)
+	}
+	return p
+}
+
 // ForSystem implements policy.Manager.
 func (m *Instance) ForSystem() policy.System {
 	if m.system == nil {
@@ -43,3 +43,37 @@ func TestPolicy(t *testing.T) {
 		}
 	}
 }
+
+func TestPolicyForUser(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Bandwidth: &Policy_Bandwidth{
+					UplinkRate:   1024,
+					DownlinkRate: 2048,
+				},
+			},
+		},
+		UserBandwidth: map[string]*Policy_Bandwidth{
+			"love@example.com": {
+				UplinkRate:  4096,
+				UplinkBurst: 8192,
+			},
+		},
+	})
+	common.Must(err)
+
+	{
+		p := manager.ForUser(0, "test@example.com")
+		if p.Bandwidth.UplinkRate != 1024 || p.Bandwidth.DownlinkRate != 2048 {
+			t.Error("unexpected level bandwidth: ", p.Bandwidth)
+		}
+	}
+
+	{
+		p := manager.ForUser(0, "love@example.com")
+		if p.Bandwidth.UplinkRate != 4096 || p.Bandwidth.UplinkBurst != 8192 || p.Bandwidth.DownlinkRate != 2048 {
+			t.Error("unexpected user bandwidth: ", p.Bandwidth)
+		}
+	}
+}
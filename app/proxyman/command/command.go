@@ -2,13 +2,16 @@ package command
 
 import (
 	"context"
+	"time"
 
 	grpc "google.golang.org/grpc"
 
 	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/inbound"
 	"github.com/eagleql/xray-core/features/outbound"
+	"github.com/eagleql/xray-core/features/stats"
 	"github.com/eagleql/xray-core/proxy"
 )
 
@@ -66,6 +69,7 @@ type handlerServer struct {
 	s   *core.Instance
 	ihm inbound.Manager
 	ohm outbound.Manager
+	sm  stats.Manager
 }
 
 func (s *handlerServer) AddInbound(ctx context.Context, request *AddInboundRequest) (*AddInboundResponse, error) {
@@ -98,6 +102,105 @@ func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundR
 	return &AlterInboundResponse{}, operation.ApplyInbound(ctx, handler)
 }
 
+func (s *handlerServer) ListInbounds(ctx context.Context, request *ListInboundsRequest) (*ListInboundsResponse, error) {
+	handlers := s.ihm.ListHandlers(ctx)
+	response := &ListInboundsResponse{}
+	for _, handler := range handlers {
+		config := &core.InboundHandlerConfig{Tag: handler.Tag()}
+		if h, ok := handler.(core.GetInboundConfig); ok && h.GetInboundConfig() != nil {
+			config = h.GetInboundConfig()
+		}
+		response.Inbounds = append(response.Inbounds, config)
+	}
+	return response, nil
+}
+
+func (s *handlerServer) GetUserStatus(ctx context.Context, request *GetUserStatusRequest) (*GetUserStatusResponse, error) {
+	ul, err := s.getUserLister(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	user := ul.GetUserByEmail(ctx, request.Email)
+	if user == nil {
+		return nil, newError("user ", request.Email, " not found")
+	}
+
+	traffic := stats.GetUserTraffic(s.sm, user.Email)
+	return &GetUserStatusResponse{
+		User: &protocol.User{
+			Level:             user.Level,
+			Email:             user.Email,
+			ExpiryTime:        user.ExpiryTime,
+			TotalTrafficLimit: user.TotalTrafficLimit,
+		},
+		Traffic:   traffic,
+		Expired:   user.IsExpired(time.Now()),
+		OverQuota: user.IsOverQuota(traffic),
+	}, nil
+}
+
+func (s *handlerServer) KickUser(ctx context.Context, request *KickUserRequest) (*KickUserResponse, error) {
+	handler, err := s.ihm.GetHandler(ctx, request.Tag)
+	if err != nil {
+		return nil, newError("failed to get handler: ", request.Tag).Base(err)
+	}
+	p, err := getInbound(handler)
+	if err != nil {
+		return nil, err
+	}
+	uk, ok := p.(proxy.UserKicker)
+	if !ok {
+		return nil, newError("proxy is not a UserKicker")
+	}
+	if request.Email == "" {
+		return nil, newError("email must not be empty")
+	}
+	return &KickUserResponse{Count: uint32(uk.KickUser(ctx, request.Email))}, nil
+}
+
+func (s *handlerServer) getUserLister(ctx context.Context, tag string) (proxy.UserLister, error) {
+	handler, err := s.ihm.GetHandler(ctx, tag)
+	if err != nil {
+		return nil, newError("failed to get handler: ", tag).Base(err)
+	}
+	p, err := getInbound(handler)
+	if err != nil {
+		return nil, err
+	}
+	ul, ok := p.(proxy.UserLister)
+	if !ok {
+		return nil, newError("proxy is not a UserLister")
+	}
+	return ul, nil
+}
+
+func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUserResponse, error) {
+	ul, err := s.getUserLister(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.Email) > 0 {
+		user := ul.GetUserByEmail(ctx, request.Email)
+		if user == nil {
+			return &GetInboundUserResponse{}, nil
+		}
+		return &GetInboundUserResponse{Users: []*protocol.User{protocol.ToProtoUser(user)}}, nil
+	}
+	var users []*protocol.User
+	for _, user := range ul.GetUsers(ctx) {
+		users = append(users, protocol.ToProtoUser(user))
+	}
+	return &GetInboundUserResponse{Users: users}, nil
+}
+
+func (s *handlerServer) GetInboundUserCount(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUsersCountResponse, error) {
+	ul, err := s.getUserLister(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return &GetInboundUsersCountResponse{Count: ul.GetUsersCount(ctx)}, nil
+}
+
 func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundRequest) (*AddOutboundResponse, error) {
 	if err := core.AddOutboundHandler(s.s, request.Outbound); err != nil {
 		return nil, err
@@ -123,6 +226,26 @@ func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboun
 	return &AlterOutboundResponse{}, operation.ApplyOutbound(ctx, handler)
 }
 
+func (s *handlerServer) ListOutbounds(ctx context.Context, request *ListOutboundsRequest) (*ListOutboundsResponse, error) {
+	handlers := s.ohm.ListHandlers(ctx)
+	response := &ListOutboundsResponse{}
+	for _, handler := range handlers {
+		config := &core.OutboundHandlerConfig{Tag: handler.Tag()}
+		if h, ok := handler.(core.GetOutboundConfig); ok && h.GetOutboundConfig() != nil {
+			config = h.GetOutboundConfig()
+		}
+		response.Outbounds = append(response.Outbounds, config)
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ReloadConfig(ctx context.Context, request *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	if err := s.s.ReloadConfig(); err != nil {
+		return nil, newError("failed to reload config").Base(err)
+	}
+	return &ReloadConfigResponse{}, nil
+}
+
 func (s *handlerServer) mustEmbedUnimplementedHandlerServiceServer() {}
 
 type service struct {
@@ -133,9 +256,10 @@ func (s *service) Register(server *grpc.Server) {
 	hs := &handlerServer{
 		s: s.v,
 	}
-	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager) {
+	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager, sm stats.Manager) {
 		hs.ihm = im
 		hs.ohm = om
+		hs.sm = sm
 	}))
 	RegisterHandlerServiceServer(server, hs)
 
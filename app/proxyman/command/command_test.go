@@ -0,0 +1,159 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/features/inbound"
+	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/proxy"
+	"github.com/eagleql/xray-core/proxy/trojan"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+type testInbound struct {
+	users []*protocol.MemoryUser
+}
+
+func (*testInbound) Network() []net.Network {
+	return []net.Network{net.Network_TCP}
+}
+
+func (*testInbound) Process(context.Context, net.Network, internet.Connection, routing.Dispatcher) error {
+	return nil
+}
+
+func (p *testInbound) GetUserByEmail(ctx context.Context, email string) *protocol.MemoryUser {
+	for _, u := range p.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (p *testInbound) GetUsers(context.Context) []*protocol.MemoryUser {
+	return p.users
+}
+
+func (p *testInbound) GetUsersCount(context.Context) int64 {
+	return int64(len(p.users))
+}
+
+type testHandler struct {
+	tag   string
+	proxy proxy.Inbound
+}
+
+func (*testHandler) Start() error { return nil }
+func (*testHandler) Close() error { return nil }
+
+func (h *testHandler) Tag() string { return h.tag }
+
+func (*testHandler) GetRandomInboundProxy() (interface{}, net.Port, int) { return nil, 0, 0 }
+
+func (h *testHandler) GetInbound() proxy.Inbound { return h.proxy }
+
+type testManager struct {
+	handlers []inbound.Handler
+}
+
+func (*testManager) Type() interface{} { return inbound.ManagerType() }
+func (*testManager) Start() error      { return nil }
+func (*testManager) Close() error      { return nil }
+
+func (m *testManager) GetHandler(ctx context.Context, tag string) (inbound.Handler, error) {
+	for _, h := range m.handlers {
+		if h.Tag() == tag {
+			return h, nil
+		}
+	}
+	return nil, newError("handler not found: ", tag)
+}
+
+func (m *testManager) AddHandler(ctx context.Context, handler inbound.Handler) error {
+	m.handlers = append(m.handlers, handler)
+	return nil
+}
+
+func (m *testManager) ListHandlers(context.Context) []inbound.Handler {
+	return m.handlers
+}
+
+func (*testManager) RemoveHandler(context.Context, string) error {
+	return nil
+}
+
+func newTestServer() (*handlerServer, *protocol.User) {
+	user := &protocol.User{
+		Email:             "love@example.com",
+		Level:             1,
+		TotalTrafficLimit: 1024,
+		Account:           serial.ToTypedMessage(&trojan.Account{Password: "password"}),
+	}
+	mUser, err := user.ToMemoryUser()
+	common.Must(err)
+
+	return &handlerServer{
+		ihm: &testManager{
+			handlers: []inbound.Handler{
+				&testHandler{tag: "in", proxy: &testInbound{users: []*protocol.MemoryUser{mUser}}},
+				&testHandler{tag: "other", proxy: &testInbound{}},
+			},
+		},
+	}, user
+}
+
+func TestGetInboundUsers(t *testing.T) {
+	s, user := newTestServer()
+
+	resp, err := s.GetInboundUsers(context.Background(), &GetInboundUserRequest{Tag: "in"})
+	common.Must(err)
+	if len(resp.Users) != 1 || !proto.Equal(resp.Users[0], user) {
+		t.Error("unexpected users: ", resp.Users)
+	}
+
+	resp, err = s.GetInboundUsers(context.Background(), &GetInboundUserRequest{Tag: "in", Email: "love@example.com"})
+	common.Must(err)
+	if len(resp.Users) != 1 || !proto.Equal(resp.Users[0], user) {
+		t.Error("unexpected users: ", resp.Users)
+	}
+
+	resp, err = s.GetInboundUsers(context.Background(), &GetInboundUserRequest{Tag: "in", Email: "test@example.com"})
+	common.Must(err)
+	if len(resp.Users) != 0 {
+		t.Error("expect no user, but got ", resp.Users)
+	}
+
+	if _, err := s.GetInboundUsers(context.Background(), &GetInboundUserRequest{Tag: "none"}); err == nil {
+		t.Error("expect error for unknown inbound")
+	}
+}
+
+func TestGetInboundUserCount(t *testing.T) {
+	s, _ := newTestServer()
+
+	for tag, count := range map[string]int64{"in": 1, "other": 0} {
+		resp, err := s.GetInboundUserCount(context.Background(), &GetInboundUserRequest{Tag: tag})
+		common.Must(err)
+		if resp.Count != count {
+			t.Error("expect ", count, " users in ", tag, ", but got ", resp.Count)
+		}
+	}
+}
+
+func TestListInbounds(t *testing.T) {
+	s, _ := newTestServer()
+
+	resp, err := s.ListInbounds(context.Background(), &ListInboundsRequest{})
+	common.Must(err)
+	if len(resp.Inbounds) != 2 || resp.Inbounds[0].Tag != "in" || resp.Inbounds[1].Tag != "other" {
+		t.Error("unexpected inbounds: ", resp.Inbounds)
+	}
+}
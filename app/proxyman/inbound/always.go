@@ -46,6 +46,7 @@ type AlwaysOnInboundHandler struct {
 	workers []worker
 	mux     *mux.Server
 	tag     string
+	config  *core.InboundHandlerConfig
 }
 
 func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*AlwaysOnInboundHandler, error) {
@@ -185,3 +186,8 @@ func (h *AlwaysOnInboundHandler) Tag() string {
 func (h *AlwaysOnInboundHandler) GetInbound() proxy.Inbound {
 	return h.proxy
 }
+
+// GetInboundConfig implements core.GetInboundConfig.
+func (h *AlwaysOnInboundHandler) GetInboundConfig() *core.InboundHandlerConfig {
+	return h.config
+}
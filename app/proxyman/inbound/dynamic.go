@@ -28,6 +28,7 @@ type DynamicInboundHandler struct {
 	lastRefresh    time.Time
 	mux            *mux.Server
 	task           *task.Periodic
+	config         *core.InboundHandlerConfig
 
 	ctx context.Context
 }
@@ -201,3 +202,8 @@ func (h *DynamicInboundHandler) GetRandomInboundProxy() (interface{}, net.Port,
 func (h *DynamicInboundHandler) Tag() string {
 	return h.tag
 }
+
+// GetInboundConfig implements core.GetInboundConfig.
+func (h *DynamicInboundHandler) GetInboundConfig() *core.InboundHandlerConfig {
+	return h.config
+}
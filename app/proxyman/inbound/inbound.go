@@ -4,6 +4,7 @@ package inbound
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/eagleql/xray-core/app/proxyman"
@@ -69,6 +70,25 @@ func (m *Manager) GetHandler(ctx context.Context, tag string) (inbound.Handler,
 	return handler, nil
 }
 
+// ListHandlers implements inbound.Manager. Untagged handlers come first in the order they were added, followed by the
+// tagged ones sorted by tag.
+func (m *Manager) ListHandlers(ctx context.Context) []inbound.Handler {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	handlers := make([]inbound.Handler, 0, len(m.taggedHandlers)+len(m.untaggedHandler))
+	handlers = append(handlers, m.untaggedHandler...)
+	tags := make([]string, 0, len(m.taggedHandlers))
+	for tag := range m.taggedHandlers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		handlers = append(handlers, m.taggedHandlers[tag])
+	}
+	return handlers
+}
+
 // RemoveHandler implements inbound.Manager.
 func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	if tag == "" {
@@ -162,11 +182,21 @@ func NewHandler(ctx context.Context, config *core.InboundHandlerConfig) (inbound
 
 	allocStrategy := receiverSettings.AllocationStrategy
 	if allocStrategy == nil || allocStrategy.Type == proxyman.AllocationStrategy_Always {
-		return NewAlwaysOnInboundHandler(ctx, tag, receiverSettings, proxySettings)
+		h, err := NewAlwaysOnInboundHandler(ctx, tag, receiverSettings, proxySettings)
+		if err != nil {
+			return nil, err
+		}
+		h.config = config
+		return h, nil
 	}
 
 	if allocStrategy.Type == proxyman.AllocationStrategy_Random {
-		return NewDynamicInboundHandler(ctx, tag, receiverSettings, proxySettings)
+		h, err := NewDynamicInboundHandler(ctx, tag, receiverSettings, proxySettings)
+		if err != nil {
+			return nil, err
+		}
+		h.config = config
+		return h, nil
 	}
 	return nil, newError("unknown allocation strategy: ", receiverSettings.AllocationStrategy.Type).AtError()
 }
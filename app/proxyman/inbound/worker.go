@@ -42,7 +42,8 @@ type tcpWorker struct {
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
 
-	hub internet.Listener
+	hub    internet.Listener
+	plugin common.Closable
 
 	ctx context.Context
 }
@@ -117,11 +118,26 @@ func (w *tcpWorker) Proxy() proxy.Inbound {
 
 func (w *tcpWorker) Start() error {
 	ctx := context.Background()
-	hub, err := internet.ListenTCP(ctx, w.address, w.port, w.stream, func(conn internet.Connection) {
+	address, port := w.address, w.port
+	plugin, hasPlugin := w.proxy.(proxy.PluginInbound)
+	hasPlugin = hasPlugin && plugin.HasPlugin()
+	if hasPlugin {
+		// The plugin takes over the configured address, and forwards connections to a local port.
+		address, port = net.LocalHostIP, 0
+	}
+	hub, err := internet.ListenTCP(ctx, address, port, w.stream, func(conn internet.Connection) {
 		go w.callback(conn)
 	})
 	if err != nil {
-		return newError("failed to listen TCP on ", w.port).AtWarning().Base(err)
+		return newError("failed to listen TCP on ", port).AtWarning().Base(err)
+	}
+	if hasPlugin {
+		p, err := plugin.StartPlugin(net.TCPDestination(w.address, w.port), net.DestinationFromAddr(hub.Addr()))
+		if err != nil {
+			hub.Close()
+			return newError("failed to start plugin on ", w.port).AtWarning().Base(err)
+		}
+		w.plugin = p
 	}
 	w.hub = hub
 	return nil
@@ -129,6 +145,11 @@ func (w *tcpWorker) Start() error {
 
 func (w *tcpWorker) Close() error {
 	var errors []interface{}
+	if w.plugin != nil {
+		if err := w.plugin.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	if w.hub != nil {
 		if err := common.Close(w.hub); err != nil {
 			errors = append(errors, err)
@@ -48,6 +48,7 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 // Handler is an implements of outbound.Handler.
 type Handler struct {
 	tag             string
+	config          *core.OutboundHandlerConfig
 	senderSettings  *proxyman.SenderConfig
 	streamSettings  *internet.MemoryStreamConfig
 	proxy           proxy.Outbound
@@ -63,6 +64,7 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 	uplinkCounter, downlinkCounter := getStatCounter(v, config.Tag)
 	h := &Handler{
 		tag:             config.Tag,
+		config:          config,
 		outboundManager: v.GetFeature(outbound.ManagerType()).(outbound.Manager),
 		uplinkCounter:   uplinkCounter,
 		downlinkCounter: downlinkCounter,
@@ -217,13 +219,21 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 	return h.proxy
 }
 
+// GetOutboundConfig implements core.GetOutboundConfig.
+func (h *Handler) GetOutboundConfig() *core.OutboundHandlerConfig {
+	return h.config
+}
+
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if r, ok := h.proxy.(common.Runnable); ok {
+		return r.Start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
 	common.Close(h.mux)
-	return nil
+	return common.Close(h.proxy)
 }
@@ -78,3 +78,53 @@ func TestOutboundWithStatCounter(t *testing.T) {
 		t.Errorf("Expected conn to be StatCouterConnection")
 	}
 }
+
+func TestListOutbounds(t *testing.T) {
+	v, _ := core.New(&core.Config{})
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	m, _ := New(ctx, nil)
+	v.AddFeature(m)
+
+	configs := []*core.OutboundHandlerConfig{
+		{
+			Tag:           "c",
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		},
+		{
+			Tag:           "a",
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		},
+		{
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		},
+		{
+			Tag:           "b",
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		},
+	}
+	for _, config := range configs {
+		h, err := NewHandler(ctx, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.AddHandler(ctx, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handlers := m.ListHandlers(ctx)
+	if len(handlers) != len(configs) {
+		t.Fatalf("expect %d handlers, but got %d", len(configs), len(handlers))
+	}
+	for i, tag := range []string{"", "a", "b", "c"} {
+		if handlers[i].Tag() != tag {
+			t.Errorf("expect handler %d to be %q, but got %q", i, tag, handlers[i].Tag())
+		}
+	}
+	for _, h := range handlers {
+		config := h.(core.GetOutboundConfig).GetOutboundConfig()
+		if config.Tag != h.Tag() {
+			t.Errorf("expect config of %q, but got %q", h.Tag(), config.Tag)
+		}
+	}
+}
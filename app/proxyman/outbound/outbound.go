@@ -4,6 +4,7 @@ package outbound
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"sync"
 
@@ -124,6 +125,25 @@ func (m *Manager) AddHandler(ctx context.Context, handler outbound.Handler) erro
 	return nil
 }
 
+// ListHandlers implements outbound.Manager. Untagged handlers come first in the order they were added, followed by the
+// tagged ones sorted by tag.
+func (m *Manager) ListHandlers(ctx context.Context) []outbound.Handler {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	handlers := make([]outbound.Handler, 0, len(m.taggedHandler)+len(m.untaggedHandlers))
+	handlers = append(handlers, m.untaggedHandlers...)
+	tags := make([]string, 0, len(m.taggedHandler))
+	for tag := range m.taggedHandler {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		handlers = append(handlers, m.taggedHandler[tag])
+	}
+	return handlers
+}
+
 // RemoveHandler implements outbound.Manager.
 func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	if tag == "" {
@@ -2,8 +2,11 @@ package reverse
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
 	"github.com/eagleql/xray-core/common/mux"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/session"
@@ -19,6 +22,8 @@ type Bridge struct {
 	dispatcher  routing.Dispatcher
 	tag         string
 	domain      string
+	id          string
+	weight      uint32
 	workers     []*BridgeWorker
 	monitorTask *task.Periodic
 }
@@ -36,6 +41,14 @@ func NewBridge(config *BridgeConfig, dispatcher routing.Dispatcher) (*Bridge, er
 		dispatcher: dispatcher,
 		tag:        config.Tag,
 		domain:     config.Domain,
+		id:         config.Id,
+		weight:     config.Weight,
+	}
+	if b.id == "" {
+		b.id = b.tag
+	}
+	if b.weight == 0 {
+		b.weight = 1
 	}
 	b.monitorTask = &task.Periodic{
 		Execute:  b.monitor,
@@ -48,7 +61,7 @@ func (b *Bridge) cleanup() {
 	var activeWorkers []*BridgeWorker
 
 	for _, w := range b.workers {
-		if w.IsActive() {
+		if w.IsActive() || w.IsPaused() {
 			activeWorkers = append(activeWorkers, w)
 		}
 	}
@@ -63,16 +76,20 @@ func (b *Bridge) monitor() error {
 
 	var numConnections uint32
 	var numWorker uint32
+	var numPaused uint32
 
 	for _, w := range b.workers {
 		if w.IsActive() {
 			numConnections += w.Connections()
 			numWorker++
+		} else if w.IsPaused() {
+			numPaused++
 		}
 	}
 
-	if numWorker == 0 || numConnections/numWorker > 16 {
-		worker, err := NewBridgeWorker(b.domain, b.tag, b.dispatcher)
+	// Workers paused by the portal are not replaced, as the portal wouldn't pick the new ones either.
+	if (numWorker == 0 && numPaused == 0) || (numWorker > 0 && numConnections/numWorker > 16) {
+		worker, err := NewBridgeWorker(b.domain, b.tag, b.id, b.weight, b.dispatcher)
 		if err != nil {
 			newError("failed to create bridge worker").Base(err).AtWarning().WriteToLog()
 			return nil
@@ -87,18 +104,32 @@ func (b *Bridge) Start() error {
 	return b.monitorTask.Start()
 }
 
+// Close stops creating workers, and tells the portals to drain the existing ones.
 func (b *Bridge) Close() error {
-	return b.monitorTask.Close()
+	err := b.monitorTask.Close()
+	for _, w := range b.workers {
+		w.drain()
+	}
+	return err
 }
 
 type BridgeWorker struct {
 	tag        string
+	id         string
+	weight     uint32
 	worker     *mux.ServerWorker
 	dispatcher routing.Dispatcher
-	state      Control_State
+
+	access  sync.Mutex
+	control buf.Writer
+
+	stateAccess sync.Mutex
+	state       Control_State
+	// controlClosed is true once the portal closes the control connection, after which the worker is not resumed.
+	controlClosed bool
 }
 
-func NewBridgeWorker(domain string, tag string, d routing.Dispatcher) (*BridgeWorker, error) {
+func NewBridgeWorker(domain string, tag string, id string, weight uint32, d routing.Dispatcher) (*BridgeWorker, error) {
 	ctx := context.Background()
 	ctx = session.ContextWithInbound(ctx, &session.Inbound{
 		Tag: tag,
@@ -115,6 +146,8 @@ func NewBridgeWorker(domain string, tag string, d routing.Dispatcher) (*BridgeWo
 	w := &BridgeWorker{
 		dispatcher: d,
 		tag:        tag,
+		id:         id,
+		weight:     weight,
 	}
 
 	worker, err := mux.NewServerWorker(context.Background(), w, link)
@@ -139,14 +172,58 @@ func (w *BridgeWorker) Close() error {
 }
 
 func (w *BridgeWorker) IsActive() bool {
+	w.stateAccess.Lock()
+	defer w.stateAccess.Unlock()
+
 	return w.state == Control_ACTIVE && !w.worker.Closed()
 }
 
+// IsPaused returns whether the portal drains the worker, but may resume it.
+func (w *BridgeWorker) IsPaused() bool {
+	w.stateAccess.Lock()
+	defer w.stateAccess.Unlock()
+
+	return w.state == Control_DRAIN && !w.controlClosed && !w.worker.Closed()
+}
+
 func (w *BridgeWorker) Connections() uint32 {
 	return w.worker.ActiveConnections()
 }
 
+// sendControl sends the state of the worker, along with the ID and weight of the bridge, to the portal.
+func (w *BridgeWorker) sendControl(state Control_State) error {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	if w.control == nil {
+		return newError("control connection not established")
+	}
+	msg := &Control{
+		State:    state,
+		BridgeId: w.id,
+		Weight:   w.weight,
+	}
+	msg.FillInRandom()
+	b, err := proto.Marshal(msg)
+	common.Must(err)
+	return w.control.WriteMultiBuffer(buf.MergeBytes(nil, b))
+}
+
+// drain tells the portal not to pick the worker any more.
+func (w *BridgeWorker) drain() {
+	if err := w.sendControl(Control_DRAIN); err != nil {
+		newError("failed to drain bridge worker").Base(err).WriteToLog()
+	}
+}
+
 func (w *BridgeWorker) handleInternalConn(link transport.Link) {
+	w.access.Lock()
+	w.control = link.Writer
+	w.access.Unlock()
+	if err := w.sendControl(Control_ACTIVE); err != nil {
+		newError("failed to send bridge ID").Base(err).WriteToLog()
+	}
+
 	go func() {
 		reader := link.Reader
 		for {
@@ -160,15 +237,23 @@ func (w *BridgeWorker) handleInternalConn(link transport.Link) {
 					newError("failed to parse proto message").Base(err).WriteToLog()
 					break
 				}
-				if ctl.State != w.state {
-					w.state = ctl.State
-				}
+				w.stateAccess.Lock()
+				w.state = ctl.State
+				w.stateAccess.Unlock()
 			}
+			buf.ReleaseMulti(mb)
 		}
+		w.stateAccess.Lock()
+		w.controlClosed = true
+		w.stateAccess.Unlock()
 	}()
 }
 
 func (w *BridgeWorker) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	if isXUDP(dest) {
+		return w.dispatchXUDP(ctx)
+	}
+
 	if !isInternalDomain(dest) {
 		ctx = session.ContextWithInbound(ctx, &session.Inbound{
 			Tag: w.tag,
@@ -190,3 +275,23 @@ func (w *BridgeWorker) Dispatch(ctx context.Context, dest net.Destination) (*tra
 		Writer: downlinkWriter,
 	}, nil
 }
+
+// dispatchXUDP serves a session carrying UDP packets in XUDP from the portal, dispatching the packets through the
+// worker itself.
+func (w *BridgeWorker) dispatchXUDP(ctx context.Context) (*transport.Link, error) {
+	opt := []pipe.Option{pipe.WithSizeLimit(16 * 1024)}
+	uplinkReader, uplinkWriter := pipe.New(opt...)
+	downlinkReader, downlinkWriter := pipe.New(opt...)
+
+	if _, err := mux.NewServerWorker(ctx, w, &transport.Link{
+		Reader: uplinkReader,
+		Writer: downlinkWriter,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &transport.Link{
+		Reader: downlinkReader,
+		Writer: uplinkWriter,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package command
+
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
+
+import (
+	"context"
+
+	grpc "google.golang.org/grpc"
+
+	"github.com/eagleql/xray-core/app/reverse"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/core"
+)
+
+// ReverseServer is an implementation of ReverseService.
+type ReverseServer struct {
+	V *core.Instance
+}
+
+func (s *ReverseServer) reverse() (*reverse.Reverse, error) {
+	r, ok := s.V.GetFeature((*reverse.Reverse)(nil)).(*reverse.Reverse)
+	if !ok || r == nil {
+		return nil, newError("reverse proxy not configured")
+	}
+	return r, nil
+}
+
+// ListBridges implements ReverseService.
+func (s *ReverseServer) ListBridges(ctx context.Context, request *ListBridgesRequest) (*ListBridgesResponse, error) {
+	r, err := s.reverse()
+	if err != nil {
+		return nil, err
+	}
+
+	portals := r.GetPortals()
+	if request.Tag != "" {
+		p := r.GetPortal(request.Tag)
+		if p == nil {
+			return nil, newError("portal ", request.Tag, " not found")
+		}
+		portals = []*reverse.Portal{p}
+	}
+
+	response := &ListBridgesResponse{}
+	for _, p := range portals {
+		portal := &Portal{
+			Tag: p.Tag(),
+		}
+		for _, b := range p.Bridges() {
+			bridge := &Bridge{
+				Id:       b.ID,
+				Weight:   b.Weight,
+				Draining: b.Draining,
+			}
+			for _, w := range b.Workers {
+				bridge.Workers = append(bridge.Workers, &Worker{
+					ActiveConnections: w.ActiveConnections,
+					TotalConnections:  w.TotalConnections,
+					Draining:          w.Draining,
+				})
+			}
+			portal.Bridges = append(portal.Bridges, bridge)
+		}
+		response.Portals = append(response.Portals, portal)
+	}
+	return response, nil
+}
+
+// DrainBridge implements ReverseService.
+func (s *ReverseServer) DrainBridge(ctx context.Context, request *DrainBridgeRequest) (*DrainBridgeResponse, error) {
+	r, err := s.reverse()
+	if err != nil {
+		return nil, err
+	}
+	p := r.GetPortal(request.Tag)
+	if p == nil {
+		return nil, newError("portal ", request.Tag, " not found")
+	}
+	p.DrainBridge(request.BridgeId, request.Cancel)
+	return &DrainBridgeResponse{}, nil
+}
+
+func (s *ReverseServer) mustEmbedUnimplementedReverseServiceServer() {}
+
+type service struct {
+	v *core.Instance
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterReverseServiceServer(server, &ReverseServer{
+		V: s.v,
+	})
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		s := core.MustFromContext(ctx)
+		return &service{v: s}, nil
+	}))
+}
@@ -0,0 +1,190 @@
+package reverse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/eagleql/xray-core/app/stats"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/mux"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/transport"
+	"github.com/eagleql/xray-core/transport/pipe"
+)
+
+// newTestClientWorker creates a mux client worker whose connection to the bridge is never read.
+func newTestClientWorker() *mux.ClientWorker {
+	uplinkReader, _ := pipe.New(pipe.WithoutSizeLimit())
+	_, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	client, err := mux.NewClientWorker(transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, mux.ClientStrategy{})
+	common.Must(err)
+	return client
+}
+
+// newTestPortalWorker creates a worker from the bridge with the given ID and weight, with connections in addition to
+// its control connection.
+func newTestPortalWorker(picker *StaticMuxPicker, id string, weight uint32, connections int) *PortalWorker {
+	w, err := NewPortalWorker(newTestClientWorker(), nil, picker.isDrained)
+	common.Must(err)
+	w.updateBridge(&Control{BridgeId: id, Weight: weight})
+	for i := 0; i < connections; i++ {
+		addTestConnection(w)
+	}
+	picker.AddWorker(w)
+	return w
+}
+
+func addTestConnection(w *PortalWorker) {
+	reader, _ := pipe.New(pipe.WithoutSizeLimit())
+	_, writer := pipe.New(pipe.WithoutSizeLimit())
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+	})
+	if !w.client.Dispatch(ctx, &transport.Link{Reader: reader, Writer: writer}) {
+		panic("failed to dispatch test connection")
+	}
+}
+
+func TestStaticPickerLeastLoad(t *testing.T) {
+	picker, err := NewStaticMuxPicker()
+	common.Must(err)
+	a := newTestPortalWorker(picker, "a", 1, 2)
+	b := newTestPortalWorker(picker, "b", 2, 4)
+
+	// 3 connections per weight on a, and 2.5 on b.
+	if client, _ := picker.PickAvailable(); client != b.client {
+		t.Error("expect the worker of bridge b to be picked")
+	}
+
+	addTestConnection(b)
+	addTestConnection(b)
+	if client, _ := picker.PickAvailable(); client != a.client {
+		t.Error("expect the worker of bridge a to be picked")
+	}
+}
+
+func TestStaticPickerDrain(t *testing.T) {
+	picker, err := NewStaticMuxPicker()
+	common.Must(err)
+	a := newTestPortalWorker(picker, "a", 1, 0)
+	b := newTestPortalWorker(picker, "b", 1, 5)
+
+	picker.drainBridge("a", false)
+	if client, _ := picker.PickAvailable(); client != b.client {
+		t.Error("expect the worker of the drained bridge not to be picked")
+	}
+	if bridges := picker.bridges(); len(bridges) != 2 || !bridges[0].Draining || bridges[1].Draining {
+		t.Error("expect only bridge a to be listed as draining")
+	}
+
+	// Without other workers available, the fallback still skips the drained bridge.
+	b.updateBridge(&Control{BridgeId: "b", Weight: 1, State: Control_DRAIN})
+	if client, _ := picker.PickAvailable(); client != b.client {
+		t.Error("expect the fallback not to pick the worker of the drained bridge")
+	}
+
+	picker.drainBridge("a", true)
+	if client, _ := picker.PickAvailable(); client != a.client {
+		t.Error("expect the worker of bridge a to be picked once the drain is cancelled")
+	}
+}
+
+func TestPortalWorkerHeartbeatDrain(t *testing.T) {
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	drained := true
+	w := &PortalWorker{
+		client:    newTestClientWorker(),
+		writer:    writer,
+		reader:    reader,
+		isDrained: func(string) bool { return drained },
+		weight:    1,
+	}
+
+	readState := func() Control_State {
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		defer buf.ReleaseMulti(mb)
+		var ctl Control
+		common.Must(proto.Unmarshal(mb[0].Bytes(), &ctl))
+		return ctl.State
+	}
+
+	for i := 0; i < 3; i++ {
+		common.Must(w.heartbeat())
+		if state := readState(); state != Control_DRAIN {
+			t.Error("expect the drained worker to be told to drain, but got ", state)
+		}
+	}
+	if w.IsDraining() || w.writer == nil {
+		t.Fatal("expect the drained worker to be kept for cancel")
+	}
+
+	drained = false
+	common.Must(w.heartbeat())
+	if state := readState(); state != Control_ACTIVE {
+		t.Error("expect the worker to be active again once the drain is cancelled, but got ", state)
+	}
+}
+
+func TestBridgeWorkerPause(t *testing.T) {
+	w := &BridgeWorker{}
+	reader, _ := pipe.New(pipe.WithoutSizeLimit())
+	_, writer := pipe.New(pipe.WithoutSizeLimit())
+	worker, err := mux.NewServerWorker(context.Background(), w, &transport.Link{Reader: reader, Writer: writer})
+	common.Must(err)
+	w.worker = worker
+
+	controlReader, controlWriter := pipe.New(pipe.WithoutSizeLimit())
+	_, portalWriter := pipe.New(pipe.WithoutSizeLimit())
+	w.handleInternalConn(transport.Link{Reader: controlReader, Writer: portalWriter})
+
+	sendState := func(state Control_State) {
+		ctl := &Control{State: state}
+		ctl.FillInRandom()
+		b, err := proto.Marshal(ctl)
+		common.Must(err)
+		common.Must(controlWriter.WriteMultiBuffer(buf.MergeBytes(nil, b)))
+	}
+	waitFor := func(condition func() bool, message string) {
+		for i := 0; i < 100 && !condition(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !condition() {
+			t.Error(message)
+		}
+	}
+
+	sendState(Control_DRAIN)
+	waitFor(func() bool { return w.IsPaused() && !w.IsActive() }, "expect the worker drained by the portal to be paused")
+	sendState(Control_ACTIVE)
+	waitFor(w.IsActive, "expect the worker to be active again")
+	sendState(Control_DRAIN)
+	controlWriter.Close()
+	waitFor(func() bool { return !w.IsPaused() && !w.IsActive() }, "expect the worker not to be resumed once the portal closes the control connection")
+}
+
+func TestPortalBridgeStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	p := &Portal{stats: m, tag: "portal"}
+
+	uplink := new(bridgeCounter)
+	downlink := new(bridgeCounter)
+	writer := &countingWriter{Writer: buf.Discard, counter: uplink}
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("before"))))
+
+	p.setCounters("a", uplink, downlink)
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("hello"))))
+
+	if c := m.GetCounter("portal>>>portal>>>bridge>>>a>>>traffic>>>uplink"); c == nil || c.Value() != 5 {
+		t.Error("expect 5 bytes counted for bridge a once its ID is known")
+	}
+	if c := m.GetCounter("portal>>>portal>>>bridge>>>a>>>traffic>>>downlink"); c == nil || c.Value() != 0 {
+		t.Error("expect no downlink traffic counted for bridge a")
+	}
+}
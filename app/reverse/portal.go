@@ -2,16 +2,20 @@ package reverse
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/dice"
 	"github.com/eagleql/xray-core/common/mux"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/common/xudp"
 	"github.com/eagleql/xray-core/features/outbound"
+	"github.com/eagleql/xray-core/features/stats"
 	"github.com/eagleql/xray-core/transport"
 	"github.com/eagleql/xray-core/transport/pipe"
 	"github.com/golang/protobuf/proto"
@@ -19,13 +23,16 @@ import (
 
 type Portal struct {
 	ohm    outbound.Manager
+	stats  stats.Manager
 	tag    string
 	domain string
 	picker *StaticMuxPicker
 	client *mux.ClientManager
+	// xudp is whether to carry UDP to bridges in XUDP, which bridges without XUDP support can't handle.
+	xudp bool
 }
 
-func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
+func NewPortal(config *PortalConfig, ohm outbound.Manager, sm stats.Manager) (*Portal, error) {
 	if config.Tag == "" {
 		return nil, newError("portal tag is empty")
 	}
@@ -38,15 +45,18 @@ func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
 	if err != nil {
 		return nil, err
 	}
+	picker.strategy = config.Strategy
 
 	return &Portal{
 		ohm:    ohm,
+		stats:  sm,
 		tag:    config.Tag,
 		domain: config.Domain,
 		picker: picker,
 		client: &mux.ClientManager{
 			Picker: picker,
 		},
+		xudp: config.Xudp,
 	}, nil
 }
 
@@ -61,6 +71,22 @@ func (p *Portal) Close() error {
 	return p.ohm.RemoveHandler(context.Background(), p.tag)
 }
 
+// Tag returns the tag of the portal.
+func (p *Portal) Tag() string {
+	return p.tag
+}
+
+// Bridges returns the bridges connected to the portal, sorted by ID.
+func (p *Portal) Bridges() []*BridgeInfo {
+	return p.picker.bridges()
+}
+
+// DrainBridge stops picking the workers of the bridge, and tells the bridge to drain them. The workers stay connected
+// until cancel, which resumes picking them and tells the bridge they are active again.
+func (p *Portal) DrainBridge(id string, cancel bool) {
+	p.picker.drainBridge(id, cancel)
+}
+
 func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) error {
 	outboundMeta := session.OutboundFromContext(ctx)
 	if outboundMeta == nil {
@@ -68,12 +94,20 @@ func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) err
 	}
 
 	if isDomain(outboundMeta.Target, p.domain) {
-		muxClient, err := mux.NewClientWorker(*link, mux.ClientStrategy{})
+		uplink := new(bridgeCounter)
+		downlink := new(bridgeCounter)
+		muxClient, err := mux.NewClientWorker(transport.Link{
+			Reader: &countingReader{Reader: link.Reader, counter: downlink},
+			Writer: &countingWriter{Writer: link.Writer, counter: uplink},
+		}, mux.ClientStrategy{})
 		if err != nil {
 			return newError("failed to create mux client worker").Base(err).AtWarning()
 		}
 
-		worker, err := NewPortalWorker(muxClient)
+		onBridge := func(id string) {
+			p.setCounters(id, uplink, downlink)
+		}
+		worker, err := NewPortalWorker(muxClient, onBridge, p.picker.isDrained)
 		if err != nil {
 			return newError("failed to create portal worker").Base(err)
 		}
@@ -82,9 +116,71 @@ func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) err
 		return nil
 	}
 
+	// DNS and QUIC talk to one server and gain nothing from full-cone NAT, so they keep a mux session to their target,
+	// the same as in VLESS and VMess.
+	if target := outboundMeta.Target; p.xudp && target.Network == net.Network_UDP && target.Port != 53 && target.Port != 443 {
+		return p.dispatchXUDP(ctx, link)
+	}
+
 	return p.client.Dispatch(ctx, link)
 }
 
+// dispatchXUDP carries the UDP packets of the link in XUDP, along with their addresses, so that the bridge sends them
+// all from one socket and returns responses from any address.
+func (p *Portal) dispatchXUDP(ctx context.Context, link *transport.Link) error {
+	target := session.OutboundFromContext(ctx).Target
+
+	opt := []pipe.Option{pipe.WithSizeLimit(16 * 1024)}
+	uplinkReader, uplinkWriter := pipe.New(opt...)
+	downlinkReader, downlinkWriter := pipe.New(opt...)
+
+	xudpCtx := session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: xudpDestination,
+	})
+	if err := p.client.Dispatch(xudpCtx, &transport.Link{
+		Reader: uplinkReader,
+		Writer: downlinkWriter,
+	}); err != nil {
+		return err
+	}
+
+	requestDone := func() error {
+		if err := buf.Copy(link.Reader, xudp.NewPacketWriter(uplinkWriter, target)); err != nil {
+			return newError("failed to transfer request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		if err := buf.Copy(xudp.NewPacketReader(&buf.BufferedReader{Reader: downlinkReader}), link.Writer); err != nil {
+			return newError("failed to transfer response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(uplinkWriter)), task.OnSuccess(responseDone, task.Close(link.Writer))); err != nil {
+		common.Interrupt(uplinkWriter)
+		common.Interrupt(downlinkReader)
+		common.Interrupt(link.Reader)
+		return newError("XUDP connection ends").Base(err)
+	}
+	return nil
+}
+
+// setCounters sets the traffic counters of a worker once the ID of its bridge is known.
+func (p *Portal) setCounters(id string, uplink, downlink *bridgeCounter) {
+	if p.stats == nil || id == "" {
+		return
+	}
+	name := "portal>>>" + p.tag + ">>>bridge>>>" + id + ">>>traffic>>>"
+	if c, _ := stats.GetOrRegisterCounter(p.stats, name+"uplink"); c != nil {
+		uplink.set(c)
+	}
+	if c, _ := stats.GetOrRegisterCounter(p.stats, name+"downlink"); c != nil {
+		downlink.set(c)
+	}
+}
+
 type Outbound struct {
 	portal *Portal
 	tag    string
@@ -109,14 +205,34 @@ func (o *Outbound) Close() error {
 	return nil
 }
 
+// BridgeInfo is the state of a bridge connected to a portal.
+type BridgeInfo struct {
+	ID       string
+	Weight   uint32
+	Draining bool
+	Workers  []*WorkerInfo
+}
+
+// WorkerInfo is the state of a worker connection from a bridge.
+type WorkerInfo struct {
+	ActiveConnections uint32
+	TotalConnections  uint32
+	Draining          bool
+}
+
 type StaticMuxPicker struct {
-	access  sync.Mutex
-	workers []*PortalWorker
-	cTask   *task.Periodic
+	access   sync.Mutex
+	workers  []*PortalWorker
+	cTask    *task.Periodic
+	strategy PortalConfig_Strategy
+	// drained is the IDs of bridges drained by the portal.
+	drained map[string]bool
 }
 
 func NewStaticMuxPicker() (*StaticMuxPicker, error) {
-	p := &StaticMuxPicker{}
+	p := &StaticMuxPicker{
+		drained: make(map[string]bool),
+	}
 	p.cTask = &task.Periodic{
 		Execute:  p.cleanup,
 		Interval: time.Second * 30,
@@ -151,13 +267,17 @@ func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
 		return nil, newError("empty worker list")
 	}
 
+	if w := p.pickBridgeWorker(); w != nil {
+		return w.client, nil
+	}
+
 	var minIdx int = -1
 	var minConn uint32 = 9999
 	for i, w := range p.workers {
-		if w.draining {
+		if w.IsFull() {
 			continue
 		}
-		if w.client.Closed() {
+		if id, _, _ := w.Bridge(); p.drained[id] {
 			continue
 		}
 		if w.client.ActiveConnections() < minConn {
@@ -166,18 +286,6 @@ func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
 		}
 	}
 
-	if minIdx == -1 {
-		for i, w := range p.workers {
-			if w.IsFull() {
-				continue
-			}
-			if w.client.ActiveConnections() < minConn {
-				minConn = w.client.ActiveConnections()
-				minIdx = i
-			}
-		}
-	}
-
 	if minIdx != -1 {
 		return p.workers[minIdx].client, nil
 	}
@@ -185,6 +293,65 @@ func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
 	return nil, newError("no mux client worker available")
 }
 
+// bridgeLoad is the load of the available workers of a bridge.
+type bridgeLoad struct {
+	weight      uint32
+	connections uint32
+	worker      *PortalWorker
+}
+
+// pickBridgeWorker picks a bridge by the strategy, and then the worker with the least connections of the bridge.
+// It returns nil if no worker is available.
+func (p *StaticMuxPicker) pickBridgeWorker() *PortalWorker {
+	var ids []string
+	loads := make(map[string]*bridgeLoad)
+	for _, w := range p.workers {
+		if w.IsDraining() || w.IsFull() {
+			continue
+		}
+		id, weight, bridgeDraining := w.Bridge()
+		if bridgeDraining || p.drained[id] {
+			continue
+		}
+		load, found := loads[id]
+		if !found {
+			load = &bridgeLoad{weight: weight}
+			loads[id] = load
+			ids = append(ids, id)
+		}
+		connections := w.client.ActiveConnections()
+		load.connections += connections
+		if load.worker == nil || connections < load.worker.client.ActiveConnections() {
+			load.worker = w
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if p.strategy == PortalConfig_WEIGHTED {
+		var total int
+		for _, id := range ids {
+			total += int(loads[id].weight)
+		}
+		r := dice.Roll(total)
+		for _, id := range ids {
+			if r -= int(loads[id].weight); r < 0 {
+				return loads[id].worker
+			}
+		}
+	}
+
+	picked := loads[ids[0]]
+	for _, id := range ids[1:] {
+		// Compare connections per weight without division.
+		if load := loads[id]; uint64(load.connections)*uint64(picked.weight) < uint64(picked.connections)*uint64(load.weight) {
+			picked = load
+		}
+	}
+	return picked.worker
+}
+
 func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 	p.access.Lock()
 	defer p.access.Unlock()
@@ -192,15 +359,77 @@ func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 	p.workers = append(p.workers, worker)
 }
 
+func (p *StaticMuxPicker) isDrained(id string) bool {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	return p.drained[id]
+}
+
+func (p *StaticMuxPicker) drainBridge(id string, cancel bool) {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	if cancel {
+		delete(p.drained, id)
+	} else {
+		p.drained[id] = true
+	}
+}
+
+func (p *StaticMuxPicker) bridges() []*BridgeInfo {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	var bridges []*BridgeInfo
+	index := make(map[string]*BridgeInfo)
+	for _, w := range p.workers {
+		if w.Closed() {
+			continue
+		}
+		id, weight, bridgeDraining := w.Bridge()
+		bridge, found := index[id]
+		if !found {
+			bridge = &BridgeInfo{
+				ID:       id,
+				Weight:   weight,
+				Draining: p.drained[id],
+			}
+			index[id] = bridge
+			bridges = append(bridges, bridge)
+		}
+		bridge.Workers = append(bridge.Workers, &WorkerInfo{
+			ActiveConnections: w.client.ActiveConnections(),
+			TotalConnections:  w.client.TotalConnections(),
+			Draining:          w.IsDraining() || bridgeDraining,
+		})
+	}
+	sort.Slice(bridges, func(i, j int) bool {
+		return bridges[i].ID < bridges[j].ID
+	})
+	return bridges
+}
+
 type PortalWorker struct {
 	client   *mux.ClientWorker
 	control  *task.Periodic
 	writer   buf.Writer
 	reader   buf.Reader
 	draining bool
+
+	// onBridge is called when the ID of the bridge is known.
+	onBridge func(id string)
+	// isDrained returns whether the bridge is drained by the portal.
+	isDrained func(id string) bool
+
+	access         sync.RWMutex
+	bridgeID       string
+	weight         uint32
+	bridgeDraining bool
 }
 
-func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
+// NewPortalWorker creates a worker on the connection from a bridge. The callbacks may be nil.
+func NewPortalWorker(client *mux.ClientWorker, onBridge func(id string), isDrained func(id string) bool) (*PortalWorker, error) {
 	opt := []pipe.Option{pipe.WithSizeLimit(16 * 1024)}
 	uplinkReader, uplinkWriter := pipe.New(opt...)
 	downlinkReader, downlinkWriter := pipe.New(opt...)
@@ -217,32 +446,86 @@ func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
 		return nil, newError("unable to dispatch control connection")
 	}
 	w := &PortalWorker{
-		client: client,
-		reader: downlinkReader,
-		writer: uplinkWriter,
+		client:    client,
+		reader:    downlinkReader,
+		writer:    uplinkWriter,
+		onBridge:  onBridge,
+		isDrained: isDrained,
+		weight:    1,
 	}
 	w.control = &task.Periodic{
 		Execute:  w.heartbeat,
 		Interval: time.Second * 2,
 	}
 	w.control.Start()
+	go w.readControl(downlinkReader)
 	return w, nil
 }
 
+// readControl reads the control messages from the bridge.
+func (w *PortalWorker) readControl(reader buf.Reader) {
+	for {
+		mb, err := reader.ReadMultiBuffer()
+		if err != nil {
+			return
+		}
+		for _, b := range mb {
+			var ctl Control
+			if err := proto.Unmarshal(b.Bytes(), &ctl); err != nil {
+				newError("failed to parse proto message").Base(err).WriteToLog()
+				continue
+			}
+			w.updateBridge(&ctl)
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
+
+func (w *PortalWorker) updateBridge(ctl *Control) {
+	w.access.Lock()
+	changed := ctl.BridgeId != w.bridgeID
+	w.bridgeID = ctl.BridgeId
+	w.weight = ctl.Weight
+	if w.weight == 0 {
+		w.weight = 1
+	}
+	w.bridgeDraining = ctl.State == Control_DRAIN
+	w.access.Unlock()
+
+	if changed && w.onBridge != nil {
+		w.onBridge(ctl.BridgeId)
+	}
+}
+
+// Bridge returns the ID and weight of the bridge of the worker, and whether the bridge is draining the worker.
+func (w *PortalWorker) Bridge() (string, uint32, bool) {
+	w.access.RLock()
+	defer w.access.RUnlock()
+
+	return w.bridgeID, w.weight, w.bridgeDraining
+}
+
 func (w *PortalWorker) heartbeat() error {
 	if w.client.Closed() {
 		return newError("client worker stopped")
 	}
 
-	if w.draining || w.writer == nil {
+	if w.IsDraining() || w.writer == nil {
 		return newError("already disposed")
 	}
 
 	msg := &Control{}
 	msg.FillInRandom()
 
+	// A worker of a bridge drained by the portal is told to drain on every heartbeat, and to be active again once
+	// the drain is cancelled. A worker with too many connections is drained for good.
+	if id, _, _ := w.Bridge(); w.isDrained != nil && w.isDrained(id) {
+		msg.State = Control_DRAIN
+	}
 	if w.client.TotalConnections() > 256 {
+		w.access.Lock()
 		w.draining = true
+		w.access.Unlock()
 		msg.State = Control_DRAIN
 
 		defer func() {
@@ -258,6 +541,14 @@ func (w *PortalWorker) heartbeat() error {
 	return w.writer.WriteMultiBuffer(mb)
 }
 
+// IsDraining returns whether the portal is draining the worker.
+func (w *PortalWorker) IsDraining() bool {
+	w.access.RLock()
+	defer w.access.RUnlock()
+
+	return w.draining
+}
+
 func (w *PortalWorker) IsFull() bool {
 	return w.client.IsFull()
 }
@@ -265,3 +556,58 @@ func (w *PortalWorker) IsFull() bool {
 func (w *PortalWorker) Closed() bool {
 	return w.client.Closed()
 }
+
+// bridgeCounter counts the traffic of a worker, once the ID of its bridge is known.
+type bridgeCounter struct {
+	access  sync.RWMutex
+	counter stats.Counter
+}
+
+func (c *bridgeCounter) set(counter stats.Counter) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.counter = counter
+}
+
+func (c *bridgeCounter) add(n int32) {
+	c.access.RLock()
+	defer c.access.RUnlock()
+
+	if c.counter != nil {
+		c.counter.Add(int64(n))
+	}
+}
+
+type countingReader struct {
+	buf.Reader
+	counter *bridgeCounter
+}
+
+func (r *countingReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.counter.add(mb.Len())
+	return mb, err
+}
+
+func (r *countingReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+type countingWriter struct {
+	buf.Writer
+	counter *bridgeCounter
+}
+
+func (w *countingWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.counter.add(mb.Len())
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *countingWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+func (w *countingWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
@@ -11,12 +11,16 @@ import (
 	core "github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/outbound"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
 )
 
 const (
 	internalDomain = "reverse.internal.example.com"
 )
 
+// xudpDestination is the destination of mux sessions carrying UDP packets in XUDP, the same as in VLESS and VMess.
+var xudpDestination = net.TCPDestination(net.DomainAddress("v1.mux.cool"), net.Port(666))
+
 func isDomain(dest net.Destination, domain string) bool {
 	return dest.Address.Family().IsDomain() && dest.Address.Domain() == domain
 }
@@ -25,11 +29,15 @@ func isInternalDomain(dest net.Destination) bool {
 	return isDomain(dest, internalDomain)
 }
 
+func isXUDP(dest net.Destination) bool {
+	return dest.Address == xudpDestination.Address
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		r := new(Reverse)
-		if err := core.RequireFeatures(ctx, func(d routing.Dispatcher, om outbound.Manager) error {
-			return r.Init(config.(*Config), d, om)
+		if err := core.RequireFeatures(ctx, func(d routing.Dispatcher, om outbound.Manager, sm stats.Manager) error {
+			return r.Init(config.(*Config), d, om, sm)
 		}); err != nil {
 			return nil, err
 		}
@@ -42,7 +50,7 @@ type Reverse struct {
 	portals []*Portal
 }
 
-func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manager) error {
+func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manager, sm stats.Manager) error {
 	for _, bConfig := range config.BridgeConfig {
 		b, err := NewBridge(bConfig, d)
 		if err != nil {
@@ -52,7 +60,7 @@ func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manage
 	}
 
 	for _, pConfig := range config.PortalConfig {
-		p, err := NewPortal(pConfig, ohm)
+		p, err := NewPortal(pConfig, ohm, sm)
 		if err != nil {
 			return err
 		}
@@ -62,6 +70,21 @@ func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manage
 	return nil
 }
 
+// GetPortal returns the portal with the tag, nil if not found.
+func (r *Reverse) GetPortal(tag string) *Portal {
+	for _, p := range r.portals {
+		if p.tag == tag {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetPortals returns all the portals.
+func (r *Reverse) GetPortals() []*Portal {
+	return r.portals
+}
+
 func (r *Reverse) Type() interface{} {
 	return (*Reverse)(nil)
 }
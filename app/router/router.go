@@ -4,6 +4,7 @@ package router
 
 import (
 	"context"
+	"sync"
 
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/core"
@@ -15,10 +16,12 @@ import (
 
 // Router is an implementation of routing.Router.
 type Router struct {
+	access         sync.RWMutex
 	domainStrategy Config_DomainStrategy
 	rules          []*Rule
 	balancers      map[string]*Balancer
 	dns            dns.Client
+	ohm            outbound.Manager
 }
 
 // Route is an implementation of routing.Route.
@@ -32,6 +35,7 @@ type Route struct {
 func (r *Router) Init(config *Config, d dns.Client, ohm outbound.Manager) error {
 	r.domainStrategy = config.DomainStrategy
 	r.dns = d
+	r.ohm = ohm
 
 	r.balancers = make(map[string]*Balancer, len(config.BalancingRule))
 	for _, rule := range config.BalancingRule {
@@ -80,24 +84,28 @@ func (r *Router) PickRoute(ctx routing.Context) (routing.Route, error) {
 }
 
 func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context, error) {
-	if r.domainStrategy == Config_IpOnDemand {
+	r.access.RLock()
+	domainStrategy, rules := r.domainStrategy, r.rules
+	r.access.RUnlock()
+
+	if domainStrategy == Config_IpOnDemand {
 		ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 	}
 
-	for _, rule := range r.rules {
+	for _, rule := range rules {
 		if rule.Apply(ctx) {
 			return rule, ctx, nil
 		}
 	}
 
-	if r.domainStrategy != Config_IpIfNonMatch || len(ctx.GetTargetDomain()) == 0 {
+	if domainStrategy != Config_IpIfNonMatch || len(ctx.GetTargetDomain()) == 0 {
 		return nil, ctx, common.ErrNoClue
 	}
 
 	ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 
 	// Try applying rules again if we have IPs.
-	for _, rule := range r.rules {
+	for _, rule := range rules {
 		if rule.Apply(ctx) {
 			return rule, ctx, nil
 		}
@@ -106,6 +114,26 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 	return nil, ctx, common.ErrNoClue
 }
 
+// Reload implements features.Reloadable.
+func (r *Router) Reload(config interface{}) error {
+	c, ok := config.(*Config)
+	if !ok {
+		return common.ErrNoClue
+	}
+	nr := new(Router)
+	if err := nr.Init(c, r.dns, r.ohm); err != nil {
+		return newError("failed to reload routing rules").Base(err)
+	}
+
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	r.domainStrategy = nr.domainStrategy
+	r.rules = nr.rules
+	r.balancers = nr.balancers
+	return nil
+}
+
 // Start implements common.Runnable.
 func (*Router) Start() error {
 	return nil
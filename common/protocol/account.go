@@ -1,10 +1,18 @@
 package protocol
 
+import "github.com/golang/protobuf/proto"
+
 // Account is a user identity used for authentication.
 type Account interface {
 	Equals(Account) bool
 }
 
+// ProtoAccount is an Account that can be converted back into its proto form.
+type ProtoAccount interface {
+	Account
+	ToProto() proto.Message
+}
+
 // AsAccount is an object can be converted into account.
 type AsAccount interface {
 	AsAccount() (Account, error)
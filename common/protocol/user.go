@@ -1,5 +1,11 @@
 package protocol
 
+import (
+	"time"
+
+	"github.com/eagleql/xray-core/common/serial"
+)
+
 func (u *User) GetTypedAccount() (Account, error) {
 	if u.GetAccount() == nil {
 		return nil, newError("Account missing").AtWarning()
@@ -24,16 +30,49 @@ func (u *User) ToMemoryUser() (*MemoryUser, error) {
 		return nil, err
 	}
 	return &MemoryUser{
-		Account: account,
-		Email:   u.Email,
-		Level:   u.Level,
+		Account:           account,
+		Email:             u.Email,
+		Level:             u.Level,
+		ExpiryTime:        u.ExpiryTime,
+		TotalTrafficLimit: u.TotalTrafficLimit,
 	}, nil
 }
 
+// ToProtoUser converts a MemoryUser back into User.
+func ToProtoUser(mu *MemoryUser) *User {
+	if mu == nil {
+		return nil
+	}
+	u := &User{
+		Level:             mu.Level,
+		Email:             mu.Email,
+		ExpiryTime:        mu.ExpiryTime,
+		TotalTrafficLimit: mu.TotalTrafficLimit,
+	}
+	if account, ok := mu.Account.(ProtoAccount); ok {
+		u.Account = serial.ToTypedMessage(account.ToProto())
+	}
+	return u
+}
+
 // MemoryUser is a parsed form of User, to reduce number of parsing of Account proto.
 type MemoryUser struct {
 	// Account is the parsed account of the protocol.
 	Account Account
 	Email   string
 	Level   uint32
+	// ExpiryTime is the Unix time in seconds after which the user is rejected. 0 for never.
+	ExpiryTime int64
+	// TotalTrafficLimit is the maximum total traffic of the user in bytes. 0 for unlimited.
+	TotalTrafficLimit uint64
+}
+
+// IsExpired returns true if the user has an expiry time which is before the given time.
+func (u *MemoryUser) IsExpired(now time.Time) bool {
+	return u.ExpiryTime > 0 && now.Unix() >= u.ExpiryTime
+}
+
+// IsOverQuota returns true if the user has a traffic limit which the given traffic reaches.
+func (u *MemoryUser) IsOverQuota(traffic int64) bool {
+	return u.TotalTrafficLimit > 0 && traffic >= 0 && uint64(traffic) >= u.TotalTrafficLimit
 }
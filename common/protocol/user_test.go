@@ -0,0 +1,69 @@
+package protocol_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/eagleql/xray-core/common"
+	. "github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/proxy/vless"
+)
+
+func TestMemoryUserExpiry(t *testing.T) {
+	now := time.Now()
+
+	user := &MemoryUser{}
+	if user.IsExpired(now) {
+		t.Error("expect user without expiry time not to expire")
+	}
+
+	user.ExpiryTime = now.Add(time.Hour).Unix()
+	if user.IsExpired(now) {
+		t.Error("expect user not to expire before its expiry time")
+	}
+
+	user.ExpiryTime = now.Add(-time.Hour).Unix()
+	if !user.IsExpired(now) {
+		t.Error("expect user to expire after its expiry time")
+	}
+}
+
+func TestMemoryUserQuota(t *testing.T) {
+	user := &MemoryUser{}
+	if user.IsOverQuota(1 << 40) {
+		t.Error("expect user without traffic limit not to be over quota")
+	}
+
+	user.TotalTrafficLimit = 1024
+	if user.IsOverQuota(1023) {
+		t.Error("expect user within quota")
+	}
+	if !user.IsOverQuota(1024) {
+		t.Error("expect user over quota")
+	}
+}
+
+func TestToProtoUser(t *testing.T) {
+	user := &User{
+		Level:             1,
+		Email:             "love@example.com",
+		ExpiryTime:        1700000000,
+		TotalTrafficLimit: 1024,
+		Account: serial.ToTypedMessage(&vless.Account{
+			Id:   "27848739-7e62-4138-9fd3-098a63964b6b",
+			Flow: "xtls-rprx-direct",
+		}),
+	}
+	mUser, err := user.ToMemoryUser()
+	common.Must(err)
+
+	if actual := ToProtoUser(mUser); !proto.Equal(actual, user) {
+		t.Error("expect ", user, " but got ", actual)
+	}
+	if ToProtoUser(nil) != nil {
+		t.Error("expect nil user to be converted to nil")
+	}
+}
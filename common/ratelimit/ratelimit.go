@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by all connections it is attached to.
+// Reservations may overdraw the bucket, in which case the caller is expected
+// to wait until the debt is paid back.
+type Limiter struct {
+	access sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// New creates a new Limiter with the given rate in bytes per second and burst in bytes.
+// If burst is not positive, it defaults to the rate.
+func New(rate int64, burst int64) *Limiter {
+	l := &Limiter{}
+	l.SetLimit(rate, burst)
+	l.tokens = l.burst
+	return l
+}
+
+// SetLimit updates the rate and burst of the limiter, keeping the tokens already accumulated.
+func (l *Limiter) SetLimit(rate int64, burst int64) {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	if burst <= 0 {
+		burst = rate
+	}
+	l.rate = float64(rate)
+	l.burst = float64(burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Limit returns the current rate and burst of the limiter.
+func (l *Limiter) Limit() (int64, int64) {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	return int64(l.rate), int64(l.burst)
+}
+
+// Reserve takes n tokens from the bucket and returns how long the caller has to wait before consuming them.
+func (l *Limiter) Reserve(n int64) time.Duration {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait takes n tokens from the bucket and blocks until they are available or ctx is done. It returns the time spent
+// waiting. If ctx is done first, the tokens are given back and the error of ctx is returned.
+func (l *Limiter) Wait(ctx context.Context, n int64) (time.Duration, error) {
+	d := l.Reserve(n)
+	if d <= 0 {
+		return 0, nil
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return d, nil
+	case <-ctx.Done():
+		l.refund(n)
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// refund gives back n tokens taken by an abandoned reservation.
+func (l *Limiter) refund(n int64) {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	l.tokens += float64(n)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/eagleql/xray-core/common/ratelimit"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	l := New(1000, 2000)
+
+	if d := l.Reserve(2000); d != 0 {
+		t.Error("expect no delay within burst, but got ", d)
+	}
+
+	d := l.Reserve(500)
+	if d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Error("expect about 500ms delay, but got ", d)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := New(0, 0)
+
+	if d := l.Reserve(1 << 30); d != 0 {
+		t.Error("expect no delay, but got ", d)
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l := New(1000, 0)
+	l.SetLimit(2000, 4000)
+
+	rate, burst := l.Limit()
+	if rate != 2000 || burst != 4000 {
+		t.Error("unexpected limit: ", rate, " ", burst)
+	}
+}
+
+func TestLimiterWaitCancel(t *testing.T) {
+	l := New(1000, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := l.Wait(ctx, 10000); err == nil {
+		t.Error("expect error when context is done")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Error("expect wait to be interrupted, but took ", d)
+	}
+	if d := l.Reserve(100); d > 100*time.Millisecond {
+		t.Error("expect tokens to be given back, but got delay ", d)
+	}
+}
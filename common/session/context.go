@@ -11,6 +11,7 @@ const (
 	contentSessionKey
 	muxPreferedSessionKey
 	sockoptSessionKey
+	loopbackSessionKey
 )
 
 // ContextWithID returns a new context with the given ID.
@@ -84,3 +85,17 @@ func SockoptFromContext(ctx context.Context) *Sockopt {
 	}
 	return nil
 }
+
+// ContextWithLoopback returns a new context marked as dispatched again by a loopback outbound.
+func ContextWithLoopback(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loopbackSessionKey, true)
+}
+
+// LoopbackFromContext returns whether the context is dispatched again by a loopback outbound, in which case the
+// traffic has been accounted for its user by the first dispatch already.
+func LoopbackFromContext(ctx context.Context) bool {
+	if val, ok := ctx.Value(loopbackSessionKey).(bool); ok {
+		return val
+	}
+	return false
+}
@@ -0,0 +1,465 @@
+package core
+
+import (
+	"github.com/golang/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/features"
+	"github.com/eagleql/xray-core/features/inbound"
+	"github.com/eagleql/xray-core/features/outbound"
+)
+
+// ConfigReloader loads the latest config of an Instance, usually from the files it was started with.
+type ConfigReloader func() (*Config, error)
+
+// SetConfigReloader sets the ConfigReloader used by ReloadConfig.
+func (s *Instance) SetConfigReloader(reloader ConfigReloader) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	s.reloader = reloader
+}
+
+// ReloadConfig loads the config with the ConfigReloader of the Instance, and applies it with Reload.
+func (s *Instance) ReloadConfig() error {
+	s.access.Lock()
+	reloader := s.reloader
+	s.access.Unlock()
+
+	if reloader == nil {
+		return newError("config reloading is not supported")
+	}
+	config, err := reloader()
+	if err != nil {
+		return newError("failed to load config").Base(err)
+	}
+	return s.Reload(config)
+}
+
+// Reload applies the given config to the running Instance.
+// Only the inbound and outbound handlers whose configs have changed are recreated, so unchanged handlers keep their connections.
+// Changed app settings are applied to the features that implement features.Reloadable, such as routing and DNS.
+// Other changes, such as adding or removing an app, need a restart and are ignored with a warning.
+// All new handlers are created before anything is changed, and the changes already applied are rolled back if a later
+// one fails, so the Instance keeps running with its previous config when Reload returns an error.
+func (s *Instance) Reload(config *Config) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	outbounds, err := s.planOutbounds(config.Outbound)
+	if err != nil {
+		return newError("failed to reload outbounds").Base(err)
+	}
+	inbounds, err := s.planInbounds(config.Inbound)
+	if err != nil {
+		outbounds.discard()
+		return newError("failed to reload inbounds").Base(err)
+	}
+
+	rollbackApps, err := s.reloadApps(config.App)
+	if err != nil {
+		outbounds.discard()
+		inbounds.discard()
+		return newError("failed to reload apps").Base(err)
+	}
+	if err := outbounds.apply(); err != nil {
+		outbounds.rollback()
+		inbounds.discard()
+		rollbackApps()
+		return newError("failed to reload outbounds").Base(err)
+	}
+	if err := inbounds.apply(); err != nil {
+		inbounds.rollback()
+		outbounds.rollback()
+		rollbackApps()
+		return newError("failed to reload inbounds").Base(err)
+	}
+	s.config = config
+
+	newError("config reloaded").AtWarning().WriteToLog()
+	return nil
+}
+
+// reloadApps applies the changed app settings. It returns a function that restores the previous settings.
+func (s *Instance) reloadApps(apps []*serial.TypedMessage) (func(), error) {
+	running := make(map[string]*serial.TypedMessage)
+	if s.config != nil {
+		for _, app := range s.config.App {
+			running[app.Type] = app
+		}
+	}
+
+	var reloaded []*serial.TypedMessage
+	rollback := func() {
+		for _, app := range reloaded {
+			settings, err := app.GetInstance()
+			if err == nil {
+				_, err = s.reloadFeature(settings)
+			}
+			if err != nil {
+				newError("failed to restore app ", app.Type).Base(err).AtError().WriteToLog()
+			}
+		}
+	}
+
+	for _, app := range apps {
+		old, found := running[app.Type]
+		delete(running, app.Type)
+		if !found {
+			newError("new app ", app.Type, " is ignored, restart to apply it").AtWarning().WriteToLog()
+			continue
+		}
+		if configEqual(old, app) {
+			continue
+		}
+		settings, err := app.GetInstance()
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		ok, err := s.reloadFeature(settings)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		if !ok {
+			newError("changes of app ", app.Type, " are ignored, restart to apply them").AtWarning().WriteToLog()
+			continue
+		}
+		reloaded = append(reloaded, old)
+		newError("app ", app.Type, " reloaded").AtInfo().WriteToLog()
+	}
+
+	for t := range running {
+		newError("removal of app ", t, " is ignored, restart to apply it").AtWarning().WriteToLog()
+	}
+	return rollback, nil
+}
+
+func (s *Instance) reloadFeature(settings interface{}) (bool, error) {
+	for _, f := range s.features {
+		r, ok := f.(features.Reloadable)
+		if !ok {
+			continue
+		}
+		err := r.Reload(settings)
+		if err == common.ErrNoClue {
+			continue
+		}
+		return true, err
+	}
+	return false, nil
+}
+
+// taggedHandler is the common part of inbound and outbound handlers.
+type taggedHandler interface {
+	common.Runnable
+	Tag() string
+}
+
+// handlerReload is a planned change of the inbound or outbound handlers, whose new handlers are already created.
+type handlerReload struct {
+	kind string
+	// removed are the tags of the running handlers to remove, and old are their configs to recreate them on rollback.
+	removed []string
+	old     []proto.Message
+	added   []taggedHandler
+
+	create func(proto.Message) (taggedHandler, error)
+	add    func(taggedHandler) error
+	remove func(string) error
+
+	removedCount int
+	addedCount   int
+}
+
+// apply removes and adds the handlers.
+func (r *handlerReload) apply() error {
+	for _, tag := range r.removed {
+		newError("removing ", r.kind, " ", tag).AtInfo().WriteToLog()
+		if err := r.remove(tag); err != nil {
+			return newError("failed to remove ", r.kind, " ", tag).Base(err)
+		}
+		r.removedCount++
+	}
+	for _, handler := range r.added {
+		newError("adding ", r.kind, " ", handler.Tag()).AtInfo().WriteToLog()
+		// A handler that fails to start is still added, so it has to be removed on rollback as well.
+		r.addedCount++
+		if err := r.add(handler); err != nil {
+			return newError("failed to add ", r.kind, " ", handler.Tag()).Base(err)
+		}
+	}
+	return nil
+}
+
+// rollback undoes apply, recreating the removed handlers from their configs.
+func (r *handlerReload) rollback() {
+	for _, handler := range r.added[:r.addedCount] {
+		if err := r.remove(handler.Tag()); err != nil && err != common.ErrNoClue {
+			newError("failed to remove ", r.kind, " ", handler.Tag()).Base(err).AtError().WriteToLog()
+		}
+	}
+	for _, handler := range r.added[r.addedCount:] {
+		common.Close(handler)
+	}
+	for _, config := range r.old[:r.removedCount] {
+		handler, err := r.create(config)
+		if err == nil {
+			err = r.add(handler)
+		}
+		if err != nil {
+			newError("failed to restore ", r.kind).Base(err).AtError().WriteToLog()
+		}
+	}
+}
+
+// discard closes the new handlers when the change is not applied.
+func (r *handlerReload) discard() {
+	for _, handler := range r.added {
+		common.Close(handler)
+	}
+}
+
+func (s *Instance) planInbounds(configs []*InboundHandlerConfig) (*handlerReload, error) {
+	ihm := s.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	r := &handlerReload{
+		kind: "inbound",
+		create: func(config proto.Message) (taggedHandler, error) {
+			rawHandler, err := CreateObject(s, config)
+			if err != nil {
+				return nil, err
+			}
+			handler, ok := rawHandler.(inbound.Handler)
+			if !ok {
+				return nil, newError("not an InboundHandler")
+			}
+			return handler, nil
+		},
+		add: func(handler taggedHandler) error {
+			return ihm.AddHandler(s.ctx, handler.(inbound.Handler))
+		},
+		remove: func(tag string) error {
+			return ihm.RemoveHandler(s.ctx, tag)
+		},
+	}
+
+	running := make(map[string]proto.Message)
+	var untagged []proto.Message
+	for _, handler := range ihm.ListHandlers(s.ctx) {
+		h, ok := handler.(GetInboundConfig)
+		if !ok || h.GetInboundConfig() == nil {
+			continue
+		}
+		if handler.Tag() == "" {
+			untagged = append(untagged, h.GetInboundConfig())
+			continue
+		}
+		running[handler.Tag()] = h.GetInboundConfig()
+	}
+
+	var newUntagged []proto.Message
+	for _, config := range configs {
+		if config.Tag == "" {
+			newUntagged = append(newUntagged, config)
+			continue
+		}
+		if old, found := running[config.Tag]; found {
+			delete(running, config.Tag)
+			if configEqual(old, config) {
+				continue
+			}
+			r.removed = append(r.removed, config.Tag)
+			r.old = append(r.old, old)
+		}
+		handler, err := r.create(config)
+		if err != nil {
+			r.discard()
+			return nil, err
+		}
+		r.added = append(r.added, handler)
+	}
+	for tag, old := range running {
+		r.removed = append(r.removed, tag)
+		r.old = append(r.old, old)
+	}
+	if !configsEqual(untagged, newUntagged) {
+		newError("changes of inbounds without tag are ignored, restart to apply them").AtWarning().WriteToLog()
+	}
+	return r, nil
+}
+
+func (s *Instance) planOutbounds(configs []*OutboundHandlerConfig) (*handlerReload, error) {
+	ohm := s.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	r := &handlerReload{
+		kind: "outbound",
+		create: func(config proto.Message) (taggedHandler, error) {
+			rawHandler, err := CreateObject(s, config)
+			if err != nil {
+				return nil, err
+			}
+			handler, ok := rawHandler.(outbound.Handler)
+			if !ok {
+				return nil, newError("not an OutboundHandler")
+			}
+			return handler, nil
+		},
+		add: func(handler taggedHandler) error {
+			return ohm.AddHandler(s.ctx, handler.(outbound.Handler))
+		},
+		remove: func(tag string) error {
+			old := ohm.GetHandler(tag)
+			if err := ohm.RemoveHandler(s.ctx, tag); err != nil {
+				return err
+			}
+			common.Close(old)
+			return nil
+		},
+	}
+
+	running := make(map[string]proto.Message)
+	var untagged []proto.Message
+	for _, handler := range ohm.ListHandlers(s.ctx) {
+		h, ok := handler.(GetOutboundConfig)
+		if !ok || h.GetOutboundConfig() == nil {
+			continue
+		}
+		if handler.Tag() == "" {
+			untagged = append(untagged, h.GetOutboundConfig())
+			continue
+		}
+		running[handler.Tag()] = h.GetOutboundConfig()
+	}
+
+	// The first outbound is the default one. When it changes, both the old and the new default handlers are
+	// recreated, so that the new default one is added first after the old one is removed.
+	forced := make(map[string]bool)
+	d := ohm.GetDefaultHandler()
+	if len(configs) > 0 {
+		if d == nil || d.Tag() != configs[0].Tag {
+			if (d != nil && d.Tag() == "") || configs[0].Tag == "" {
+				newError("change of the default outbound is ignored, restart to apply it").AtWarning().WriteToLog()
+			} else {
+				if d != nil {
+					forced[d.Tag()] = true
+				}
+				forced[configs[0].Tag] = true
+			}
+		}
+	}
+
+	var newUntagged []proto.Message
+	for _, config := range configs {
+		if config.Tag == "" {
+			newUntagged = append(newUntagged, config)
+			continue
+		}
+		if old, found := running[config.Tag]; found {
+			delete(running, config.Tag)
+			if !forced[config.Tag] && configEqual(old, config) {
+				continue
+			}
+			r.removed = append(r.removed, config.Tag)
+			r.old = append(r.old, old)
+		}
+		handler, err := r.create(config)
+		if err != nil {
+			r.discard()
+			return nil, err
+		}
+		r.added = append(r.added, handler)
+	}
+	for tag, old := range running {
+		r.removed = append(r.removed, tag)
+		r.old = append(r.old, old)
+	}
+	if !configsEqual(untagged, newUntagged) {
+		newError("changes of outbounds without tag are ignored, restart to apply them").AtWarning().WriteToLog()
+	}
+
+	// The old default handler is removed first, so that it becomes the default one again when restored first.
+	if d != nil {
+		for i, tag := range r.removed {
+			if tag == d.Tag() {
+				r.removed[0], r.removed[i] = r.removed[i], r.removed[0]
+				r.old[0], r.old[i] = r.old[i], r.old[0]
+				break
+			}
+		}
+	}
+	return r, nil
+}
+
+// configsEqual reports whether two lists of configs are equal, regardless of their order.
+func configsEqual(a, b []proto.Message) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	matched := make([]bool, len(b))
+	for _, ca := range a {
+		found := false
+		for i, cb := range b {
+			if !matched[i] && configEqual(ca, cb) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// configEqual reports whether two configs are equal. TypedMessages in the configs are compared by the messages they
+// hold rather than their serialized bytes, which are not stable for messages with map fields.
+func configEqual(a, b proto.Message) bool {
+	a, b = proto.Clone(a), proto.Clone(b)
+	if normalizeTypedMessages(proto.MessageReflect(a)) != nil || normalizeTypedMessages(proto.MessageReflect(b)) != nil {
+		return false
+	}
+	return proto.Equal(a, b)
+}
+
+// normalizeTypedMessages serializes all TypedMessages in the message deterministically.
+func normalizeTypedMessages(m protoreflect.Message) error {
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = normalizeTypedMessages(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = normalizeTypedMessages(mv.Message())
+				return err == nil
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			err = normalizeTypedMessages(v.Message())
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tm, ok := m.Interface().(*serial.TypedMessage)
+	if !ok {
+		return nil
+	}
+	instance, err := tm.GetInstance()
+	if err != nil {
+		return err
+	}
+	if err := normalizeTypedMessages(proto.MessageReflect(instance)); err != nil {
+		return err
+	}
+	tm.Value, err = protov2.MarshalOptions{Deterministic: true}.Marshal(proto.MessageV2(instance))
+	return err
+}
@@ -0,0 +1,185 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eagleql/xray-core/app/dispatcher"
+	"github.com/eagleql/xray-core/app/proxyman"
+	_ "github.com/eagleql/xray-core/app/proxyman/inbound"
+	_ "github.com/eagleql/xray-core/app/proxyman/outbound"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/serial"
+	. "github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/features/inbound"
+	"github.com/eagleql/xray-core/features/outbound"
+	"github.com/eagleql/xray-core/proxy/freedom"
+	"github.com/eagleql/xray-core/proxy/socks"
+	"github.com/eagleql/xray-core/testing/servers/tcp"
+)
+
+func TestReload(t *testing.T) {
+	port := tcp.PickPort()
+
+	newConfig := func(strategy freedom.Config_DomainStrategy) *Config {
+		return &Config{
+			App: []*serial.TypedMessage{
+				serial.ToTypedMessage(&dispatcher.Config{}),
+				serial.ToTypedMessage(&proxyman.InboundConfig{}),
+				serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			},
+			Inbound: []*InboundHandlerConfig{
+				{
+					Tag: "in",
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortRange: net.SinglePortRange(port),
+						Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{
+						AuthType: socks.AuthType_PASSWORD,
+						Accounts: map[string]string{
+							"a": "1",
+							"b": "2",
+							"c": "3",
+							"d": "4",
+						},
+					}),
+				},
+			},
+			Outbound: []*OutboundHandlerConfig{
+				{
+					Tag:           "direct",
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+				{
+					Tag: "ipv4",
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{
+						DomainStrategy: strategy,
+					}),
+				},
+			},
+		}
+	}
+
+	server, err := New(newConfig(freedom.Config_USE_IP))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	ihm := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	ohm := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	in, err := ihm.GetHandler(context.Background(), "in")
+	common.Must(err)
+	direct := ohm.GetHandler("direct")
+	ipv4 := ohm.GetHandler("ipv4")
+
+	common.Must(server.Reload(newConfig(freedom.Config_USE_IP4)))
+
+	if h, _ := ihm.GetHandler(context.Background(), "in"); h != in {
+		t.Error("expect unchanged inbound to be kept")
+	}
+	if ohm.GetHandler("direct") != direct {
+		t.Error("expect unchanged outbound to be kept")
+	}
+	if h := ohm.GetHandler("ipv4"); h == nil || h == ipv4 {
+		t.Error("expect changed outbound to be recreated")
+	}
+	if h := ohm.GetDefaultHandler(); h == nil || h.Tag() != "direct" {
+		t.Error("expect default outbound to be kept")
+	}
+
+	config := newConfig(freedom.Config_USE_IP4)
+	config.Outbound[0], config.Outbound[1] = config.Outbound[1], config.Outbound[0]
+	common.Must(server.Reload(config))
+
+	if h := ohm.GetDefaultHandler(); h == nil || h.Tag() != "ipv4" {
+		t.Error("expect default outbound to be changed")
+	}
+	if ohm.GetHandler("direct") == nil {
+		t.Error("expect previous default outbound to be kept")
+	}
+}
+
+func TestReloadRollback(t *testing.T) {
+	port := tcp.PickPort()
+
+	newConfig := func(strategy freedom.Config_DomainStrategy, inbounds ...*InboundHandlerConfig) *Config {
+		return &Config{
+			App: []*serial.TypedMessage{
+				serial.ToTypedMessage(&dispatcher.Config{}),
+				serial.ToTypedMessage(&proxyman.InboundConfig{}),
+				serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			},
+			Inbound: append([]*InboundHandlerConfig{
+				{
+					Tag: "in",
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortRange: net.SinglePortRange(port),
+						Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{}),
+				},
+			}, inbounds...),
+			Outbound: []*OutboundHandlerConfig{
+				{
+					Tag: "direct",
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{
+						DomainStrategy: strategy,
+					}),
+				},
+			},
+		}
+	}
+
+	server, err := New(newConfig(freedom.Config_USE_IP))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	// The new inbound fails to start, as its port is taken.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	taken := net.Port(listener.Addr().(*net.TCPAddr).Port)
+
+	config := newConfig(freedom.Config_USE_IP4, &InboundHandlerConfig{
+		Tag: "taken",
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			PortRange: net.SinglePortRange(taken),
+			Listen:    net.NewIPOrDomain(net.LocalHostIP),
+		}),
+		ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{}),
+	})
+	if err := server.Reload(config); err == nil {
+		t.Fatal("expect error when an inbound fails to start")
+	}
+
+	ihm := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	ohm := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	if _, err := ihm.GetHandler(context.Background(), "taken"); err == nil {
+		t.Error("expect failed inbound to be removed")
+	}
+	if _, err := ihm.GetHandler(context.Background(), "in"); err != nil {
+		t.Error("expect inbound to be kept, but got ", err)
+	}
+	direct, ok := ohm.GetHandler("direct").(GetOutboundConfig)
+	if !ok {
+		t.Fatal("expect outbound to be restored")
+	}
+	settings, err := direct.GetOutboundConfig().ProxySettings.GetInstance()
+	common.Must(err)
+	if s := settings.(*freedom.Config).DomainStrategy; s != freedom.Config_USE_IP {
+		t.Error("expect outbound to be restored with its previous config, but got ", s)
+	}
+	if h := ohm.GetDefaultHandler(); h == nil || h.Tag() != "direct" {
+		t.Error("expect default outbound to be restored")
+	}
+
+	// The previous config is kept, so reloading it changes nothing.
+	in, _ := ihm.GetHandler(context.Background(), "in")
+	common.Must(server.Reload(newConfig(freedom.Config_USE_IP)))
+	if h, _ := ihm.GetHandler(context.Background(), "in"); h != in {
+		t.Error("expect unchanged inbound to be kept")
+	}
+}
@@ -92,10 +92,22 @@ type Instance struct {
 	features           []features.Feature
 	featureResolutions []resolution
 	running            bool
+	config             *Config
+	reloader           ConfigReloader
 
 	ctx context.Context
 }
 
+// GetInboundConfig is the interface for inbound handlers that keep the config they are created from.
+type GetInboundConfig interface {
+	GetInboundConfig() *InboundHandlerConfig
+}
+
+// GetOutboundConfig is the interface for outbound handlers that keep the config they are created from.
+type GetOutboundConfig interface {
+	GetOutboundConfig() *OutboundHandlerConfig
+}
+
 func AddInboundHandler(server *Instance, config *InboundHandlerConfig) error {
 	inboundManager := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
 	rawHandler, err := CreateObject(server, config)
@@ -182,6 +194,7 @@ func NewWithContext(ctx context.Context, config *Config) (*Instance, error) {
 
 func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
 	server.ctx = context.WithValue(server.ctx, "cone", os.Getenv("XRAY_CONE_DISABLED") != "true")
+	server.config = config
 
 	if config.Transport != nil {
 		features.PrintDeprecatedFeatureWarning("global transport settings")
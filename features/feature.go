@@ -11,6 +11,12 @@ type Feature interface {
 	common.Runnable
 }
 
+// Reloadable is the interface for features that can apply a new config without being recreated.
+type Reloadable interface {
+	// Reload applies the given config to the feature. It returns common.ErrNoClue if the config is not for this feature.
+	Reload(config interface{}) error
+}
+
 // PrintDeprecatedFeatureWarning prints a warning for deprecated feature.
 func PrintDeprecatedFeatureWarning(feature string) {
 	newError("You are using a deprecated feature: " + feature + ". Please update your config file with latest configuration format, or update your client software.").WriteToLog()
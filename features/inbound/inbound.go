@@ -29,6 +29,8 @@ type Manager interface {
 	GetHandler(ctx context.Context, tag string) (Handler, error)
 	// AddHandler adds the given handler into this Manager.
 	AddHandler(ctx context.Context, handler Handler) error
+	// ListHandlers returns all InboundHandlers in this Manager.
+	ListHandlers(ctx context.Context) []Handler
 
 	// RemoveHandler removes a handler from Manager.
 	RemoveHandler(ctx context.Context, tag string) error
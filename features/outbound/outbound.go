@@ -32,6 +32,8 @@ type Manager interface {
 	GetDefaultHandler() Handler
 	// AddHandler adds a handler into this outbound.Manager.
 	AddHandler(ctx context.Context, handler Handler) error
+	// ListHandlers returns all outbound.Handlers in this outbound.Manager.
+	ListHandlers(ctx context.Context) []Handler
 
 	// RemoveHandler removes a handler from outbound.Manager.
 	RemoveHandler(ctx context.Context, tag string) error
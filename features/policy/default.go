@@ -21,6 +21,11 @@ func (DefaultManager) ForLevel(level uint32) Session {
 	return p
 }
 
+// ForUser implements Manager.
+func (m DefaultManager) ForUser(level uint32, email string) Session {
+	return m.ForLevel(level)
+}
+
 // ForSystem implements Manager.
 func (DefaultManager) ForSystem() System {
 	return System{}
@@ -35,6 +35,26 @@ type Buffer struct {
 	PerConnection int32
 }
 
+// Bandwidth contains settings for throughput limits.
+type Bandwidth struct {
+	// Maximum uplink rate in bytes per second. 0 for unlimited.
+	UplinkRate int64
+	// Maximum downlink rate in bytes per second. 0 for unlimited.
+	DownlinkRate int64
+	// Maximum uplink burst in bytes.
+	UplinkBurst int64
+	// Maximum downlink burst in bytes.
+	DownlinkBurst int64
+}
+
+// Limit contains settings for the concurrency of a user.
+type Limit struct {
+	// Maximum number of concurrent connections per user. 0 for unlimited.
+	Connections uint32
+	// Maximum number of distinct source IPs with active connections per user. 0 for unlimited.
+	SourceIPs uint32
+}
+
 // SystemStats contains stat policy settings on system level.
 type SystemStats struct {
 	// Whether or not to enable stat counter for uplink traffic in inbound handlers.
@@ -55,9 +75,11 @@ type System struct {
 
 // Session is session based settings for controlling Xray requests. It contains various settings (or limits) that may differ for different users in the context.
 type Session struct {
-	Timeouts Timeout // Timeout settings
-	Stats    Stats
-	Buffer   Buffer
+	Timeouts  Timeout // Timeout settings
+	Stats     Stats
+	Buffer    Buffer
+	Bandwidth Bandwidth
+	Limit     Limit
 }
 
 // Manager is a feature that provides Policy for the given user by its id or level.
@@ -69,6 +91,9 @@ type Manager interface {
 	// ForLevel returns the Session policy for the given user level.
 	ForLevel(level uint32) Session
 
+	// ForUser returns the Session policy for the given user, i.e., the policy of its level with per-user overrides applied.
+	ForUser(level uint32, email string) Session
+
 	// ForSystem returns the System policy for Xray system.
 	ForSystem() System
 }
@@ -99,6 +99,17 @@ func GetOrRegisterChannel(m Manager, name string) (Channel, error) {
 	return m.RegisterChannel(name)
 }
 
+// GetUserTraffic returns the total traffic of the user, i.e., the sum of its uplink and downlink traffic counters.
+func GetUserTraffic(m Manager, email string) int64 {
+	var traffic int64
+	for _, direction := range []string{"uplink", "downlink"} {
+		if c := m.GetCounter("user>>>" + email + ">>>traffic>>>" + direction); c != nil {
+			traffic += c.Value()
+		}
+	}
+	return traffic
+}
+
 // ManagerType returns the type of Manager interface. Can be used to implement common.HasType.
 //
 // xray:api:stable
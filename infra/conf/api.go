@@ -6,13 +6,61 @@ import (
 	"github.com/eagleql/xray-core/app/commander"
 	loggerservice "github.com/eagleql/xray-core/app/log/command"
 	handlerservice "github.com/eagleql/xray-core/app/proxyman/command"
+	reverseservice "github.com/eagleql/xray-core/app/reverse/command"
+	routerservice "github.com/eagleql/xray-core/app/router/command"
 	statsservice "github.com/eagleql/xray-core/app/stats/command"
 	"github.com/eagleql/xray-core/common/serial"
 )
 
 type APIConfig struct {
-	Tag      string   `json:"tag"`
-	Services []string `json:"services"`
+	Tag         string            `json:"tag"`
+	Services    []string          `json:"services"`
+	TLS         *APITLSConfig     `json:"tls"`
+	Credentials []*APICredential  `json:"credentials"`
+	Gateway     *APIGatewayConfig `json:"gateway"`
+}
+
+// APIGatewayConfig enables the JSON over HTTP gateway of the API. Without listen, the gateway is served on the API
+// inbound together with gRPC. Listen must be a loopback address if there are no credentials.
+type APIGatewayConfig struct {
+	Listen string `json:"listen"`
+}
+
+type APITLSConfig struct {
+	CertificateFile string `json:"certificateFile"`
+	KeyFile         string `json:"keyFile"`
+	ClientCAFile    string `json:"clientCaFile"`
+}
+
+func (c *APITLSConfig) Build() (*commander.TLSConfig, error) {
+	if c.CertificateFile == "" || c.KeyFile == "" {
+		return nil, newError("API TLS requires both certificateFile and keyFile.")
+	}
+	return &commander.TLSConfig{
+		CertificateFile: c.CertificateFile,
+		KeyFile:         c.KeyFile,
+		ClientCaFile:    c.ClientCAFile,
+	}, nil
+}
+
+type APICredential struct {
+	Token      string     `json:"token"`
+	CommonName string     `json:"commonName"`
+	Scopes     StringList `json:"scopes"`
+}
+
+func (c *APICredential) Build() (*commander.Credential, error) {
+	if c.Token == "" && c.CommonName == "" {
+		return nil, newError("API credential requires token or commonName.")
+	}
+	if len(c.Scopes) == 0 {
+		return nil, newError("API credential requires scopes.")
+	}
+	return &commander.Credential{
+		Token:      c.Token,
+		CommonName: c.CommonName,
+		Scope:      c.Scopes,
+	}, nil
 }
 
 func (c *APIConfig) Build() (*commander.Config, error) {
@@ -31,11 +79,47 @@ func (c *APIConfig) Build() (*commander.Config, error) {
 			services = append(services, serial.ToTypedMessage(&loggerservice.Config{}))
 		case "statsservice":
 			services = append(services, serial.ToTypedMessage(&statsservice.Config{}))
+		case "routingservice":
+			services = append(services, serial.ToTypedMessage(&routerservice.Config{}))
+		case "reverseservice":
+			services = append(services, serial.ToTypedMessage(&reverseservice.Config{}))
 		}
 	}
 
-	return &commander.Config{
+	config := &commander.Config{
 		Tag:     c.Tag,
 		Service: services,
-	}, nil
+	}
+	if c.TLS != nil {
+		tls, err := c.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Tls = tls
+	}
+	for _, credential := range c.Credentials {
+		cred, err := credential.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Credential = append(config.Credential, cred)
+	}
+	if config.Tls == nil || config.Tls.ClientCaFile == "" {
+		for _, cred := range config.Credential {
+			if cred.CommonName != "" {
+				return nil, newError("API credential with commonName requires tls.clientCaFile.")
+			}
+		}
+	}
+
+	if c.Gateway != nil {
+		if c.Gateway.Listen == "" && config.Tls != nil {
+			return nil, newError("API gateway with tls requires listen.")
+		}
+		config.Gateway = &commander.GatewayConfig{
+			Listen: c.Gateway.Listen,
+		}
+	}
+
+	return config, nil
 }
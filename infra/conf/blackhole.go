@@ -2,6 +2,7 @@ package conf
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/golang/protobuf/proto"
 
@@ -15,14 +16,60 @@ func (*NoneResponse) Build() (proto.Message, error) {
 	return new(blackhole.NoneResponse), nil
 }
 
-type HTTPResponse struct{}
+type HTTPResponse struct {
+	Status uint32            `json:"status"`
+	Header map[string]string `json:"header"`
+	Body   string            `json:"body"`
+}
+
+func (v *HTTPResponse) Build() (proto.Message, error) {
+	if v.Status != 0 && (v.Status < 100 || v.Status > 999) {
+		return nil, newError("invalid HTTP status: ", v.Status)
+	}
+	response := &blackhole.HTTPResponse{
+		Status: v.Status,
+	}
+	if v.Body != "" {
+		response.Body = []byte(v.Body)
+	}
+	keys := make([]string, 0, len(v.Header))
+	for key := range v.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		response.Header = append(response.Header, &blackhole.Header{Key: key, Value: v.Header[key]})
+	}
+	return response, nil
+}
 
-func (*HTTPResponse) Build() (proto.Message, error) {
-	return new(blackhole.HTTPResponse), nil
+type TLSResponse struct {
+	Alert uint32 `json:"alert"`
+}
+
+func (v *TLSResponse) Build() (proto.Message, error) {
+	if v.Alert > 255 {
+		return nil, newError("invalid TLS alert: ", v.Alert)
+	}
+	return &blackhole.TLSResponse{Alert: v.Alert}, nil
+}
+
+type DNSResponse struct {
+	NXDomain bool `json:"nxdomain"`
+}
+
+func (v *DNSResponse) Build() (proto.Message, error) {
+	return &blackhole.DNSResponse{Nxdomain: v.NXDomain}, nil
+}
+
+type BlackholeTarpit struct {
+	Duration uint32 `json:"duration"`
+	Interval uint32 `json:"interval"`
 }
 
 type BlackholeConfig struct {
-	Response json.RawMessage `json:"response"`
+	Response json.RawMessage  `json:"response"`
+	Tarpit   *BlackholeTarpit `json:"tarpit"`
 }
 
 func (v *BlackholeConfig) Build() (proto.Message, error) {
@@ -38,6 +85,12 @@ func (v *BlackholeConfig) Build() (proto.Message, error) {
 		}
 		config.Response = serial.ToTypedMessage(responseSettings)
 	}
+	if v.Tarpit != nil {
+		config.Tarpit = &blackhole.Tarpit{
+			Duration: v.Tarpit.Duration,
+			Interval: v.Tarpit.Interval,
+		}
+	}
 
 	return config, nil
 }
@@ -47,6 +100,8 @@ var (
 		ConfigCreatorCache{
 			"none": func() interface{} { return new(NoneResponse) },
 			"http": func() interface{} { return new(HTTPResponse) },
+			"tls":  func() interface{} { return new(TLSResponse) },
+			"dns":  func() interface{} { return new(DNSResponse) },
 		},
 		"type",
 		"")
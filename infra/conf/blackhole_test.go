@@ -25,6 +25,46 @@ func TestHTTPResponseJSON(t *testing.T) {
 				Response: serial.ToTypedMessage(&blackhole.HTTPResponse{}),
 			},
 		},
+		{
+			Input: `{
+				"response": {
+					"type": "http",
+					"status": 404,
+					"header": {"Server": "nginx"},
+					"body": "not found"
+				},
+				"tarpit": {
+					"duration": 60,
+					"interval": 500
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.HTTPResponse{
+					Status: 404,
+					Header: []*blackhole.Header{{Key: "Server", Value: "nginx"}},
+					Body:   []byte("not found"),
+				}),
+				Tarpit: &blackhole.Tarpit{
+					Duration: 60,
+					Interval: 500,
+				},
+			},
+		},
+		{
+			Input: `{
+				"response": {
+					"type": "dns",
+					"nxdomain": true
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.DNSResponse{
+					Nxdomain: true,
+				}),
+			},
+		},
 		{
 			Input:  `{}`,
 			Parser: loadJSON(creator),
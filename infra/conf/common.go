@@ -3,6 +3,7 @@ package conf
 import (
 	"encoding/json"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/eagleql/xray-core/common/net"
@@ -182,6 +183,45 @@ func (v *PortRange) UnmarshalJSON(data []byte) error {
 	return newError("invalid port range: ", string(data))
 }
 
+// NumberRange is a range of non-negative integers, either a single number or a string like "10-20".
+type NumberRange struct {
+	From uint64
+	To   uint64
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
+func (v *NumberRange) UnmarshalJSON(data []byte) error {
+	var number uint64
+	if err := json.Unmarshal(data, &number); err == nil {
+		v.From = number
+		v.To = number
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return newError("invalid number range: ", string(data)).Base(err)
+	}
+	pair := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	from, err := strconv.ParseUint(strings.TrimSpace(pair[0]), 10, 64)
+	if err != nil {
+		return newError("invalid number range: ", s).Base(err)
+	}
+	to := from
+	if len(pair) == 2 {
+		to, err = strconv.ParseUint(strings.TrimSpace(pair[1]), 10, 64)
+		if err != nil {
+			return newError("invalid number range: ", s).Base(err)
+		}
+	}
+	if from > to {
+		return newError("invalid number range ", from, " -> ", to)
+	}
+	v.From = from
+	v.To = to
+	return nil
+}
+
 type PortList struct {
 	Range []PortRange
 }
@@ -239,3 +279,19 @@ func (v *User) Build() *protocol.User {
 		Level: uint32(v.LevelByte),
 	}
 }
+
+// UserLimits is the optional expiry time and traffic quota of an inbound user.
+type UserLimits struct {
+	ExpiryTime        int64  `json:"expiryTime"`
+	TotalTrafficLimit uint64 `json:"totalTrafficLimit"`
+}
+
+// Apply sets the limits to the given user.
+func (l *UserLimits) Apply(user *protocol.User) error {
+	if l.ExpiryTime < 0 {
+		return newError("invalid expiryTime: ", l.ExpiryTime)
+	}
+	user.ExpiryTime = l.ExpiryTime
+	user.TotalTrafficLimit = l.TotalTrafficLimit
+	return nil
+}
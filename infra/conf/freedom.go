@@ -1,6 +1,9 @@
 package conf
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"net"
 	"strings"
 
@@ -11,10 +14,102 @@ import (
 )
 
 type FreedomConfig struct {
-	DomainStrategy string  `json:"domainStrategy"`
-	Timeout        *uint32 `json:"timeout"`
-	Redirect       string  `json:"redirect"`
-	UserLevel      uint32  `json:"userLevel"`
+	DomainStrategy string    `json:"domainStrategy"`
+	Timeout        *uint32   `json:"timeout"`
+	Redirect       string    `json:"redirect"`
+	UserLevel      uint32    `json:"userLevel"`
+	Fragment       *Fragment `json:"fragment"`
+	Noises         []*Noise  `json:"noises"`
+	ProxyProtocol  uint32    `json:"proxyProtocol"`
+}
+
+type Fragment struct {
+	Packets  string       `json:"packets"`
+	Length   *NumberRange `json:"length"`
+	Interval *NumberRange `json:"interval"`
+}
+
+// Build builds the fragment settings of freedom outbound.
+func (c *Fragment) Build() (*freedom.Fragment, error) {
+	config := new(freedom.Fragment)
+	switch strings.ToLower(c.Packets) {
+	case "tlshello":
+		// Both PacketsFrom and PacketsTo are 0 for the TLS ClientHello.
+	case "":
+		return nil, newError("fragment packets is not specified")
+	default:
+		packets := new(NumberRange)
+		if err := packets.UnmarshalJSON([]byte(`"` + c.Packets + `"`)); err != nil {
+			return nil, newError("invalid fragment packets: ", c.Packets).Base(err)
+		}
+		if packets.From == 0 {
+			return nil, newError("fragment packets start from 1")
+		}
+		config.PacketsFrom = packets.From
+		config.PacketsTo = packets.To
+	}
+
+	if c.Length == nil || c.Length.From == 0 {
+		return nil, newError("fragment length must be positive")
+	}
+	config.LengthMin = c.Length.From
+	config.LengthMax = c.Length.To
+	if c.Interval != nil {
+		config.IntervalMin = c.Interval.From
+		config.IntervalMax = c.Interval.To
+	}
+	return config, nil
+}
+
+type Noise struct {
+	Type   string          `json:"type"`
+	Packet json.RawMessage `json:"packet"`
+	Delay  *NumberRange    `json:"delay"`
+}
+
+// Build builds a noise of freedom outbound.
+func (c *Noise) Build() (*freedom.Noise, error) {
+	config := new(freedom.Noise)
+	if c.Delay != nil {
+		config.DelayMin = c.Delay.From
+		config.DelayMax = c.Delay.To
+	}
+
+	if strings.ToLower(c.Type) == "rand" {
+		length := new(NumberRange)
+		if err := json.Unmarshal(c.Packet, length); err != nil {
+			return nil, newError("invalid noise length: ", string(c.Packet)).Base(err)
+		}
+		if length.From == 0 {
+			return nil, newError("noise length must be positive")
+		}
+		config.LengthMin = length.From
+		config.LengthMax = length.To
+		return config, nil
+	}
+
+	var packet string
+	if err := json.Unmarshal(c.Packet, &packet); err != nil {
+		return nil, newError("invalid noise packet: ", string(c.Packet)).Base(err)
+	}
+	var err error
+	switch strings.ToLower(c.Type) {
+	case "str":
+		config.Packet = []byte(packet)
+	case "base64":
+		config.Packet, err = base64.StdEncoding.DecodeString(packet)
+	case "hex":
+		config.Packet, err = hex.DecodeString(packet)
+	default:
+		return nil, newError("unknown noise type: ", c.Type)
+	}
+	if err != nil {
+		return nil, newError("invalid noise packet: ", packet).Base(err)
+	}
+	if len(config.Packet) == 0 {
+		return nil, newError("noise packet is empty")
+	}
+	return config, nil
 }
 
 // Build implements Buildable
@@ -34,6 +129,24 @@ func (c *FreedomConfig) Build() (proto.Message, error) {
 		config.Timeout = *c.Timeout
 	}
 	config.UserLevel = c.UserLevel
+	if c.ProxyProtocol > 2 {
+		return nil, newError("unsupported PROXY protocol version: ", c.ProxyProtocol)
+	}
+	config.ProxyProtocol = c.ProxyProtocol
+	if c.Fragment != nil {
+		fragment, err := c.Fragment.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fragment = fragment
+	}
+	for _, n := range c.Noises {
+		noise, err := n.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Noises = append(config.Noises, noise)
+	}
 	if len(c.Redirect) > 0 {
 		host, portStr, err := net.SplitHostPort(c.Redirect)
 		if err != nil {
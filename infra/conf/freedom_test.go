@@ -39,5 +39,42 @@ func TestFreedomConfig(t *testing.T) {
 				UserLevel: 1,
 			},
 		},
+		{
+			Input: `{
+				"proxyProtocol": 2
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				DomainStrategy: freedom.Config_AS_IS,
+				ProxyProtocol:  2,
+			},
+		},
+		{
+			Input: `{
+				"fragment": {
+					"packets": "tlshello",
+					"length": "100-200",
+					"interval": 10
+				},
+				"noises": [
+					{"type": "rand", "packet": "10-20", "delay": "10-16"},
+					{"type": "hex", "packet": "0102"}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				DomainStrategy: freedom.Config_AS_IS,
+				Fragment: &freedom.Fragment{
+					LengthMin:   100,
+					LengthMax:   200,
+					IntervalMin: 10,
+					IntervalMax: 10,
+				},
+				Noises: []*freedom.Noise{
+					{LengthMin: 10, LengthMax: 20, DelayMin: 10, DelayMax: 16},
+					{Packet: []byte{1, 2}},
+				},
+			},
+		},
 	})
 }
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/serial"
@@ -12,6 +13,8 @@ import (
 type HTTPAccount struct {
 	Username string `json:"user"`
 	Password string `json:"pass"`
+	Email    string `json:"email"`
+	Level    byte   `json:"level"`
 }
 
 func (v *HTTPAccount) Build() *http.Account {
@@ -21,6 +24,25 @@ func (v *HTTPAccount) Build() *http.Account {
 	}
 }
 
+// BuildUser builds the account as a user if it has its own email or level, nil otherwise.
+func (v *HTTPAccount) BuildUser(userLevel uint32) *protocol.User {
+	if v.Email == "" && v.Level == 0 {
+		return nil
+	}
+	user := &protocol.User{
+		Email:   v.Email,
+		Level:   userLevel,
+		Account: serial.ToTypedMessage(v.Build()),
+	}
+	if user.Email == "" {
+		user.Email = v.Username
+	}
+	if v.Level != 0 {
+		user.Level = uint32(v.Level)
+	}
+	return user
+}
+
 type HTTPServerConfig struct {
 	Timeout     uint32         `json:"timeout"`
 	Accounts    []*HTTPAccount `json:"accounts"`
@@ -35,11 +57,15 @@ func (c *HTTPServerConfig) Build() (proto.Message, error) {
 		UserLevel:        c.UserLevel,
 	}
 
-	if len(c.Accounts) > 0 {
-		config.Accounts = make(map[string]string)
-		for _, account := range c.Accounts {
-			config.Accounts[account.Username] = account.Password
+	for _, account := range c.Accounts {
+		if user := account.BuildUser(c.UserLevel); user != nil {
+			config.Users = append(config.Users, user)
+			continue
+		}
+		if config.Accounts == nil {
+			config.Accounts = make(map[string]string)
 		}
+		config.Accounts[account.Username] = account.Password
 	}
 
 	return config, nil
@@ -52,6 +78,7 @@ type HTTPRemoteConfig struct {
 }
 type HTTPClientConfig struct {
 	Servers []*HTTPRemoteConfig `json:"servers"`
+	Headers map[string]string   `json:"headers"`
 }
 
 func (v *HTTPClientConfig) Build() (proto.Message, error) {
@@ -76,5 +103,16 @@ func (v *HTTPClientConfig) Build() (proto.Message, error) {
 		}
 		config.Server[idx] = server
 	}
+	keys := make([]string, 0, len(v.Headers))
+	for key := range v.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		config.Header = append(config.Header, &http.Header{
+			Key:   key,
+			Value: v.Headers[key],
+		})
+	}
 	return config, nil
 }
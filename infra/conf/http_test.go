@@ -3,6 +3,9 @@ package conf_test
 import (
 	"testing"
 
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
 	. "github.com/eagleql/xray-core/infra/conf"
 	"github.com/eagleql/xray-core/proxy/http"
 )
@@ -35,5 +38,77 @@ func TestHTTPServerConfig(t *testing.T) {
 				Timeout:          10,
 			},
 		},
+		{
+			Input: `{
+				"accounts": [
+					{
+						"user": "my-username",
+						"pass": "my-password",
+						"email": "love@example.com"
+					}
+				],
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &http.ServerConfig{
+				Users: []*protocol.User{
+					{
+						Email: "love@example.com",
+						Level: 1,
+						Account: serial.ToTypedMessage(&http.Account{
+							Username: "my-username",
+							Password: "my-password",
+						}),
+					},
+				},
+				UserLevel: 1,
+			},
+		},
+	})
+}
+
+func TestHTTPClientConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(HTTPClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"servers": [
+					{
+						"address": "127.0.0.1",
+						"port": 443
+					}
+				],
+				"headers": {
+					"X-Token": "token",
+					"Proxy-Authorization": "Bearer token"
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &http.ClientConfig{
+				Server: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Ip{
+								Ip: []byte{127, 0, 0, 1},
+							},
+						},
+						Port: 443,
+					},
+				},
+				Header: []*http.Header{
+					{
+						Key:   "Proxy-Authorization",
+						Value: "Bearer token",
+					},
+					{
+						Key:   "X-Token",
+						Value: "token",
+					},
+				},
+			},
+		},
 	})
 }
@@ -0,0 +1,18 @@
+package conf
+
+import (
+	"github.com/eagleql/xray-core/proxy/loopback"
+	"github.com/golang/protobuf/proto"
+)
+
+type LoopbackConfig struct {
+	InboundTag string `json:"inboundTag"`
+}
+
+// Build implements Buildable
+func (c *LoopbackConfig) Build() (proto.Message, error) {
+	if c.InboundTag == "" {
+		return nil, newError("loopback inbound tag is not specified")
+	}
+	return &loopback.Config{InboundTag: c.InboundTag}, nil
+}
@@ -0,0 +1,26 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "github.com/eagleql/xray-core/infra/conf"
+	"github.com/eagleql/xray-core/proxy/loopback"
+)
+
+func TestLoopbackConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(LoopbackConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"inboundTag": "second-pass"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &loopback.Config{
+				InboundTag: "second-pass",
+			},
+		},
+	})
+}
@@ -3,6 +3,7 @@ package conf
 import (
 	"encoding/hex"
 	"encoding/json"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 
@@ -15,26 +16,38 @@ type MTProtoAccount struct {
 	Secret string `json:"secret"`
 }
 
-// Build implements Buildable
+// Build implements Buildable. A secret of "ee", 32 chars and the hex of a domain is for the fake TLS mode.
 func (a *MTProtoAccount) Build() (*mtproto.Account, error) {
-	if len(a.Secret) != 32 {
+	account := new(mtproto.Account)
+	secret := a.Secret
+	if len(secret) > 34 && strings.ToLower(secret[:2]) == "ee" {
+		domain, err := hex.DecodeString(secret[34:])
+		if err != nil {
+			return nil, newError("failed to decode fake TLS domain: ", secret[34:]).Base(err)
+		}
+		account.FakeTlsDomain = string(domain)
+		secret = secret[2:34]
+	}
+	if len(secret) != 32 {
 		return nil, newError("MTProto secret must have 32 chars")
 	}
-	secret, err := hex.DecodeString(a.Secret)
+	var err error
+	account.Secret, err = hex.DecodeString(secret)
 	if err != nil {
 		return nil, newError("failed to decode secret: ", a.Secret).Base(err)
 	}
-	return &mtproto.Account{
-		Secret: secret,
-	}, nil
+	return account, nil
 }
 
 type MTProtoServerConfig struct {
-	Users []json.RawMessage `json:"users"`
+	Users    []json.RawMessage `json:"users"`
+	Fallback string            `json:"fallback"`
 }
 
 func (c *MTProtoServerConfig) Build() (proto.Message, error) {
-	config := &mtproto.ServerConfig{}
+	config := &mtproto.ServerConfig{
+		Fallback: c.Fallback,
+	}
 
 	if len(c.Users) == 0 {
 		return nil, newError("zero MTProto users configured.")
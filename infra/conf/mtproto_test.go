@@ -36,5 +36,25 @@ func TestMTProtoServerConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"users": [{
+					"secret": "eeb0cbcef5a486d9636472ac27f8e11a9d7777772e6578616d706c652e636f6d"
+				}],
+				"fallback": "127.0.0.1:8443"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &mtproto.ServerConfig{
+				User: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&mtproto.Account{
+							Secret:        []byte{176, 203, 206, 245, 164, 134, 217, 99, 100, 114, 172, 39, 248, 225, 26, 157},
+							FakeTlsDomain: "www.example.com",
+						}),
+					},
+				},
+				Fallback: "127.0.0.1:8443",
+			},
+		},
 	})
 }
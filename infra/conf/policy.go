@@ -1,7 +1,12 @@
 package conf
 
 import (
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
 	"github.com/eagleql/xray-core/app/policy"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/core"
 )
 
 type Policy struct {
@@ -12,6 +17,12 @@ type Policy struct {
 	StatsUserUplink   bool    `json:"statsUserUplink"`
 	StatsUserDownlink bool    `json:"statsUserDownlink"`
 	BufferSize        *int32  `json:"bufferSize"`
+	UplinkRate        uint64  `json:"uplinkRate"`
+	UplinkBurst       uint64  `json:"uplinkBurst"`
+	DownlinkRate      uint64  `json:"downlinkRate"`
+	DownlinkBurst     uint64  `json:"downlinkBurst"`
+	MaxConnections    uint32  `json:"maxConnections"`
+	MaxSourceIPs      uint32  `json:"maxSourceIPs"`
 }
 
 func (t *Policy) Build() (*policy.Policy, error) {
@@ -47,9 +58,41 @@ func (t *Policy) Build() (*policy.Policy, error) {
 		}
 	}
 
+	if t.UplinkRate > 0 || t.DownlinkRate > 0 {
+		p.Bandwidth = &policy.Policy_Bandwidth{
+			UplinkRate:    t.UplinkRate,
+			UplinkBurst:   t.UplinkBurst,
+			DownlinkRate:  t.DownlinkRate,
+			DownlinkBurst: t.DownlinkBurst,
+		}
+	}
+
+	if t.MaxConnections > 0 || t.MaxSourceIPs > 0 {
+		p.Limit = &policy.Policy_Limit{
+			Connections: t.MaxConnections,
+			SourceIps:   t.MaxSourceIPs,
+		}
+	}
+
 	return p, nil
 }
 
+type UserPolicy struct {
+	UplinkRate    uint64 `json:"uplinkRate"`
+	UplinkBurst   uint64 `json:"uplinkBurst"`
+	DownlinkRate  uint64 `json:"downlinkRate"`
+	DownlinkBurst uint64 `json:"downlinkBurst"`
+}
+
+func (p *UserPolicy) Build() (*policy.Policy_Bandwidth, error) {
+	return &policy.Policy_Bandwidth{
+		UplinkRate:    p.UplinkRate,
+		UplinkBurst:   p.UplinkBurst,
+		DownlinkRate:  p.DownlinkRate,
+		DownlinkBurst: p.DownlinkBurst,
+	}, nil
+}
+
 type SystemPolicy struct {
 	StatsInboundUplink    bool `json:"statsInboundUplink"`
 	StatsInboundDownlink  bool `json:"statsInboundDownlink"`
@@ -69,8 +112,9 @@ func (p *SystemPolicy) Build() (*policy.SystemPolicy, error) {
 }
 
 type PolicyConfig struct {
-	Levels map[uint32]*Policy `json:"levels"`
-	System *SystemPolicy      `json:"system"`
+	Levels map[uint32]*Policy     `json:"levels"`
+	Users  map[string]*UserPolicy `json:"users"`
+	System *SystemPolicy          `json:"system"`
 }
 
 func (c *PolicyConfig) Build() (*policy.Config, error) {
@@ -88,6 +132,19 @@ func (c *PolicyConfig) Build() (*policy.Config, error) {
 		Level: levels,
 	}
 
+	if len(c.Users) > 0 {
+		config.UserBandwidth = make(map[string]*policy.Policy_Bandwidth)
+		for email, p := range c.Users {
+			if p != nil {
+				bw, err := p.Build()
+				if err != nil {
+					return nil, err
+				}
+				config.UserBandwidth[email] = bw
+			}
+		}
+	}
+
 	if c.System != nil {
 		sc, err := c.System.Build()
 		if err != nil {
@@ -98,3 +155,48 @@ func (c *PolicyConfig) Build() (*policy.Config, error) {
 
 	return config, nil
 }
+
+// checkUserQuotas returns an error if a user of the inbounds has a traffic quota, while the traffic of the users at its
+// level is not counted, as the quota is measured with the user traffic counters.
+func (c *PolicyConfig) checkUserQuotas(inbounds []*core.InboundHandlerConfig) error {
+	for _, ic := range inbounds {
+		settings, err := ic.ProxySettings.GetInstance()
+		if err != nil {
+			return err
+		}
+		for _, user := range findUsers(proto.MessageReflect(settings)) {
+			if user.TotalTrafficLimit == 0 {
+				continue
+			}
+			var p *Policy
+			if c != nil {
+				p = c.Levels[user.Level]
+			}
+			if p == nil || !p.StatsUserUplink || !p.StatsUserDownlink {
+				return newError("traffic limit of user ", user.Email, " requires statsUserUplink and statsUserDownlink in policy of level ", user.Level)
+			}
+		}
+	}
+	return nil
+}
+
+// findUsers returns the users in the message, looking into its nested messages.
+func findUsers(m protoreflect.Message) []*protocol.User {
+	if user, ok := m.Interface().(*protocol.User); ok {
+		return []*protocol.User{user}
+	}
+	var users []*protocol.User
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil || fd.IsMap():
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				users = append(users, findUsers(v.List().Get(i).Message())...)
+			}
+		default:
+			users = append(users, findUsers(v.Message())...)
+		}
+		return true
+	})
+	return users
+}
@@ -38,3 +38,42 @@ func TestBufferSize(t *testing.T) {
 		}
 	}
 }
+
+func TestPolicyBandwidth(t *testing.T) {
+	pConf := PolicyConfig{
+		Levels: map[uint32]*Policy{
+			0: {
+				UplinkRate:   1024,
+				DownlinkRate: 2048,
+			},
+		},
+		Users: map[string]*UserPolicy{
+			"love@example.com": {
+				UplinkRate:  4096,
+				UplinkBurst: 8192,
+			},
+		},
+	}
+	config, err := pConf.Build()
+	common.Must(err)
+
+	if b := config.Level[0].Bandwidth; b.UplinkRate != 1024 || b.DownlinkRate != 2048 {
+		t.Error("unexpected level bandwidth: ", b)
+	}
+	if b := config.UserBandwidth["love@example.com"]; b.UplinkRate != 4096 || b.UplinkBurst != 8192 {
+		t.Error("unexpected user bandwidth: ", b)
+	}
+}
+
+func TestPolicyLimit(t *testing.T) {
+	pConf := Policy{
+		MaxConnections: 8,
+		MaxSourceIPs:   2,
+	}
+	p, err := pConf.Build()
+	common.Must(err)
+
+	if p.Limit.Connections != 8 || p.Limit.SourceIps != 2 {
+		t.Error("unexpected limit: ", p.Limit)
+	}
+}
@@ -1,6 +1,8 @@
 package conf
 
 import (
+	"strings"
+
 	"github.com/eagleql/xray-core/app/reverse"
 	"github.com/golang/protobuf/proto"
 )
@@ -8,25 +10,41 @@ import (
 type BridgeConfig struct {
 	Tag    string `json:"tag"`
 	Domain string `json:"domain"`
+	ID     string `json:"id"`
+	Weight uint32 `json:"weight"`
 }
 
 func (c *BridgeConfig) Build() (*reverse.BridgeConfig, error) {
 	return &reverse.BridgeConfig{
 		Tag:    c.Tag,
 		Domain: c.Domain,
+		Id:     c.ID,
+		Weight: c.Weight,
 	}, nil
 }
 
 type PortalConfig struct {
-	Tag    string `json:"tag"`
-	Domain string `json:"domain"`
+	Tag      string `json:"tag"`
+	Domain   string `json:"domain"`
+	Strategy string `json:"strategy"`
+	XUDP     bool   `json:"xudp"`
 }
 
 func (c *PortalConfig) Build() (*reverse.PortalConfig, error) {
-	return &reverse.PortalConfig{
+	config := &reverse.PortalConfig{
 		Tag:    c.Tag,
 		Domain: c.Domain,
-	}, nil
+		Xudp:   c.XUDP,
+	}
+	switch strings.ToLower(c.Strategy) {
+	case "", "leastload":
+		config.Strategy = reverse.PortalConfig_LEAST_LOAD
+	case "weighted":
+		config.Strategy = reverse.PortalConfig_WEIGHTED
+	default:
+		return nil, newError("unknown portal strategy: ", c.Strategy)
+	}
+	return config, nil
 }
 
 type ReverseConfig struct {
@@ -41,5 +41,30 @@ func TestReverseConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"bridges": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"id": "bridge-1",
+					"weight": 3
+				}],
+				"portals": [{
+					"tag": "test",
+					"domain": "test.example.com",
+					"strategy": "weighted",
+					"xudp": true
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &reverse.Config{
+				BridgeConfig: []*reverse.BridgeConfig{
+					{Tag: "test", Domain: "test.example.com", Id: "bridge-1", Weight: 3},
+				},
+				PortalConfig: []*reverse.PortalConfig{
+					{Tag: "test", Domain: "test.example.com", Strategy: reverse.PortalConfig_WEIGHTED, Xudp: true},
+				},
+			},
+		},
 	})
 }
@@ -28,48 +28,83 @@ func cipherFromString(c string) shadowsocks.CipherType {
 		return shadowsocks.CipherType_CHACHA20_POLY1305
 	case "none", "plain":
 		return shadowsocks.CipherType_NONE
+	case "2022-blake3-aes-128-gcm":
+		return shadowsocks.CipherType_BLAKE3_AES_128_GCM
+	case "2022-blake3-aes-256-gcm":
+		return shadowsocks.CipherType_BLAKE3_AES_256_GCM
+	case "2022-blake3-chacha20-poly1305":
+		return shadowsocks.CipherType_BLAKE3_CHACHA20_POLY1305
 	default:
 		return shadowsocks.CipherType_UNKNOWN
 	}
 }
 
+func is2022Cipher(c shadowsocks.CipherType) bool {
+	switch c {
+	case shadowsocks.CipherType_BLAKE3_AES_128_GCM, shadowsocks.CipherType_BLAKE3_AES_256_GCM, shadowsocks.CipherType_BLAKE3_CHACHA20_POLY1305:
+		return true
+	default:
+		return false
+	}
+}
+
 type ShadowsocksUserConfig struct {
 	Cipher   string `json:"method"`
 	Password string `json:"password"`
 	Level    byte   `json:"level"`
 	Email    string `json:"email"`
+	UserLimits
 }
 
 type ShadowsocksServerConfig struct {
-	Cipher      string                   `json:"method"`
-	Password    string                   `json:"password"`
-	Level       byte                     `json:"level"`
-	Email       string                   `json:"email"`
-	Users       []*ShadowsocksUserConfig `json:"clients"`
-	NetworkList *NetworkList             `json:"network"`
+	Cipher   string                   `json:"method"`
+	Password string                   `json:"password"`
+	Level    byte                     `json:"level"`
+	Email    string                   `json:"email"`
+	Users    []*ShadowsocksUserConfig `json:"clients"`
+	UserLimits
+	NetworkList *NetworkList `json:"network"`
+	Plugin      string       `json:"plugin"`
+	PluginOpts  string       `json:"pluginOpts"`
+	PluginArgs  []string     `json:"pluginArgs"`
 }
 
 func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 	config := new(shadowsocks.ServerConfig)
 	config.Network = v.NetworkList.Build()
+	config.Plugin = v.Plugin
+	config.PluginOpts = v.PluginOpts
+	config.PluginArgs = v.PluginArgs
 
 	if v.Users != nil {
+		// With multiple users, the password of Shadowsocks 2022 server is the identity PSK.
+		if is2022Cipher(cipherFromString(v.Cipher)) {
+			config.Key = v.Password
+		}
 		for _, user := range v.Users {
+			method := user.Cipher
+			if method == "" {
+				method = v.Cipher
+			}
 			account := &shadowsocks.Account{
 				Password:   user.Password,
-				CipherType: cipherFromString(user.Cipher),
+				CipherType: cipherFromString(method),
 			}
 			if account.Password == "" {
 				return nil, newError("Shadowsocks password is not specified.")
 			}
-			if account.CipherType < 5 || account.CipherType > 7 {
-				return nil, newError("unsupported cipher method: ", user.Cipher)
+			if (account.CipherType < 5 || account.CipherType > 7) && !is2022Cipher(account.CipherType) {
+				return nil, newError("unsupported cipher method: ", method)
 			}
-			config.Users = append(config.Users, &protocol.User{
+			u := &protocol.User{
 				Email:   user.Email,
 				Level:   uint32(user.Level),
 				Account: serial.ToTypedMessage(account),
-			})
+			}
+			if err := user.UserLimits.Apply(u); err != nil {
+				return nil, newError("invalid Shadowsocks user").Base(err)
+			}
+			config.Users = append(config.Users, u)
 		}
 	} else {
 		account := &shadowsocks.Account{
@@ -82,11 +117,15 @@ func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 		if account.CipherType == shadowsocks.CipherType_UNKNOWN {
 			return nil, newError("unknown cipher method: ", v.Cipher)
 		}
-		config.Users = append(config.Users, &protocol.User{
+		u := &protocol.User{
 			Email:   v.Email,
 			Level:   uint32(v.Level),
 			Account: serial.ToTypedMessage(account),
-		})
+		}
+		if err := v.UserLimits.Apply(u); err != nil {
+			return nil, newError("invalid Shadowsocks user").Base(err)
+		}
+		config.Users = append(config.Users, u)
 	}
 
 	return config, nil
@@ -102,7 +141,10 @@ type ShadowsocksServerTarget struct {
 }
 
 type ShadowsocksClientConfig struct {
-	Servers []*ShadowsocksServerTarget `json:"servers"`
+	Servers    []*ShadowsocksServerTarget `json:"servers"`
+	Plugin     string                     `json:"plugin"`
+	PluginOpts string                     `json:"pluginOpts"`
+	PluginArgs []string                   `json:"pluginArgs"`
 }
 
 func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
@@ -147,6 +189,9 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 	}
 
 	config.Server = serverSpecs
+	config.Plugin = v.Plugin
+	config.PluginOpts = v.PluginOpts
+	config.PluginArgs = v.PluginArgs
 
 	return config, nil
 }
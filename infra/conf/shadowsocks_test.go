@@ -32,5 +32,51 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 				Network: []net.Network{net.Network_TCP},
 			},
 		},
+		{
+			Input: `{
+				"method": "aes-128-gcm",
+				"password": "xray-password",
+				"email": "love@example.com",
+				"expiryTime": 1700000000,
+				"totalTrafficLimit": 1073741824
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				Users: []*protocol.User{{
+					Email: "love@example.com",
+					Account: serial.ToTypedMessage(&shadowsocks.Account{
+						CipherType: shadowsocks.CipherType_AES_128_GCM,
+						Password:   "xray-password",
+					}),
+					ExpiryTime:        1700000000,
+					TotalTrafficLimit: 1073741824,
+				}},
+				Network: []net.Network{net.Network_TCP},
+			},
+		},
+		{
+			Input: `{
+				"method": "2022-blake3-aes-128-gcm",
+				"password": "vLHHZ0mA9ThmpLD4ECpO2Q==",
+				"clients": [
+					{
+						"password": "qB7a6YhsAjTqHcP6f/Xkqg==",
+						"email": "love@example.com"
+					}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				Users: []*protocol.User{{
+					Email: "love@example.com",
+					Account: serial.ToTypedMessage(&shadowsocks.Account{
+						CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM,
+						Password:   "qB7a6YhsAjTqHcP6f/Xkqg==",
+					}),
+				}},
+				Network: []net.Network{net.Network_TCP},
+				Key:     "vLHHZ0mA9ThmpLD4ECpO2Q==",
+			},
+		},
 	})
 }
@@ -12,6 +12,8 @@ import (
 type SocksAccount struct {
 	Username string `json:"user"`
 	Password string `json:"pass"`
+	Email    string `json:"email"`
+	Level    byte   `json:"level"`
 }
 
 func (v *SocksAccount) Build() *socks.Account {
@@ -21,6 +23,25 @@ func (v *SocksAccount) Build() *socks.Account {
 	}
 }
 
+// BuildUser builds the account as a user if it has its own email or level, nil otherwise.
+func (v *SocksAccount) BuildUser(userLevel uint32) *protocol.User {
+	if v.Email == "" && v.Level == 0 {
+		return nil
+	}
+	user := &protocol.User{
+		Email:   v.Email,
+		Level:   userLevel,
+		Account: serial.ToTypedMessage(v.Build()),
+	}
+	if user.Email == "" {
+		user.Email = v.Username
+	}
+	if v.Level != 0 {
+		user.Level = uint32(v.Level)
+	}
+	return user
+}
+
 const (
 	AuthMethodNoAuth   = "noauth"
 	AuthMethodUserPass = "password"
@@ -47,11 +68,15 @@ func (v *SocksServerConfig) Build() (proto.Message, error) {
 		config.AuthType = socks.AuthType_NO_AUTH
 	}
 
-	if len(v.Accounts) > 0 {
-		config.Accounts = make(map[string]string, len(v.Accounts))
-		for _, account := range v.Accounts {
-			config.Accounts[account.Username] = account.Password
+	for _, account := range v.Accounts {
+		if user := account.BuildUser(v.UserLevel); user != nil {
+			config.Users = append(config.Users, user)
+			continue
+		}
+		if config.Accounts == nil {
+			config.Accounts = make(map[string]string, len(v.Accounts))
 		}
+		config.Accounts[account.Username] = account.Password
 	}
 
 	config.UdpEnabled = v.UDP
@@ -64,6 +89,20 @@ func (v *SocksServerConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+// MixedServerConfig is the config of a SOCKS server that also accepts HTTP proxy requests.
+type MixedServerConfig struct {
+	SocksServerConfig
+}
+
+func (v *MixedServerConfig) Build() (proto.Message, error) {
+	config, err := v.SocksServerConfig.Build()
+	if err != nil {
+		return nil, err
+	}
+	config.(*socks.ServerConfig).Mixed = true
+	return config, nil
+}
+
 type SocksRemoteConfig struct {
 	Address *Address          `json:"address"`
 	Port    uint16            `json:"port"`
@@ -46,6 +46,67 @@ func TestSocksInboundConfig(t *testing.T) {
 				UserLevel: 1,
 			},
 		},
+		{
+			Input: `{
+				"auth": "password",
+				"accounts": [
+					{
+						"user": "my-username",
+						"pass": "my-password",
+						"email": "love@example.com",
+						"level": 2
+					}
+				],
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &socks.ServerConfig{
+				AuthType: socks.AuthType_PASSWORD,
+				Users: []*protocol.User{
+					{
+						Email: "love@example.com",
+						Level: 2,
+						Account: serial.ToTypedMessage(&socks.Account{
+							Username: "my-username",
+							Password: "my-password",
+						}),
+					},
+				},
+				UserLevel: 1,
+			},
+		},
+	})
+}
+
+func TestMixedInboundConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(MixedServerConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"auth": "password",
+				"accounts": [
+					{
+						"user": "my-username",
+						"pass": "my-password"
+					}
+				],
+				"udp": true,
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &socks.ServerConfig{
+				AuthType: socks.AuthType_PASSWORD,
+				Accounts: map[string]string{
+					"my-username": "my-password",
+				},
+				UdpEnabled: true,
+				UserLevel:  1,
+				Mixed:      true,
+			},
+		},
 	})
 }
 
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"github.com/eagleql/xray-core/proxy/ssh"
+	"github.com/golang/protobuf/proto"
+)
+
+type SSHClientConfig struct {
+	Address              *Address   `json:"address"`
+	Port                 uint16     `json:"port"`
+	User                 string     `json:"user"`
+	Password             string     `json:"password"`
+	PrivateKeyFile       string     `json:"privateKeyFile"`
+	PrivateKey           []string   `json:"privateKey"`
+	PrivateKeyPassphrase string     `json:"privateKeyPassphrase"`
+	HostKey              StringList `json:"hostKey"`
+	UserLevel            uint32     `json:"userLevel"`
+}
+
+// Build implements Buildable
+func (c *SSHClientConfig) Build() (proto.Message, error) {
+	if c.Address == nil {
+		return nil, newError("SSH server address is not specified.")
+	}
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.User == "" {
+		return nil, newError("SSH user is not specified.")
+	}
+	config := &ssh.Config{
+		Address:   c.Address.Build(),
+		Port:      uint32(c.Port),
+		User:      c.User,
+		Password:  c.Password,
+		HostKey:   c.HostKey,
+		UserLevel: c.UserLevel,
+	}
+	if c.PrivateKeyFile != "" || len(c.PrivateKey) > 0 {
+		key, err := readFileOrString(c.PrivateKeyFile, c.PrivateKey)
+		if err != nil {
+			return nil, newError("failed to read SSH private key").Base(err)
+		}
+		config.PrivateKey = key
+		if c.PrivateKeyPassphrase != "" {
+			config.PrivateKeyPassphrase = []byte(c.PrivateKeyPassphrase)
+		}
+	}
+	if config.Password == "" && len(config.PrivateKey) == 0 {
+		return nil, newError("neither SSH password nor private key is specified.")
+	}
+	if len(config.HostKey) == 0 {
+		return nil, newError("SSH host key is not specified.")
+	}
+	return config, nil
+}
@@ -1,12 +1,15 @@
 package conf
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"math"
 	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/platform/filesystem"
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/serial"
@@ -15,6 +18,7 @@ import (
 	"github.com/eagleql/xray-core/transport/internet/http"
 	"github.com/eagleql/xray-core/transport/internet/kcp"
 	"github.com/eagleql/xray-core/transport/internet/quic"
+	"github.com/eagleql/xray-core/transport/internet/reality"
 	"github.com/eagleql/xray-core/transport/internet/tcp"
 	"github.com/eagleql/xray-core/transport/internet/tls"
 	"github.com/eagleql/xray-core/transport/internet/websocket"
@@ -443,6 +447,96 @@ func (c *XTLSConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+type REALITYConfig struct {
+	Dest        string   `json:"dest"`
+	ServerNames []string `json:"serverNames"`
+	PrivateKey  string   `json:"privateKey"`
+	MaxTimeDiff uint64   `json:"maxTimeDiff"`
+	ShortIds    []string `json:"shortIds"`
+
+	Fingerprint string `json:"fingerprint"`
+	ServerName  string `json:"serverName"`
+	PublicKey   string `json:"publicKey"`
+	ShortId     string `json:"shortId"`
+}
+
+// Build implements Buildable.
+func (c *REALITYConfig) Build() (proto.Message, error) {
+	config := new(reality.Config)
+	if c.PrivateKey != "" {
+		if c.Dest == "" {
+			return nil, newError(`REALITY: empty "dest"`)
+		}
+		if _, err := net.ParseDestination("tcp:" + c.Dest); err != nil {
+			return nil, newError(`REALITY: invalid "dest": `, c.Dest).Base(err)
+		}
+		if len(c.ServerNames) == 0 {
+			return nil, newError(`REALITY: empty "serverNames"`)
+		}
+		privateKey, err := parseX25519Key(c.PrivateKey)
+		if err != nil {
+			return nil, newError(`REALITY: invalid "privateKey": `, c.PrivateKey).Base(err)
+		}
+		if len(c.ShortIds) == 0 {
+			return nil, newError(`REALITY: empty "shortIds"`)
+		}
+		config.ShortIds = make([][]byte, len(c.ShortIds))
+		for i, s := range c.ShortIds {
+			if config.ShortIds[i], err = parseShortID(s); err != nil {
+				return nil, newError(`REALITY: invalid "shortIds": `, s).Base(err)
+			}
+		}
+		config.Dest = c.Dest
+		config.ServerNames = c.ServerNames
+		config.PrivateKey = privateKey
+		config.MaxTimeDiff = c.MaxTimeDiff
+	} else {
+		if c.Fingerprint == "" {
+			c.Fingerprint = "chrome"
+		}
+		if _, ok := tls.Fingerprints[strings.ToLower(c.Fingerprint)]; !ok {
+			return nil, newError(`REALITY: unknown "fingerprint": `, c.Fingerprint)
+		}
+		publicKey, err := parseX25519Key(c.PublicKey)
+		if err != nil {
+			return nil, newError(`REALITY: invalid "publicKey": `, c.PublicKey).Base(err)
+		}
+		shortID, err := parseShortID(c.ShortId)
+		if err != nil {
+			return nil, newError(`REALITY: invalid "shortId": `, c.ShortId).Base(err)
+		}
+		config.Fingerprint = strings.ToLower(c.Fingerprint)
+		config.ServerName = c.ServerName
+		config.PublicKey = publicKey
+		config.ShortId = shortID
+	}
+	return config, nil
+}
+
+// parseX25519Key decodes a key in URL-safe base64 without padding, as generated by "xray x25519".
+func parseX25519Key(s string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, newError("expect 32 bytes but got ", len(key))
+	}
+	return key, nil
+}
+
+// parseShortID decodes a short ID of up to 8 bytes in hex.
+func parseShortID(s string) ([]byte, error) {
+	shortID, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(shortID) > 8 {
+		return nil, newError("expect up to 8 bytes but got ", len(shortID))
+	}
+	return shortID, nil
+}
+
 type TransportProtocol string
 
 // Build implements Buildable.
@@ -524,19 +618,20 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 }
 
 type StreamConfig struct {
-	Network        *TransportProtocol  `json:"network"`
-	Security       string              `json:"security"`
-	TLSSettings    *TLSConfig          `json:"tlsSettings"`
-	XTLSSettings   *XTLSConfig         `json:"xtlsSettings"`
-	TCPSettings    *TCPConfig          `json:"tcpSettings"`
-	KCPSettings    *KCPConfig          `json:"kcpSettings"`
-	WSSettings     *WebSocketConfig    `json:"wsSettings"`
-	HTTPSettings   *HTTPConfig         `json:"httpSettings"`
-	DSSettings     *DomainSocketConfig `json:"dsSettings"`
-	QUICSettings   *QUICConfig         `json:"quicSettings"`
-	SocketSettings *SocketConfig       `json:"sockopt"`
-	GRPCConfig     *GRPCConfig         `json:"grpcSettings"`
-	GUNConfig      *GRPCConfig         `json:"gunSettings"`
+	Network         *TransportProtocol  `json:"network"`
+	Security        string              `json:"security"`
+	TLSSettings     *TLSConfig          `json:"tlsSettings"`
+	XTLSSettings    *XTLSConfig         `json:"xtlsSettings"`
+	REALITYSettings *REALITYConfig      `json:"realitySettings"`
+	TCPSettings     *TCPConfig          `json:"tcpSettings"`
+	KCPSettings     *KCPConfig          `json:"kcpSettings"`
+	WSSettings      *WebSocketConfig    `json:"wsSettings"`
+	HTTPSettings    *HTTPConfig         `json:"httpSettings"`
+	DSSettings      *DomainSocketConfig `json:"dsSettings"`
+	QUICSettings    *QUICConfig         `json:"quicSettings"`
+	SocketSettings  *SocketConfig       `json:"sockopt"`
+	GRPCConfig      *GRPCConfig         `json:"grpcSettings"`
+	GUNConfig       *GRPCConfig         `json:"gunSettings"`
 }
 
 // Build implements Buildable.
@@ -586,6 +681,31 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 		config.SecuritySettings = append(config.SecuritySettings, tm)
 		config.SecurityType = tm.Type
 	}
+	if strings.EqualFold(c.Security, "reality") {
+		if config.ProtocolName != "tcp" {
+			return nil, newError("REALITY only supports TCP for now.")
+		}
+		if c.REALITYSettings == nil {
+			return nil, newError(`REALITY: Empty "realitySettings".`)
+		}
+		// The handshake must be the first data on the connection, so that it can be forwarded to "dest".
+		if c.TCPSettings != nil && len(c.TCPSettings.HeaderConfig) > 0 {
+			headerConfig, _, err := tcpHeaderLoader.Load(c.TCPSettings.HeaderConfig)
+			if err != nil {
+				return nil, newError("invalid TCP header config").Base(err).AtError()
+			}
+			if _, ok := headerConfig.(*NoOpConnectionAuthenticator); !ok {
+				return nil, newError("REALITY doesn't support TCP header.")
+			}
+		}
+		ts, err := c.REALITYSettings.Build()
+		if err != nil {
+			return nil, newError("Failed to build REALITY config.").Base(err)
+		}
+		tm := serial.ToTypedMessage(ts)
+		config.SecuritySettings = append(config.SecuritySettings, tm)
+		config.SecurityType = tm.Type
+	}
 	if c.TCPSettings != nil {
 		ts, err := c.TCPSettings.Build()
 		if err != nil {
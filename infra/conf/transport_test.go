@@ -2,8 +2,10 @@ package conf_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/serial"
 	. "github.com/eagleql/xray-core/infra/conf"
@@ -312,3 +314,24 @@ func TestTransportConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestStreamConfigREALITYHeader(t *testing.T) {
+	config := new(StreamConfig)
+	common.Must(json.Unmarshal([]byte(`{
+		"security": "reality",
+		"realitySettings": {
+			"dest": "example.com:443",
+			"serverNames": ["example.com"],
+			"privateKey": "SMYHqYUGkxjYUMa3o4AyURqnTXX9ygrmVOyFUvrbDkc",
+			"shortIds": [""]
+		},
+		"tcpSettings": {
+			"header": {
+				"type": "http"
+			}
+		}
+	}`), config))
+	if _, err := config.Build(); err == nil || !strings.Contains(err.Error(), "TCP header") {
+		t.Error("expect REALITY with TCP header to be rejected, but got ", err)
+	}
+}
@@ -99,6 +99,7 @@ type TrojanUserConfig struct {
 	Level    byte   `json:"level"`
 	Email    string `json:"email"`
 	Flow     string `json:"flow"`
+	UserLimits
 }
 
 // TrojanServerConfig is Inbound configuration
@@ -130,6 +131,9 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 		user.Email = rawUser.Email
 		user.Level = uint32(rawUser.Level)
 		user.Account = serial.ToTypedMessage(account)
+		if err := rawUser.UserLimits.Apply(user); err != nil {
+			return nil, newError("Trojan clients: invalid user").Base(err)
+		}
 		config.Users[idx] = user
 	}
 
@@ -46,6 +46,13 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		if err := json.Unmarshal(rawUser, account); err != nil {
 			return nil, newError(`VLESS clients: invalid user`).Base(err)
 		}
+		limits := new(UserLimits)
+		if err := json.Unmarshal(rawUser, limits); err != nil {
+			return nil, newError(`VLESS clients: invalid user`).Base(err)
+		}
+		if err := limits.Apply(user); err != nil {
+			return nil, newError(`VLESS clients: invalid user`).Base(err)
+		}
 
 		u, err := uuid.ParseString(account.Id)
 		if err != nil {
@@ -54,7 +61,7 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		account.Id = u.String()
 
 		switch account.Flow {
-		case "", "xtls-rprx-origin", "xtls-rprx-direct":
+		case "", "xtls-rprx-origin", "xtls-rprx-direct", "xtls-rprx-vision":
 		case "xtls-rprx-splice":
 			return nil, newError(`VLESS clients: inbound doesn't support "xtls-rprx-splice" in this version, please use "xtls-rprx-direct" instead`)
 		default:
@@ -183,7 +190,8 @@ func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 			account.Id = u.String()
 
 			switch account.Flow {
-			case "", "xtls-rprx-origin", "xtls-rprx-origin-udp443", "xtls-rprx-direct", "xtls-rprx-direct-udp443":
+			case "", "xtls-rprx-origin", "xtls-rprx-origin-udp443", "xtls-rprx-direct", "xtls-rprx-direct-udp443",
+				"xtls-rprx-vision", "xtls-rprx-vision-udp443":
 			case "xtls-rprx-splice", "xtls-rprx-splice-udp443":
 				if runtime.GOOS != "linux" && runtime.GOOS != "android" {
 					return nil, newError(`VLESS users: "` + account.Flow + `" only support linux in this version`)
@@ -108,6 +108,13 @@ func (c *VMessInboundConfig) Build() (proto.Message, error) {
 		if err := json.Unmarshal(rawData, account); err != nil {
 			return nil, newError("invalid VMess user").Base(err)
 		}
+		limits := new(UserLimits)
+		if err := json.Unmarshal(rawData, limits); err != nil {
+			return nil, newError("invalid VMess user").Base(err)
+		}
+		if err := limits.Apply(user); err != nil {
+			return nil, newError("invalid VMess user").Base(err)
+		}
 
 		u, err := uuid.ParseString(account.ID)
 		if err != nil {
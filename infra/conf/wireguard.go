@@ -0,0 +1,111 @@
+package conf
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/eagleql/xray-core/proxy/wireguard"
+	"github.com/golang/protobuf/proto"
+)
+
+type WireGuardPeerConfig struct {
+	PublicKey    string   `json:"publicKey"`
+	PreSharedKey string   `json:"preSharedKey"`
+	Endpoint     string   `json:"endpoint"`
+	KeepAlive    uint32   `json:"keepAlive"`
+	AllowedIPs   []string `json:"allowedIPs"`
+}
+
+// Build implements Buildable
+func (c *WireGuardPeerConfig) Build() (proto.Message, error) {
+	config := &wireguard.PeerConfig{
+		Endpoint:   c.Endpoint,
+		KeepAlive:  c.KeepAlive,
+		AllowedIps: c.AllowedIPs,
+	}
+	var err error
+	if config.PublicKey, err = parseWireGuardKey(c.PublicKey); err != nil {
+		return nil, newError("invalid WireGuard public key: ", c.PublicKey).Base(err)
+	}
+	if c.PreSharedKey != "" {
+		if config.PreSharedKey, err = parseWireGuardKey(c.PreSharedKey); err != nil {
+			return nil, newError("invalid WireGuard pre-shared key").Base(err)
+		}
+	}
+	if config.Endpoint == "" {
+		return nil, newError("WireGuard peer endpoint is not specified.")
+	}
+	return config, nil
+}
+
+type WireGuardConfig struct {
+	SecretKey      string                 `json:"secretKey"`
+	Address        []string               `json:"address"`
+	Peers          []*WireGuardPeerConfig `json:"peers"`
+	MTU            uint32                 `json:"mtu"`
+	Reserved       []int                  `json:"reserved"`
+	DomainStrategy string                 `json:"domainStrategy"`
+	UserLevel      uint32                 `json:"userLevel"`
+}
+
+// Build implements Buildable
+func (c *WireGuardConfig) Build() (proto.Message, error) {
+	config := &wireguard.Config{
+		Address:   c.Address,
+		Mtu:       c.MTU,
+		UserLevel: c.UserLevel,
+	}
+	var err error
+	if config.SecretKey, err = parseWireGuardKey(c.SecretKey); err != nil {
+		return nil, newError("invalid WireGuard secret key").Base(err)
+	}
+	if len(config.Address) == 0 {
+		return nil, newError("WireGuard address is not specified.")
+	}
+	if len(c.Peers) == 0 {
+		return nil, newError("0 WireGuard peer configured.")
+	}
+	for _, peer := range c.Peers {
+		pc, err := peer.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Peers = append(config.Peers, pc.(*wireguard.PeerConfig))
+	}
+
+	if len(c.Reserved) != 0 {
+		if len(c.Reserved) != 3 {
+			return nil, newError("WireGuard reserved must be 3 bytes.")
+		}
+		for _, b := range c.Reserved {
+			if b < 0 || b > 255 {
+				return nil, newError("invalid WireGuard reserved byte: ", b)
+			}
+			config.Reserved = append(config.Reserved, byte(b))
+		}
+	}
+
+	switch strings.ToLower(c.DomainStrategy) {
+	case "", "useip", "use_ip":
+		config.DomainStrategy = wireguard.Config_USE_IP
+	case "useip4", "useipv4", "use_ipv4", "use_ip_v4", "use_ip4":
+		config.DomainStrategy = wireguard.Config_USE_IP4
+	case "useip6", "useipv6", "use_ipv6", "use_ip_v6", "use_ip6":
+		config.DomainStrategy = wireguard.Config_USE_IP6
+	default:
+		return nil, newError("unsupported WireGuard domain strategy: ", c.DomainStrategy)
+	}
+	return config, nil
+}
+
+// parseWireGuardKey decodes a key in base64, as in the configuration of WireGuard.
+func parseWireGuardKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, newError("key must be 32 bytes, but got ", len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,55 @@
+package conf_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	. "github.com/eagleql/xray-core/infra/conf"
+	"github.com/eagleql/xray-core/proxy/wireguard"
+)
+
+func TestWireGuardConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(WireGuardConfig)
+	}
+	secretKey := make([]byte, 32)
+	publicKey := make([]byte, 32)
+	for i := range secretKey {
+		secretKey[i] = byte(i)
+		publicKey[i] = byte(i + 32)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"secretKey": "` + base64.StdEncoding.EncodeToString(secretKey) + `",
+				"address": ["172.16.0.2/32", "fd01::2/128"],
+				"peers": [{
+					"publicKey": "` + base64.StdEncoding.EncodeToString(publicKey) + `",
+					"endpoint": "engage.example.com:2408",
+					"keepAlive": 25,
+					"allowedIPs": ["0.0.0.0/0"]
+				}],
+				"mtu": 1280,
+				"reserved": [1, 2, 3],
+				"domainStrategy": "UseIPv4",
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &wireguard.Config{
+				SecretKey: secretKey,
+				Address:   []string{"172.16.0.2/32", "fd01::2/128"},
+				Peers: []*wireguard.PeerConfig{{
+					PublicKey:  publicKey,
+					Endpoint:   "engage.example.com:2408",
+					KeepAlive:  25,
+					AllowedIps: []string{"0.0.0.0/0"},
+				}},
+				Mtu:            1280,
+				Reserved:       []byte{1, 2, 3},
+				DomainStrategy: wireguard.Config_USE_IP4,
+				UserLevel:      1,
+			},
+		},
+	})
+}
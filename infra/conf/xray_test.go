@@ -447,3 +447,37 @@ func TestConfig_Override(t *testing.T) {
 		})
 	}
 }
+
+func TestUserQuotaRequiresStats(t *testing.T) {
+	inbound := `"inbounds": [{
+		"port": 443,
+		"protocol": "trojan",
+		"settings": {
+			"clients": [{"password": "password", "email": "love@example.com", "level": 1, "totalTrafficLimit": 1024}]
+		}
+	}]`
+	cases := []struct {
+		Input string
+		Valid bool
+	}{
+		{
+			Input: `{` + inbound + `}`,
+			Valid: false,
+		},
+		{
+			Input: `{"policy": {"levels": {"1": {"statsUserUplink": true}}}, ` + inbound + `}`,
+			Valid: false,
+		},
+		{
+			Input: `{"policy": {"levels": {"1": {"statsUserUplink": true, "statsUserDownlink": true}}}, ` + inbound + `}`,
+			Valid: true,
+		},
+	}
+	for _, c := range cases {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(c.Input), config))
+		if _, err := config.Build(); (err == nil) != c.Valid {
+			t.Error("unexpected result for ", c.Input, ": ", err)
+		}
+	}
+}
@@ -9,6 +9,15 @@ var CmdAPI = &base.Command{
 	UsageLine: "{{.Exec}} api",
 	Short:     "Call an API in an Xray process",
 	Long: `{{.Exec}} {{.LongName}} provides tools to manipulate Xray via its API.
+
+All the commands accept the following arguments to connect to an API
+protected by authentication:
+	-token
+		Bearer token of the API credential.
+	-cacert
+		CA certificate to verify the API server. Enables TLS.
+	-cert, -key
+		Client certificate and key. Enables TLS.
 `,
 	Commands: []*base.Command{
 		cmdRestartLogger,
@@ -19,5 +28,14 @@ var CmdAPI = &base.Command{
 		cmdAddOutbounds,
 		cmdRemoveInbounds,
 		cmdRemoveOutbounds,
+		cmdListInbounds,
+		cmdListOutbounds,
+		cmdInboundUser,
+		cmdInboundUserCount,
+		cmdAddUsers,
+		cmdRemoveUsers,
+		cmdReloadConfig,
+		cmdListBridges,
+		cmdDrainBridge,
 	},
 }
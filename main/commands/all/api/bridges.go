@@ -0,0 +1,89 @@
+package api
+
+import (
+	reverseService "github.com/eagleql/xray-core/app/reverse/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdListBridges = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api bridges [--server=127.0.0.1:8080] [-tag portal]",
+	Short:       "List bridges connected to portals",
+	Long: `
+List the bridges connected to the reverse proxy portals of Xray, with
+their workers.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-tag
+		Tag of the portal. Lists all portals if not specified.
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag portal
+`,
+	Run: executeListBridges,
+}
+
+var cmdDrainBridge = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api drainbridge [--server=127.0.0.1:8080] -tag portal [-cancel] <bridge id>",
+	Short:       "Drain a bridge connected to a portal",
+	Long: `
+Stop sending new connections of a reverse proxy portal to a bridge, and tell
+the bridge to drain its workers.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-tag
+		Tag of the portal.
+	-cancel
+		Resume sending new connections to the bridge.
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag portal bridge-1
+`,
+	Run: executeDrainBridge,
+}
+
+func executeListBridges(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := reverseService.NewReverseServiceClient(conn)
+	resp, err := client.ListBridges(ctx, &reverseService.ListBridgesRequest{Tag: *tag})
+	if err != nil {
+		base.Fatalf("failed to list bridges: %s", err)
+	}
+	showResponese(resp)
+}
+
+func executeDrainBridge(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	cancel := cmd.Flag.Bool("cancel", false, "")
+	cmd.Flag.Parse(args)
+
+	if *tag == "" || cmd.Flag.NArg() != 1 {
+		base.Fatalf("portal tag and bridge id required")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := reverseService.NewReverseServiceClient(conn)
+	resp, err := client.DrainBridge(ctx, &reverseService.DrainBridgeRequest{
+		Tag:      *tag,
+		BridgeId: cmd.Flag.Arg(0),
+		Cancel:   *cancel,
+	})
+	if err != nil {
+		base.Fatalf("failed to drain bridge: %s", err)
+	}
+	showResponese(resp)
+}
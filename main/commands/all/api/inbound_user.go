@@ -0,0 +1,51 @@
+package api
+
+import (
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdInboundUser = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api inbounduser [--server=127.0.0.1:8080] -tag=tag [-email=email]",
+	Short:       "Get inbound user",
+	Long: `
+Get users of an inbound from Xray.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-tag
+		Tag of the inbound.
+	-email
+		Email of the user. All users are listed if not specified.
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -email="xray@love.com"
+`,
+	Run: executeInboundUser,
+}
+
+func executeInboundUser(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	email := cmd.Flag.String("email", "", "")
+	cmd.Flag.Parse(args)
+	if *tag == "" {
+		base.Fatalf("inbound tag not specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	r := &handlerService.GetInboundUserRequest{
+		Tag:   *tag,
+		Email: *email,
+	}
+	resp, err := client.GetInboundUsers(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to get inbound user: %s", err)
+	}
+	showResponese(resp)
+}
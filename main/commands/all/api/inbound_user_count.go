@@ -0,0 +1,47 @@
+package api
+
+import (
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdInboundUserCount = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api inboundusercount [--server=127.0.0.1:8080] -tag=tag",
+	Short:       "Get inbound user count",
+	Long: `
+Get the number of users of an inbound from Xray.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-tag
+		Tag of the inbound.
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
+`,
+	Run: executeInboundUserCount,
+}
+
+func executeInboundUserCount(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	cmd.Flag.Parse(args)
+	if *tag == "" {
+		base.Fatalf("inbound tag not specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	r := &handlerService.GetInboundUserRequest{
+		Tag: *tag,
+	}
+	resp, err := client.GetInboundUserCount(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to get inbound user count: %s", err)
+	}
+	showResponese(resp)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdListInbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsi [--server=127.0.0.1:8080]",
+	Short:       "List inbounds",
+	Long: `
+List all inbounds of Xray, with their tags and configs.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeListInbounds,
+}
+
+func executeListInbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListInbounds(ctx, &handlerService.ListInboundsRequest{})
+	if err != nil {
+		base.Fatalf("failed to list inbounds: %s", err)
+	}
+	showResponese(resp)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdListOutbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lso [--server=127.0.0.1:8080]",
+	Short:       "List outbounds",
+	Long: `
+List all outbounds of Xray, with their tags and configs.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeListOutbounds,
+}
+
+func executeListOutbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListOutbounds(ctx, &handlerService.ListOutboundsRequest{})
+	if err != nil {
+		base.Fatalf("failed to list outbounds: %s", err)
+	}
+	showResponese(resp)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdReloadConfig = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api reload [--server=127.0.0.1:8080]",
+	Short:       "Reload config",
+	Long: `
+Reload the config files of Xray. Only the inbounds and outbounds 
+whose configs have changed are restarted, together with the routing 
+and DNS settings.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+Example:
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeReloadConfig,
+}
+
+func executeReloadConfig(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ReloadConfig(ctx, &handlerService.ReloadConfigRequest{})
+	if err != nil {
+		base.Fatalf("failed to reload config: %s", err)
+	}
+	showResponese(resp)
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +17,7 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/eagleql/xray-core/common/buf"
@@ -26,6 +29,10 @@ type serviceHandler func(ctx context.Context, conn *grpc.ClientConn, cmd *base.C
 var (
 	apiServerAddrPtr string
 	apiTimeout       int
+	apiToken         string
+	apiCACert        string
+	apiCert          string
+	apiKey           string
 )
 
 func setSharedFlags(cmd *base.Command) {
@@ -33,11 +40,57 @@ func setSharedFlags(cmd *base.Command) {
 	cmd.Flag.StringVar(&apiServerAddrPtr, "server", "127.0.0.1:8080", "")
 	cmd.Flag.IntVar(&apiTimeout, "t", 3, "")
 	cmd.Flag.IntVar(&apiTimeout, "timeout", 3, "")
+	cmd.Flag.StringVar(&apiToken, "token", "", "")
+	cmd.Flag.StringVar(&apiCACert, "cacert", "", "")
+	cmd.Flag.StringVar(&apiCert, "cert", "", "")
+	cmd.Flag.StringVar(&apiKey, "key", "", "")
+}
+
+// tokenCredential sends the API token as a bearer token with each call.
+type tokenCredential string
+
+func (t tokenCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredential) RequireTransportSecurity() bool {
+	return false
+}
+
+func dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithBlock()}
+	if apiCACert == "" && apiCert == "" {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		config := &tls.Config{}
+		if apiCACert != "" {
+			ca, err := ioutil.ReadFile(apiCACert)
+			if err != nil {
+				base.Fatalf("failed to read CA certificate %s: %s", apiCACert, err)
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(ca) {
+				base.Fatalf("failed to parse CA certificate %s", apiCACert)
+			}
+		}
+		if apiCert != "" {
+			cert, err := tls.LoadX509KeyPair(apiCert, apiKey)
+			if err != nil {
+				base.Fatalf("failed to load client certificate: %s", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	}
+	if apiToken != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredential(apiToken)))
+	}
+	return opts
 }
 
 func dialAPIServer() (conn *grpc.ClientConn, ctx context.Context, close func()) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(apiTimeout)*time.Second)
-	conn, err := grpc.DialContext(ctx, apiServerAddrPtr, grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := grpc.DialContext(ctx, apiServerAddrPtr, dialOptions()...)
 	if err != nil {
 		base.Fatalf("failed to dial %s", apiServerAddrPtr)
 	}
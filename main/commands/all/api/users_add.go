@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/infra/conf"
+	jsonSerial "github.com/eagleql/xray-core/infra/conf/serial"
+	"github.com/eagleql/xray-core/main/commands/base"
+	"github.com/eagleql/xray-core/proxy/http"
+	"github.com/eagleql/xray-core/proxy/shadowsocks"
+	"github.com/eagleql/xray-core/proxy/socks"
+	"github.com/eagleql/xray-core/proxy/trojan"
+	vlessIn "github.com/eagleql/xray-core/proxy/vless/inbound"
+	vmessIn "github.com/eagleql/xray-core/proxy/vmess/inbound"
+)
+
+var cmdAddUsers = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api adu [--server=127.0.0.1:8080] <c1.json> [c2.json]...",
+	Short:       "Add users to inbounds",
+	Long: `
+Add users to inbounds of Xray. The users of each inbound in the config
+files are added to the running inbound with the same tag.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+Example:
+    {{.Exec}} {{.LongName}} --server=127.0.0.1:8080 c1.json c2.json
+`,
+	Run: executeAddUsers,
+}
+
+func executeAddUsers(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+	unnamedArgs := cmd.Flag.Args()
+	if len(unnamedArgs) == 0 {
+		fmt.Println("reading from stdin:")
+		unnamedArgs = []string{"stdin:"}
+	}
+
+	ins := make([]conf.InboundDetourConfig, 0)
+	for _, arg := range unnamedArgs {
+		r, err := loadArg(arg)
+		if err != nil {
+			base.Fatalf("failed to load %s: %s", arg, err)
+		}
+		conf, err := jsonSerial.DecodeJSONConfig(r)
+		if err != nil {
+			base.Fatalf("failed to decode %s: %s", arg, err)
+		}
+		ins = append(ins, conf.InboundConfigs...)
+	}
+	if len(ins) == 0 {
+		base.Fatalf("no valid inbound found")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, in := range ins {
+		i, err := in.Build()
+		if err != nil {
+			base.Fatalf("failed to build conf: %s", err)
+		}
+		users, err := extractInboundUsers(i)
+		if err != nil {
+			base.Fatalf("failed to get users of inbound %s: %s", in.Tag, err)
+		}
+		for _, user := range users {
+			fmt.Println("adding user", user.Email, "to", in.Tag)
+			r := &handlerService.AlterInboundRequest{
+				Tag: in.Tag,
+				Operation: serial.ToTypedMessage(&handlerService.AddUserOperation{
+					User: user,
+				}),
+			}
+			resp, err := client.AlterInbound(ctx, r)
+			if err != nil {
+				base.Fatalf("failed to add user: %s", err)
+			}
+			showResponese(resp)
+		}
+	}
+}
+
+// extractInboundUsers returns the users in the proxy settings of an inbound.
+func extractInboundUsers(inbound *core.InboundHandlerConfig) ([]*protocol.User, error) {
+	if inbound.ProxySettings == nil {
+		return nil, nil
+	}
+	settings, err := inbound.ProxySettings.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+	switch s := settings.(type) {
+	case *vlessIn.Config:
+		return s.Clients, nil
+	case *vmessIn.Config:
+		return s.User, nil
+	case *trojan.ServerConfig:
+		return s.Users, nil
+	case *shadowsocks.ServerConfig:
+		return s.Users, nil
+	case *http.ServerConfig:
+		return append(s.Users, accountUsers(s.Accounts, s.UserLevel, func(username, password string) proto.Message {
+			return &http.Account{Username: username, Password: password}
+		})...), nil
+	case *socks.ServerConfig:
+		return append(s.Users, accountUsers(s.Accounts, s.UserLevel, func(username, password string) proto.Message {
+			return &socks.Account{Username: username, Password: password}
+		})...), nil
+	default:
+		return nil, fmt.Errorf("unsupported inbound: %s", inbound.ProxySettings.Type)
+	}
+}
+
+// accountUsers converts username and password accounts to users with the usernames as emails.
+func accountUsers(accounts map[string]string, level uint32, account func(username, password string) proto.Message) []*protocol.User {
+	usernames := make([]string, 0, len(accounts))
+	for username := range accounts {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	users := make([]*protocol.User, 0, len(usernames))
+	for _, username := range usernames {
+		users = append(users, &protocol.User{
+			Email:   username,
+			Level:   level,
+			Account: serial.ToTypedMessage(account(username, accounts[username])),
+		})
+	}
+	return users
+}
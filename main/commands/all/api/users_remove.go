@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+
+	handlerService "github.com/eagleql/xray-core/app/proxyman/command"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdRemoveUsers = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api rmu [--server=127.0.0.1:8080] -tag=tag <email1> [email2]...",
+	Short:       "Remove users from an inbound",
+	Long: `
+Remove users from an inbound of Xray.
+Arguments:
+	-s, -server 
+		The API server address. Default 127.0.0.1:8080
+	-t, -timeout
+		Timeout seconds to call API. Default 3
+	-tag
+		Tag of the inbound.
+Example:
+    {{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" "xray@love.com"
+`,
+	Run: executeRemoveUsers,
+}
+
+func executeRemoveUsers(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	cmd.Flag.Parse(args)
+	emails := cmd.Flag.Args()
+	if *tag == "" {
+		base.Fatalf("inbound tag not specified")
+	}
+	if len(emails) == 0 {
+		base.Fatalf("no user to remove")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, email := range emails {
+		fmt.Println("removing user", email, "from", *tag)
+		r := &handlerService.AlterInboundRequest{
+			Tag: *tag,
+			Operation: serial.ToTypedMessage(&handlerService.RemoveUserOperation{
+				Email: email,
+			}),
+		}
+		resp, err := client.AlterInbound(ctx, r)
+		if err != nil {
+			base.Fatalf("failed to remove user: %s", err)
+		}
+		showResponese(resp)
+	}
+}
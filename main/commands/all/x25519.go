@@ -0,0 +1,63 @@
+package all
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/eagleql/xray-core/main/commands/base"
+)
+
+var cmdX25519 = &base.Command{
+	UsageLine: `{{.Exec}} x25519 [-i "private key (base64.RawURLEncoding)"]`,
+	Short:     `Generate key pair for X25519 key exchange`,
+	Long: `
+Generate key pair for X25519 key exchange, as used by REALITY.
+
+Random: {{.Exec}} x25519
+
+From private key: {{.Exec}} x25519 -i "private key (base64.RawURLEncoding)"
+`,
+}
+
+func init() {
+	cmdX25519.Run = executeX25519 // break init loop
+}
+
+var inputPrivateKey = cmdX25519.Flag.String("i", "", "")
+
+func executeX25519(cmd *base.Command, args []string) {
+	var privateKey []byte
+	if *inputPrivateKey != "" {
+		key, err := base64.RawURLEncoding.DecodeString(*inputPrivateKey)
+		if err != nil || len(key) != curve25519.ScalarSize {
+			fmt.Println("Invalid input private key.")
+			return
+		}
+		privateKey = key
+	} else {
+		privateKey = make([]byte, curve25519.ScalarSize)
+		if _, err := rand.Read(privateKey); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	}
+
+	// Modify random bytes using algorithm described at:
+	// https://cr.yp.to/ecdh.html.
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("Private key: %v\nPublic key: %v\n",
+		base64.RawURLEncoding.EncodeToString(privateKey),
+		base64.RawURLEncoding.EncodeToString(publicKey))
+}
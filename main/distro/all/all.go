@@ -12,6 +12,7 @@ import (
 	_ "github.com/eagleql/xray-core/app/commander"
 	_ "github.com/eagleql/xray-core/app/log/command"
 	_ "github.com/eagleql/xray-core/app/proxyman/command"
+	_ "github.com/eagleql/xray-core/app/reverse/command"
 	_ "github.com/eagleql/xray-core/app/stats/command"
 
 	// Other optional features.
@@ -29,14 +30,17 @@ import (
 	_ "github.com/eagleql/xray-core/proxy/dokodemo"
 	_ "github.com/eagleql/xray-core/proxy/freedom"
 	_ "github.com/eagleql/xray-core/proxy/http"
+	_ "github.com/eagleql/xray-core/proxy/loopback"
 	_ "github.com/eagleql/xray-core/proxy/mtproto"
 	_ "github.com/eagleql/xray-core/proxy/shadowsocks"
 	_ "github.com/eagleql/xray-core/proxy/socks"
+	_ "github.com/eagleql/xray-core/proxy/ssh"
 	_ "github.com/eagleql/xray-core/proxy/trojan"
 	_ "github.com/eagleql/xray-core/proxy/vless/inbound"
 	_ "github.com/eagleql/xray-core/proxy/vless/outbound"
 	_ "github.com/eagleql/xray-core/proxy/vmess/inbound"
 	_ "github.com/eagleql/xray-core/proxy/vmess/outbound"
+	_ "github.com/eagleql/xray-core/proxy/wireguard"
 
 	// Transports
 	_ "github.com/eagleql/xray-core/transport/internet/domainsocket"
@@ -36,6 +36,10 @@ Default "auto".
 
 The -test flag tells Xray to test config files only, 
 without launching the server
+
+Xray reloads the config files on SIGHUP. Only the inbounds and
+outbounds whose configs have changed are restarted, together
+with the routing and DNS settings.
 	`,
 }
 
@@ -94,8 +98,16 @@ func executeRun(cmd *base.Command, args []string) {
 
 	{
 		osSignals := make(chan os.Signal, 1)
-		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-		<-osSignals
+		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := range osSignals {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			newError("reloading config on SIGHUP").AtWarning().WriteToLog()
+			if err := server.ReloadConfig(); err != nil {
+				newError("failed to reload config").Base(err).AtError().WriteToLog()
+			}
+		}
 	}
 }
 
@@ -125,7 +137,7 @@ func getRegepxByFormat() string {
 	}
 }
 
-func readConfDir(dirPath string) {
+func readConfDir(dirPath string, files *cmdarg.Arg) {
 	confs, err := ioutil.ReadDir(dirPath)
 	if err != nil {
 		log.Fatalln(err)
@@ -136,22 +148,23 @@ func readConfDir(dirPath string) {
 			log.Fatalln(err)
 		}
 		if matched {
-			configFiles.Set(path.Join(dirPath, f.Name()))
+			files.Set(path.Join(dirPath, f.Name()))
 		}
 	}
 }
 
 func getConfigFilePath() cmdarg.Arg {
+	files := append(cmdarg.Arg{}, configFiles...)
 	if dirExists(configDir) {
 		log.Println("Using confdir from arg:", configDir)
-		readConfDir(configDir)
+		readConfDir(configDir, &files)
 	} else if envConfDir := platform.GetConfDirPath(); dirExists(envConfDir) {
 		log.Println("Using confdir from env:", envConfDir)
-		readConfDir(envConfDir)
+		readConfDir(envConfDir, &files)
 	}
 
-	if len(configFiles) > 0 {
-		return configFiles
+	if len(files) > 0 {
+		return files
 	}
 
 	if workingDir, err := os.Getwd(); err == nil {
@@ -179,7 +192,7 @@ func getConfigFormat() string {
 	return f
 }
 
-func startXray() (core.Server, error) {
+func loadConfig() (*core.Config, error) {
 	configFiles := getConfigFilePath()
 
 	//config, err := core.LoadConfig(getConfigFormat(), configFiles[0], configFiles)
@@ -189,11 +202,20 @@ func startXray() (core.Server, error) {
 	if err != nil {
 		return nil, newError("failed to load config files: [", configFiles.String(), "]").Base(err)
 	}
+	return c, nil
+}
+
+func startXray() (*core.Instance, error) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	server, err := core.New(c)
 	if err != nil {
 		return nil, newError("failed to create server").Base(err)
 	}
+	server.SetConfigReloader(loadConfig)
 
 	return server, nil
 }
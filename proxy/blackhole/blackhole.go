@@ -8,13 +8,25 @@ import (
 	"time"
 
 	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/transport"
 	"github.com/eagleql/xray-core/transport/internet"
 )
 
+const (
+	// requestTimeout is the time to wait for each request packet to respond to.
+	requestTimeout = time.Second
+
+	defaultTarpitDuration = 2 * time.Minute
+	defaultTarpitInterval = time.Second
+)
+
 // Handler is an outbound connection that silently swallow the entire payload.
 type Handler struct {
 	response ResponseConfig
+	tarpit   *Tarpit
 }
 
 // New creates a new blackhole handler.
@@ -25,13 +37,29 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 	}
 	return &Handler{
 		response: response,
+		tarpit:   config.Tarpit,
 	}, nil
 }
 
 // Process implements OutboundHandler.Dispatch().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
-	nBytes := h.response.WriteTo(link.Writer)
-	if nBytes > 0 {
+	var target net.Destination
+	if outbound := session.OutboundFromContext(ctx); outbound != nil {
+		target = outbound.Target
+	}
+
+	var nBytes int32
+	switch response := h.response.(type) {
+	case RequestResponseConfig:
+		nBytes = respondToRequests(response, target, link)
+	case *HTTPResponse, *TLSResponse:
+		nBytes = responseForTraffic(response, readFirst(link.Reader)).WriteTo(link.Writer)
+	default:
+		nBytes = response.WriteTo(link.Writer)
+	}
+	if h.tarpit != nil {
+		h.holdConnection(ctx, link.Reader)
+	} else if nBytes > 0 {
 		// Sleep a little here to make sure the response is sent to client.
 		time.Sleep(time.Second)
 	}
@@ -39,6 +67,80 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	return nil
 }
 
+// readFirst returns the first data from the client, or nil if there is none within requestTimeout.
+func readFirst(reader buf.Reader) []byte {
+	timeoutReader, ok := reader.(buf.TimeoutReader)
+	if !ok {
+		return nil
+	}
+	mb, _ := timeoutReader.ReadMultiBufferTimeout(requestTimeout)
+	defer buf.ReleaseMulti(mb)
+	if mb.IsEmpty() {
+		return nil
+	}
+	return append([]byte(nil), mb[0].Bytes()...)
+}
+
+// respondToRequests writes the response to each request packet, until no more packets come within requestTimeout.
+// Packets without a destination of their own are sent to target.
+func respondToRequests(response RequestResponseConfig, target net.Destination, link *transport.Link) int32 {
+	reader, ok := link.Reader.(buf.TimeoutReader)
+	if !ok {
+		return response.WriteTo(link.Writer)
+	}
+	var nBytes int32
+	for {
+		mb, err := reader.ReadMultiBufferTimeout(requestTimeout)
+		for _, b := range mb {
+			dest := target
+			if b.UDP != nil {
+				dest = *b.UDP
+			}
+			nBytes += response.WriteResponseTo(b, dest, link.Writer)
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			return nBytes
+		}
+	}
+}
+
+// holdConnection reads from the connection slowly until the tarpit duration ends, so the client is stalled when
+// the buffer of the connection is full. It reads at most once per interval.
+func (h *Handler) holdConnection(ctx context.Context, reader buf.Reader) {
+	duration := time.Duration(h.tarpit.Duration) * time.Second
+	if duration == 0 {
+		duration = defaultTarpitDuration
+	}
+	interval := time.Duration(h.tarpit.Interval) * time.Millisecond
+	if interval == 0 {
+		interval = defaultTarpitInterval
+	}
+
+	deadline := time.NewTimer(duration)
+	defer deadline.Stop()
+	wait := time.NewTimer(interval)
+	defer wait.Stop()
+	for {
+		// The read takes at most the interval, and the rest of the interval is waited for before the next one.
+		if timeoutReader, ok := reader.(buf.TimeoutReader); ok {
+			mb, err := timeoutReader.ReadMultiBufferTimeout(interval)
+			buf.ReleaseMulti(mb)
+			if err != nil && err != buf.ErrReadTimeout {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-wait.C:
+			wait.Reset(interval)
+		}
+	}
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return New(ctx, config.(*Config))
@@ -38,3 +38,40 @@ func TestBlackholeHTTPResponse(t *testing.T) {
 		t.Error("expect http response, but nothing")
 	}
 }
+
+func TestBlackholeResponseForTraffic(t *testing.T) {
+	clientHello := []byte{0x16, 0x03, 0x01, 0x00, 0x10, 0x01}
+	request := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	for _, test := range []struct {
+		response *serial.TypedMessage
+		first    []byte
+		isTLS    bool
+	}{
+		{serial.ToTypedMessage(&blackhole.HTTPResponse{}), clientHello, true},
+		{serial.ToTypedMessage(&blackhole.TLSResponse{}), request, false},
+	} {
+		handler, err := blackhole.New(context.Background(), &blackhole.Config{
+			Response: test.response,
+		})
+		common.Must(err)
+
+		uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+		downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+		common.Must(uplinkWriter.WriteMultiBuffer(buf.MergeBytes(nil, test.first)))
+		response := make(chan buf.MultiBuffer, 1)
+		go func() {
+			mb, _ := downlinkReader.ReadMultiBuffer()
+			response <- mb
+		}()
+		common.Must(handler.Process(context.Background(), &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, nil))
+
+		mb := <-response
+		if mb.IsEmpty() {
+			t.Fatal("expect response, but nothing")
+		}
+		if isTLS := mb[0].Byte(0) == 0x15; isTLS != test.isTLS {
+			t.Error("expect TLS alert ", test.isTLS, " in response to ", test.first, ", but got ", mb.String())
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
@@ -1,8 +1,16 @@
 package blackhole
 
 import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
 )
 
 const (
@@ -13,6 +21,13 @@ Content-Length: 0
 
 
 `
+
+	// tlsAlertHandshakeFailure is the description of the handshake_failure alert.
+	tlsAlertHandshakeFailure = 40
+
+	// dnsTTL is the TTL in seconds of the answers in DNS responses.
+	dnsTTL  = 300
+	dnsPort = net.Port(53)
 )
 
 // ResponseConfig is the configuration for blackhole responses.
@@ -21,13 +36,140 @@ type ResponseConfig interface {
 	WriteTo(buf.Writer) int32
 }
 
+// RequestResponseConfig is the configuration for blackhole responses made for each request packet.
+type RequestResponseConfig interface {
+	ResponseConfig
+	// WriteResponseTo writes the response to the request packet sent to dest to the given buffer.
+	WriteResponseTo(request *buf.Buffer, dest net.Destination, writer buf.Writer) int32
+}
+
 // WriteTo implements ResponseConfig.WriteTo().
 func (*NoneResponse) WriteTo(buf.Writer) int32 { return 0 }
 
 // WriteTo implements ResponseConfig.WriteTo().
-func (*HTTPResponse) WriteTo(writer buf.Writer) int32 {
+func (r *HTTPResponse) WriteTo(writer buf.Writer) int32 {
+	if r.Status == 0 && len(r.Header) == 0 && len(r.Body) == 0 {
+		b := buf.New()
+		common.Must2(b.WriteString(http403response))
+		n := b.Len()
+		writer.WriteMultiBuffer(buf.MultiBuffer{b})
+		return n
+	}
+
+	status := int(r.Status)
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+	var response strings.Builder
+	response.WriteString("HTTP/1.1 " + strconv.Itoa(status) + " " + http.StatusText(status) + "\r\n")
+	response.WriteString("Connection: close\r\n")
+	for _, header := range r.Header {
+		response.WriteString(header.Key + ": " + header.Value + "\r\n")
+	}
+	response.WriteString("Content-Length: " + strconv.Itoa(len(r.Body)) + "\r\n\r\n")
+	response.Write(r.Body)
+
+	mb := buf.MergeBytes(nil, []byte(response.String()))
+	n := mb.Len()
+	writer.WriteMultiBuffer(mb)
+	return n
+}
+
+// WriteTo implements ResponseConfig.WriteTo().
+func (r *TLSResponse) WriteTo(writer buf.Writer) int32 {
+	alert := r.Alert
+	if alert == 0 {
+		alert = tlsAlertHandshakeFailure
+	}
+	b := buf.New()
+	common.Must2(b.Write([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02 /* fatal */, byte(alert)}))
+	n := b.Len()
+	writer.WriteMultiBuffer(buf.MultiBuffer{b})
+	return n
+}
+
+// responseForTraffic returns the response to a connection starting with first. An HTTP response to TLS is replaced by
+// the default TLS alert, and a TLS alert to HTTP by the default HTTP response, so that the client gets an error in
+// the protocol it speaks.
+func responseForTraffic(response ResponseConfig, first []byte) ResponseConfig {
+	isTLS := len(first) >= 2 && first[0] == 0x16 && first[1] == 0x03
+	switch response.(type) {
+	case *HTTPResponse:
+		if isTLS {
+			return new(TLSResponse)
+		}
+	case *TLSResponse:
+		if !isTLS && isHTTP(first) {
+			return new(HTTPResponse)
+		}
+	}
+	return response
+}
+
+// isHTTP returns whether b starts with an HTTP request line.
+func isHTTP(b []byte) bool {
+	i := bytes.IndexByte(b, ' ')
+	if i <= 0 {
+		return false
+	}
+	for _, c := range b[:i] {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo implements ResponseConfig.WriteTo(). DNS responses are only written for requests.
+func (*DNSResponse) WriteTo(buf.Writer) int32 { return 0 }
+
+// WriteResponseTo implements RequestResponseConfig.WriteResponseTo(). Only queries over UDP to port 53 are answered.
+func (r *DNSResponse) WriteResponseTo(request *buf.Buffer, dest net.Destination, writer buf.Writer) int32 {
+	if dest.Network != net.Network_UDP || dest.Port != dnsPort {
+		return 0
+	}
+	var parser dnsmessage.Parser
+	header, err := parser.Start(request.Bytes())
+	if err != nil || header.Response {
+		return 0
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return 0
+	}
+
+	rcode := dnsmessage.RCodeSuccess
+	if r.Nxdomain {
+		rcode = dnsmessage.RCodeNameError
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	builder.EnableCompression()
+	common.Must(builder.StartQuestions())
+	common.Must(builder.Question(question))
+	common.Must(builder.StartAnswers())
+	if !r.Nxdomain {
+		answerHeader := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: dnsTTL}
+		switch question.Type {
+		case dnsmessage.TypeA:
+			common.Must(builder.AResource(answerHeader, dnsmessage.AResource{}))
+		case dnsmessage.TypeAAAA:
+			common.Must(builder.AAAAResource(answerHeader, dnsmessage.AAAAResource{}))
+		}
+	}
+	response, err := builder.Finish()
+	if err != nil {
+		return 0
+	}
+
 	b := buf.New()
-	common.Must2(b.WriteString(http403response))
+	common.Must2(b.Write(response))
+	b.UDP = request.UDP
 	n := b.Len()
 	writer.WriteMultiBuffer(buf.MultiBuffer{b})
 	return n
@@ -2,11 +2,15 @@ package blackhole_test
 
 import (
 	"bufio"
+	"io"
 	"net/http"
 	"testing"
 
+	"golang.org/x/net/dns/dnsmessage"
+
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
 	. "github.com/eagleql/xray-core/proxy/blackhole"
 )
 
@@ -24,3 +28,69 @@ func TestHTTPResponse(t *testing.T) {
 		t.Error("expected status code 403, but got ", response.StatusCode)
 	}
 }
+
+func TestCustomHTTPResponse(t *testing.T) {
+	buffer := buf.New()
+
+	httpResponse := &HTTPResponse{
+		Status: 404,
+		Header: []*Header{{Key: "Server", Value: "nginx"}},
+		Body:   []byte("not found"),
+	}
+	httpResponse.WriteTo(buf.NewWriter(buffer))
+
+	response, err := http.ReadResponse(bufio.NewReader(buffer), nil)
+	common.Must(err)
+	if response.StatusCode != 404 {
+		t.Error("expected status code 404, but got ", response.StatusCode)
+	}
+	if server := response.Header.Get("Server"); server != "nginx" {
+		t.Error("expected server nginx, but got ", server)
+	}
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if string(body) != "not found" {
+		t.Error("expected body not found, but got ", string(body))
+	}
+}
+
+func TestDNSResponse(t *testing.T) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1234, RecursionDesired: true})
+	common.Must(builder.StartQuestions())
+	common.Must(builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("ads.example.com."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	query, err := builder.Finish()
+	common.Must(err)
+
+	for _, nxdomain := range []bool{false, true} {
+		request := buf.New()
+		common.Must2(request.Write(query))
+		buffer := buf.New()
+		(&DNSResponse{Nxdomain: nxdomain}).WriteResponseTo(request, net.UDPDestination(net.LocalHostIP, 53), buf.NewWriter(buffer))
+
+		var message dnsmessage.Message
+		common.Must(message.Unpack(buffer.Bytes()))
+		if message.ID != 1234 || !message.Response {
+			t.Error("expected response to query 1234, but got ", message.Header)
+		}
+		if nxdomain {
+			if message.RCode != dnsmessage.RCodeNameError || len(message.Answers) != 0 {
+				t.Error("expected NXDOMAIN, but got ", message.RCode, " with ", len(message.Answers), " answers")
+			}
+			continue
+		}
+		if len(message.Answers) != 1 || message.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{} {
+			t.Error("expected answer 0.0.0.0, but got ", message.Answers)
+		}
+	}
+
+	request := buf.New()
+	common.Must2(request.Write(query))
+	buffer := buf.New()
+	if n := new(DNSResponse).WriteResponseTo(request, net.UDPDestination(net.LocalHostIP, 443), buf.NewWriter(buffer)); n != 0 {
+		t.Error("expected no response to packets not to port 53, but got ", n, " bytes")
+	}
+}
@@ -0,0 +1,198 @@
+package freedom
+
+import (
+	"crypto/rand"
+	"io"
+	"time"
+
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/dice"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+func randBetween(min, max uint64) uint64 {
+	if max <= min {
+		return min
+	}
+	return min + uint64(dice.Roll(int(max-min+1)))
+}
+
+func sleepBetween(min, max uint64) {
+	if d := randBetween(min, max); d > 0 {
+		time.Sleep(time.Duration(d) * time.Millisecond)
+	}
+}
+
+// isDirect returns whether conn is a connection of the system, rather than through another transport or proxy.
+func isDirect(conn net.Conn) bool {
+	if statConn, ok := conn.(*internet.StatCouterConnection); ok {
+		conn = statConn.Connection
+	}
+	switch conn.(type) {
+	case *net.TCPConn, *net.UDPConn, *internet.PacketConnWrapper:
+		return true
+	default:
+		return false
+	}
+}
+
+// Maximum length of the payload of a TLS record.
+const maxTLSRecordSize = 16384
+
+func (f *Fragment) isTLSHello() bool {
+	return f.PacketsFrom == 0 && f.PacketsTo == 0
+}
+
+// FragmentWriter is an io.Writer that splits the writes in the range of the Fragment into multiple writes.
+type FragmentWriter struct {
+	fragment *Fragment
+	writer   io.Writer
+	count    uint64
+
+	// The beginning of the ClientHello, which is held back until the whole record is written.
+	hello     []byte
+	helloDone bool
+}
+
+// NewFragmentWriter creates a new FragmentWriter.
+func NewFragmentWriter(writer io.Writer, fragment *Fragment) *FragmentWriter {
+	return &FragmentWriter{
+		fragment: fragment,
+		writer:   writer,
+	}
+}
+
+// Write implements io.Writer.
+func (w *FragmentWriter) Write(b []byte) (int, error) {
+	if w.fragment.isTLSHello() {
+		if w.helloDone {
+			return w.writer.Write(b)
+		}
+		return w.writeHello(b)
+	}
+
+	w.count++
+	if w.count < w.fragment.PacketsFrom || w.count > w.fragment.PacketsTo {
+		return w.writer.Write(b)
+	}
+	for from := 0; from < len(b); {
+		to := from + int(randBetween(w.fragment.LengthMin, w.fragment.LengthMax))
+		if to > len(b) || to == from {
+			to = len(b)
+		}
+		if _, err := w.writer.Write(b[from:to]); err != nil {
+			return from, err
+		}
+		from = to
+		if from < len(b) {
+			sleepBetween(w.fragment.IntervalMin, w.fragment.IntervalMax)
+		}
+	}
+	return len(b), nil
+}
+
+// writeHello collects the ClientHello record at the beginning of the data, which may be split across writes, and
+// fragments it once it is complete. Data not starting with a ClientHello is written as is.
+func (w *FragmentWriter) writeHello(b []byte) (int, error) {
+	w.hello = append(w.hello, b...)
+	hello := w.hello
+	if len(hello) == 0 {
+		return 0, nil
+	}
+	if hello[0] != 22 || (len(hello) > 5 && hello[5] != 1) || (len(hello) >= 5 && int(hello[3])<<8|int(hello[4]) > maxTLSRecordSize) {
+		w.helloDone = true
+		w.hello = nil
+		if _, err := w.writer.Write(hello); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if len(hello) <= 5 {
+		return len(b), nil
+	}
+	recordLen := 5 + (int(hello[3])<<8 | int(hello[4]))
+	if len(hello) < recordLen {
+		return len(b), nil
+	}
+
+	w.helloDone = true
+	w.hello = nil
+	if err := w.writeHelloRecords(hello[:recordLen]); err != nil {
+		return 0, err
+	}
+	if len(hello) > recordLen {
+		if _, err := w.writer.Write(hello[recordLen:]); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// writeHelloRecords splits a TLS record into multiple records, each of which is sent in a write of its own.
+func (w *FragmentWriter) writeHelloRecords(record []byte) error {
+	data := record[5:]
+	for from := 0; from < len(data); {
+		to := from + int(randBetween(w.fragment.LengthMin, w.fragment.LengthMax))
+		if to > len(data) || to == from {
+			to = len(data)
+		}
+		fragment := make([]byte, 0, 5+to-from)
+		fragment = append(fragment, record[0], record[1], record[2], byte((to-from)>>8), byte(to-from))
+		fragment = append(fragment, data[from:to]...)
+		if _, err := w.writer.Write(fragment); err != nil {
+			return err
+		}
+		from = to
+		if from < len(data) {
+			sleepBetween(w.fragment.IntervalMin, w.fragment.IntervalMax)
+		}
+	}
+	return nil
+}
+
+// NoisePacketWriter is a packet writer that sends noises before the first packet.
+type NoisePacketWriter struct {
+	buf.Writer
+	noises    []*Noise
+	noiseSent bool
+}
+
+// NewNoisePacketWriter creates a new NoisePacketWriter.
+func NewNoisePacketWriter(writer buf.Writer, noises []*Noise) *NoisePacketWriter {
+	return &NoisePacketWriter{
+		Writer: writer,
+		noises: noises,
+	}
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *NoisePacketWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if w.noiseSent || mb.IsEmpty() {
+		return w.Writer.WriteMultiBuffer(mb)
+	}
+	w.noiseSent = true
+
+	for _, noise := range w.noises {
+		b := buf.New()
+		if len(noise.Packet) > 0 {
+			b.Write(noise.Packet)
+		} else {
+			length := randBetween(noise.LengthMin, noise.LengthMax)
+			if length > buf.Size {
+				length = buf.Size
+			}
+			rand.Read(b.Extend(int32(length)))
+		}
+		if mb[0].UDP != nil {
+			dest := *mb[0].UDP
+			b.UDP = &dest
+		}
+		if err := w.Writer.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+			buf.ReleaseMulti(mb)
+			return err
+		}
+		sleepBetween(noise.DelayMin, noise.DelayMax)
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
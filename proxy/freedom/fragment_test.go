@@ -0,0 +1,88 @@
+package freedom_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eagleql/xray-core/common"
+	. "github.com/eagleql/xray-core/proxy/freedom"
+)
+
+type recordingWriter struct {
+	writes [][]byte
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestFragmentTLSHello(t *testing.T) {
+	payload := make([]byte, 100)
+	payload[0] = 1 // ClientHello
+	hello := append([]byte{22, 3, 1, 0, byte(len(payload))}, payload...)
+
+	writer := &recordingWriter{}
+	fragmentWriter := NewFragmentWriter(writer, &Fragment{LengthMin: 10, LengthMax: 20, IntervalMin: 1, IntervalMax: 1})
+	common.Must2(fragmentWriter.Write(hello))
+	common.Must2(fragmentWriter.Write([]byte{23, 3, 3, 0, 0}))
+
+	if len(writer.writes) < 6 {
+		t.Error("expect at least 6 writes but got ", len(writer.writes))
+	}
+	var data []byte
+	for _, record := range writer.writes[:len(writer.writes)-1] {
+		if record[0] != 22 || record[1] != 3 || record[2] != 1 || int(record[3])<<8|int(record[4]) != len(record)-5 {
+			t.Error("expect a handshake record but got ", record[:5])
+		}
+		data = append(data, record[5:]...)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Error("expect ", payload, " but got ", data)
+	}
+	if last := writer.writes[len(writer.writes)-1]; len(last) != 5 || last[0] != 23 {
+		t.Error("expect the second write unchanged but got ", last)
+	}
+}
+
+func TestFragmentTLSHelloSplitWrites(t *testing.T) {
+	payload := make([]byte, 100)
+	payload[0] = 1 // ClientHello
+	hello := append([]byte{22, 3, 1, 0, byte(len(payload))}, payload...)
+
+	writer := &recordingWriter{}
+	fragmentWriter := NewFragmentWriter(writer, &Fragment{LengthMin: 10, LengthMax: 10})
+	for _, part := range [][]byte{hello[:3], hello[3:50], hello[50:]} {
+		if len(writer.writes) != 0 {
+			t.Fatal("expect the incomplete ClientHello to be held back")
+		}
+		common.Must2(fragmentWriter.Write(part))
+	}
+
+	// Without interval, the records are still written separately.
+	if len(writer.writes) != 10 {
+		t.Error("expect 10 writes but got ", len(writer.writes))
+	}
+	for _, record := range writer.writes {
+		if len(record) != 15 || record[0] != 22 || record[4] != 10 {
+			t.Error("expect a handshake record of 10 bytes but got ", record)
+		}
+	}
+}
+
+func TestFragmentPackets(t *testing.T) {
+	writer := &recordingWriter{}
+	fragmentWriter := NewFragmentWriter(writer, &Fragment{PacketsFrom: 2, PacketsTo: 2, LengthMin: 3, LengthMax: 3})
+	for i := 0; i < 3; i++ {
+		common.Must2(fragmentWriter.Write([]byte("0123456789")))
+	}
+
+	if len(writer.writes) != 6 {
+		t.Fatal("expect 6 writes but got ", len(writer.writes))
+	}
+	for i, expected := range []string{"0123456789", "012", "345", "678", "9", "0123456789"} {
+		if string(writer.writes[i]) != expected {
+			t.Error("expect ", expected, " but got ", string(writer.writes[i]))
+		}
+	}
+}
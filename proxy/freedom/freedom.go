@@ -6,6 +6,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/pires/go-proxyproto"
+
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/buf"
 	"github.com/eagleql/xray-core/common/dice"
@@ -155,11 +157,25 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	requestDone := func() error {
 		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
 
+		if h.config.ProxyProtocol > 0 && destination.Network == net.Network_TCP {
+			header := proxyProtocolHeader(byte(h.config.ProxyProtocol), session.InboundFromContext(ctx))
+			if _, err := header.WriteTo(conn); err != nil {
+				return newError("failed to write PROXY protocol v", h.config.ProxyProtocol, " header").Base(err)
+			}
+		}
+
 		var writer buf.Writer
 		if destination.Network == net.Network_TCP {
-			writer = buf.NewWriter(conn)
+			if h.config.Fragment != nil && isDirect(conn) {
+				writer = buf.NewWriter(NewFragmentWriter(conn, h.config.Fragment))
+			} else {
+				writer = buf.NewWriter(conn)
+			}
 		} else {
 			writer = NewPacketWriter(conn, h, ctx, UDPOverride)
+			if len(h.config.Noises) > 0 && isDirect(conn) {
+				writer = NewNoisePacketWriter(writer, h.config.Noises)
+			}
 		}
 
 		if err := buf.Copy(input, writer, buf.UpdateActivity(timer)); err != nil {
@@ -192,6 +208,34 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	return nil
 }
 
+// proxyProtocolHeader returns the PROXY protocol header carrying the addresses of the inbound connection.
+// The header has LOCAL command if the addresses are unknown.
+func proxyProtocolHeader(version byte, inbound *session.Inbound) *proxyproto.Header {
+	if inbound == nil || !inbound.Source.IsValid() || !inbound.Source.Address.Family().IsIP() {
+		return proxyproto.HeaderProxyFromAddrs(version, nil, nil)
+	}
+	source := &net.TCPAddr{IP: inbound.Source.Address.IP(), Port: int(inbound.Source.Port)}
+	var destination *net.TCPAddr
+	if inbound.Conn != nil {
+		destination, _ = inbound.Conn.LocalAddr().(*net.TCPAddr)
+	}
+	if destination == nil && inbound.Gateway.IsValid() && inbound.Gateway.Address.Family().IsIP() {
+		destination = &net.TCPAddr{IP: inbound.Gateway.Address.IP(), Port: int(inbound.Gateway.Port)}
+	}
+	if destination == nil {
+		return proxyproto.HeaderProxyFromAddrs(version, nil, nil)
+	}
+
+	sourceIP4, destinationIP4 := source.IP.To4(), destination.IP.To4()
+	switch {
+	case sourceIP4 != nil && destinationIP4 != nil:
+		source.IP, destination = sourceIP4, &net.TCPAddr{IP: destinationIP4, Port: destination.Port}
+	case sourceIP4 != nil || destinationIP4 != nil:
+		return proxyproto.HeaderProxyFromAddrs(version, nil, nil)
+	}
+	return proxyproto.HeaderProxyFromAddrs(version, source, destination)
+}
+
 func NewPacketReader(conn net.Conn, UDPOverride net.Destination) buf.Reader {
 	iConn := conn
 	statConn, ok := iConn.(*internet.StatCouterConnection)
@@ -6,8 +6,11 @@ import (
 	"encoding/base64"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/http2"
 
@@ -27,9 +30,16 @@ import (
 	"github.com/eagleql/xray-core/transport/internet/tls"
 )
 
+// refusalTimeout is how long a response refusing a tunnel over HTTP/2 may take to be returned once it arrives.
+const refusalTimeout = time.Second
+
 type Client struct {
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
+	header        []*Header
+
+	h2Access sync.Mutex
+	h2Conns  map[net.Destination]h2Conn
 }
 
 type h2Conn struct {
@@ -37,11 +47,6 @@ type h2Conn struct {
 	h2Conn  *http2.ClientConn
 }
 
-var (
-	cachedH2Mutex sync.Mutex
-	cachedH2Conns map[net.Destination]h2Conn
-)
-
 // NewClient create a new http client based on the given config.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	serverList := protocol.NewServerList()
@@ -60,9 +65,23 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	return &Client{
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		header:        config.Header,
+		h2Conns:       make(map[net.Destination]h2Conn),
 	}, nil
 }
 
+// Close implements common.Closable. It closes the HTTP/2 connections shared by tunnels.
+func (c *Client) Close() error {
+	c.h2Access.Lock()
+	defer c.h2Access.Unlock()
+
+	for dest, conn := range c.h2Conns {
+		conn.rawConn.Close()
+		delete(c.h2Conns, dest)
+	}
+	return nil
+}
+
 // Process implements proxy.Outbound.Process. We first create a socket tunnel via HTTP CONNECT method, then redirect all inbound traffic to that tunnel.
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbound := session.OutboundFromContext(ctx)
@@ -93,7 +112,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		dest := server.Destination()
 		user = server.PickUser()
 
-		netConn, err := setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, firstPayload)
+		netConn, err := c.setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, firstPayload)
 		if netConn != nil {
 			if _, ok := netConn.(*http2Conn); !ok {
 				if _, err := netConn.Write(firstPayload); err != nil {
@@ -139,8 +158,8 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	return nil
 }
 
-// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method
-func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte) (net.Conn, error) {
+// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method. Tunnels to the same server over HTTP/2 share one connection.
+func (c *Client) setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte) (net.Conn, error) {
 	req := &http.Request{
 		Method: http.MethodConnect,
 		URL:    &url.URL{Host: target},
@@ -154,6 +173,10 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
 	}
 
+	for _, h := range c.header {
+		req.Header.Set(h.Key, h.Value)
+	}
+
 	connectHTTP1 := func(rawConn net.Conn) (net.Conn, error) {
 		req.Header.Set("Proxy-Connection", "Keep-Alive")
 
@@ -177,10 +200,40 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 		return rawConn, nil
 	}
 
-	connectHTTP2 := func(rawConn net.Conn, h2clientConn *http2.ClientConn) (net.Conn, error) {
+	// connectHTTP2 opens a stream on the shared connection. Errors of the stream leave the connection to other streams,
+	// and connFailed tells whether the connection itself failed.
+	connectHTTP2 := func(rawConn net.Conn, h2clientConn *http2.ClientConn) (proxyConn net.Conn, connFailed bool, err error) {
 		pr, pw := io.Pipe()
 		req.Body = pr
 
+		// The HTTP/2 transport returns a response refusing the tunnel only after the request body ends, which it
+		// never does by itself. A 200 response is returned right after it arrives, so a response that isn't must be
+		// refusing the tunnel, and ending the body lets the transport return it.
+		responded := make(chan struct{})
+		var respondedOnce sync.Once
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				respondedOnce.Do(func() { close(responded) })
+			},
+		}))
+		roundTripped := make(chan struct{})
+		var bodyAborted int32
+		go func() {
+			select {
+			case <-responded:
+			case <-roundTripped:
+				return
+			}
+			timer := time.NewTimer(refusalTimeout)
+			defer timer.Stop()
+			select {
+			case <-roundTripped:
+			case <-timer.C:
+				atomic.StoreInt32(&bodyAborted, 1)
+				pr.CloseWithError(newError("response to CONNECT is not returned in time"))
+			}
+		}()
+
 		var pErr error
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -191,38 +244,43 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 		}()
 
 		resp, err := h2clientConn.RoundTrip(req)
+		close(roundTripped)
 		if err != nil {
-			rawConn.Close()
-			return nil, err
+			pw.Close()
+			wg.Wait()
+			return nil, true, err
 		}
 
-		wg.Wait()
-		if pErr != nil {
-			rawConn.Close()
-			return nil, pErr
+		if resp.StatusCode != http.StatusOK {
+			pw.Close()
+			wg.Wait()
+			resp.Body.Close()
+			return nil, !h2clientConn.CanTakeNewRequest(), newError("Proxy responded with non 200 code: " + resp.Status)
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			rawConn.Close()
-			return nil, newError("Proxy responded with non 200 code: " + resp.Status)
+		wg.Wait()
+		if pErr == nil && atomic.LoadInt32(&bodyAborted) == 1 {
+			pErr = newError("request body of the tunnel is aborted")
 		}
-		return newHTTP2Conn(rawConn, pw, resp.Body), nil
+		if pErr != nil {
+			pw.Close()
+			resp.Body.Close()
+			return nil, !h2clientConn.CanTakeNewRequest(), pErr
+		}
+		return newHTTP2Conn(rawConn, pw, resp.Body), false, nil
 	}
 
-	cachedH2Mutex.Lock()
-	cachedConn, cachedConnFound := cachedH2Conns[dest]
-	cachedH2Mutex.Unlock()
+	c.h2Access.Lock()
+	cachedConn, cachedConnFound := c.h2Conns[dest]
+	if cachedConnFound && !cachedConn.h2Conn.CanTakeNewRequest() {
+		delete(c.h2Conns, dest)
+		cachedConnFound = false
+	}
+	c.h2Access.Unlock()
 
 	if cachedConnFound {
-		rc, cc := cachedConn.rawConn, cachedConn.h2Conn
-		if cc.CanTakeNewRequest() {
-			proxyConn, err := connectHTTP2(rc, cc)
-			if err != nil {
-				return nil, err
-			}
-
-			return proxyConn, nil
-		}
+		proxyConn, _, err := connectHTTP2(cachedConn.rawConn, cachedConn.h2Conn)
+		return proxyConn, err
 	}
 
 	rawConn, err := dialer.Dial(ctx, dest)
@@ -255,25 +313,38 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			return nil, err
 		}
 
-		proxyConn, err := connectHTTP2(rawConn, h2clientConn)
-		if err != nil {
+		c.h2Access.Lock()
+		if cachedConn, found := c.h2Conns[dest]; found && cachedConn.h2Conn.CanTakeNewRequest() {
+			// Another tunnel to dest has set up its connection meanwhile, which is shared instead of this one.
+			c.h2Access.Unlock()
+			h2clientConn.Close()
 			rawConn.Close()
-			return nil, err
+			proxyConn, _, err := connectHTTP2(cachedConn.rawConn, cachedConn.h2Conn)
+			return proxyConn, err
 		}
-
-		cachedH2Mutex.Lock()
-		if cachedH2Conns == nil {
-			cachedH2Conns = make(map[net.Destination]h2Conn)
-		}
-
-		cachedH2Conns[dest] = h2Conn{
+		c.h2Conns[dest] = h2Conn{
 			rawConn: rawConn,
 			h2Conn:  h2clientConn,
 		}
-		cachedH2Mutex.Unlock()
+		c.h2Access.Unlock()
 
-		return proxyConn, err
+		proxyConn, connFailed, err := connectHTTP2(rawConn, h2clientConn)
+		if err != nil && !connFailed {
+			// The proxy refusing the tunnel leaves the connection to other tunnels, which may have taken it already.
+			return nil, err
+		}
+		if err != nil {
+			c.h2Access.Lock()
+			if cachedConn, found := c.h2Conns[dest]; found && cachedConn.h2Conn == h2clientConn {
+				delete(c.h2Conns, dest)
+			}
+			c.h2Access.Unlock()
+			rawConn.Close()
+			return nil, err
+		}
+		return proxyConn, nil
 	default:
+		rawConn.Close()
 		return nil, newError("negotiated unsupported application layer protocol: " + nextProto)
 	}
 }
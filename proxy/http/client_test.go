@@ -0,0 +1,287 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	gotls "crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/transport/internet"
+	"github.com/eagleql/xray-core/transport/internet/tls"
+)
+
+type testDialer struct {
+	tlsConfig *gotls.Config
+	dials     int32
+}
+
+func (d *testDialer) Dial(ctx context.Context, dest net.Destination) (internet.Connection, error) {
+	atomic.AddInt32(&d.dials, 1)
+	conn, err := net.Dial("tcp", dest.NetAddr())
+	if err != nil {
+		return nil, err
+	}
+	if d.tlsConfig == nil {
+		return conn, nil
+	}
+	return tls.Client(conn, d.tlsConfig), nil
+}
+
+func (d *testDialer) Address() net.Address {
+	return nil
+}
+
+func TestHTTP2Tunnel(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("X-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		b := make([]byte, 1024)
+		for {
+			n, err := r.Body.Read(b)
+			if n > 0 {
+				w.Write([]byte(r.Host + ":"))
+				w.Write(b[:n])
+				w.(http.Flusher).Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	dest, err := net.ParseDestination("tcp:" + server.Listener.Addr().String())
+	common.Must(err)
+	dialer := &testDialer{
+		tlsConfig: &gotls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"h2", "http/1.1"},
+		},
+	}
+	client := &Client{
+		header:  []*Header{{Key: "X-Token", Value: "token"}},
+		h2Conns: make(map[net.Destination]h2Conn),
+	}
+	defer client.Close()
+
+	for _, target := range []string{"example.com:443", "example.org:443"} {
+		conn, err := client.setUpHTTPTunnel(context.Background(), dest, target, nil, dialer, []byte("hello"))
+		common.Must(err)
+		expected := target + ":hello"
+		b := make([]byte, len(expected))
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != expected {
+			t.Error("expect ", expected, " but got ", string(b))
+		}
+		conn.Close()
+	}
+
+	if dials := atomic.LoadInt32(&dialer.dials); dials != 1 {
+		t.Error("expect tunnels to share 1 connection, but dialed ", dials)
+	}
+}
+
+func TestHTTP2TunnelConcurrentDials(t *testing.T) {
+	var open int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		b := make([]byte, 1024)
+		for {
+			n, err := r.Body.Read(b)
+			if n > 0 {
+				w.Write(b[:n])
+				w.(http.Flusher).Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt32(&open, 1)
+		case http.StateClosed, http.StateHijacked:
+			atomic.AddInt32(&open, -1)
+		}
+	}
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	dest, err := net.ParseDestination("tcp:" + server.Listener.Addr().String())
+	common.Must(err)
+	dialer := &testDialer{
+		tlsConfig: &gotls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"h2", "http/1.1"},
+		},
+	}
+	client := &Client{
+		h2Conns: make(map[net.Destination]h2Conn),
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := client.setUpHTTPTunnel(context.Background(), dest, "example.com:443", nil, dialer, []byte("hello"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b := make([]byte, len("hello"))
+			if _, err := io.ReadFull(conn, b); err != nil {
+				t.Error(err)
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 50 && atomic.LoadInt32(&open) != 1; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&open); n != 1 {
+		t.Error("expect 1 connection to be kept, but got ", n)
+	}
+}
+
+func TestHTTP2TunnelRefused(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "refused.example.com:443" {
+			<-release
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		b := make([]byte, 1024)
+		for {
+			n, err := r.Body.Read(b)
+			if n > 0 {
+				w.Write(b[:n])
+				w.(http.Flusher).Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	dest, err := net.ParseDestination("tcp:" + server.Listener.Addr().String())
+	common.Must(err)
+	dialer := &testDialer{
+		tlsConfig: &gotls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"h2", "http/1.1"},
+		},
+	}
+	client := &Client{
+		h2Conns: make(map[net.Destination]h2Conn),
+	}
+	defer client.Close()
+
+	// The refused tunnel sets up the connection, which another tunnel takes before the proxy refuses the first one.
+	refused := make(chan error, 1)
+	go func() {
+		_, err := client.setUpHTTPTunnel(context.Background(), dest, "refused.example.com:443", nil, dialer, nil)
+		refused <- err
+	}()
+	for i := 0; i < 50; i++ {
+		client.h2Access.Lock()
+		_, found := client.h2Conns[dest]
+		client.h2Access.Unlock()
+		if found {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	conn, err := client.setUpHTTPTunnel(context.Background(), dest, "example.com:443", nil, dialer, []byte("hello"))
+	common.Must(err)
+	defer conn.Close()
+	b := make([]byte, len("hello"))
+	common.Must2(io.ReadFull(conn, b))
+
+	close(release)
+	if err := <-refused; err == nil {
+		t.Fatal("expect the tunnel to be refused")
+	}
+
+	common.Must2(conn.Write([]byte("world")))
+	if _, err := io.ReadFull(conn, b); err != nil || string(b) != "world" {
+		t.Error("expect the other tunnel to stay open, but got ", string(b), " ", err)
+	}
+	if dials := atomic.LoadInt32(&dialer.dials); dials != 1 {
+		t.Error("expect tunnels to share 1 connection, but dialed ", dials)
+	}
+}
+
+func TestHTTP1TunnelHeader(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				req, err := http.ReadRequest(reader)
+				if err != nil {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != "Bearer token" {
+					conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					return
+				}
+				conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				io.Copy(conn, reader)
+			}()
+		}
+	}()
+
+	dest := net.DestinationFromAddr(listener.Addr())
+	user := &protocol.MemoryUser{Account: &Account{Username: "user", Password: "pass"}}
+	client := &Client{h2Conns: make(map[net.Destination]h2Conn)}
+	if _, err := client.setUpHTTPTunnel(context.Background(), dest, "example.com:443", user, new(testDialer), nil); err == nil {
+		t.Error("expect tunnel without token to be rejected")
+	}
+
+	client.header = []*Header{{Key: "Proxy-Authorization", Value: "Bearer token"}}
+	conn, err := client.setUpHTTPTunnel(context.Background(), dest, "example.com:443", user, new(testDialer), nil)
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write([]byte("hello")))
+	b := make([]byte, 5)
+	common.Must2(io.ReadFull(conn, b))
+	if string(b) != "hello" {
+		t.Error("expect hello but got ", string(b))
+	}
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"github.com/golang/protobuf/proto"
+
 	"github.com/eagleql/xray-core/common/protocol"
 )
 
@@ -11,18 +13,11 @@ func (a *Account) Equals(another protocol.Account) bool {
 	return false
 }
 
-func (a *Account) AsAccount() (protocol.Account, error) {
-	return a, nil
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *Account) ToProto() proto.Message {
+	return a
 }
 
-func (sc *ServerConfig) HasAccount(username, password string) bool {
-	if sc.Accounts == nil {
-		return false
-	}
-
-	p, found := sc.Accounts[username]
-	if !found {
-		return false
-	}
-	return p == password
+func (a *Account) AsAccount() (protocol.Account, error) {
+	return a, nil
 }
@@ -22,35 +22,111 @@ import (
 	"github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
 	"github.com/eagleql/xray-core/transport/internet"
 )
 
 // Server is an HTTP proxy server.
 type Server struct {
 	config        *ServerConfig
+	validator     *proxy.PasswordValidator
+	sessions      *proxy.UserSessions
 	policyManager policy.Manager
+	statsManager  stats.Manager
+	// authRequired is true if the server is configured with users or required to authenticate, so that removing all the
+	// users doesn't open the proxy.
+	authRequired bool
 }
 
 // NewServer creates a new HTTP inbound handler.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	return NewServerWithAuth(ctx, config, false)
+}
+
+// NewServerWithAuth creates a new HTTP inbound handler, which requires authentication if authRequired, even if it has
+// no users.
+func NewServerWithAuth(ctx context.Context, config *ServerConfig, authRequired bool) (*Server, error) {
+	validator := new(proxy.PasswordValidator)
+	for username, password := range config.Accounts {
+		u := &protocol.MemoryUser{
+			Email:   username,
+			Level:   config.UserLevel,
+			Account: &Account{Username: username, Password: password},
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, newError("failed to add account").Base(err).AtError()
+		}
+	}
+	for _, user := range config.Users {
+		u, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, newError("failed to get HTTP user").Base(err).AtError()
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, newError("failed to add user").Base(err).AtError()
+		}
+	}
+
 	v := core.MustFromContext(ctx)
 	s := &Server{
 		config:        config,
+		validator:     validator,
+		sessions:      proxy.NewUserSessions(),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:  v.GetFeature(stats.ManagerType()).(stats.Manager),
+		authRequired:  authRequired || validator.Count() > 0,
 	}
 
 	return s, nil
 }
 
-func (s *Server) policy() policy.Session {
+func (s *Server) policy(level uint32) policy.Session {
 	config := s.config
-	p := s.policyManager.ForLevel(config.UserLevel)
-	if config.Timeout > 0 && config.UserLevel == 0 {
+	p := s.policyManager.ForLevel(level)
+	if config.Timeout > 0 && level == 0 {
 		p.Timeouts.ConnectionIdle = time.Duration(config.Timeout) * time.Second
 	}
 	return p
 }
 
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	if _, ok := u.Account.(*Account); !ok {
+		return newError("account of user ", u.Email, " is not an HTTP account")
+	}
+	return s.validator.Add(u)
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	if err := s.validator.Del(e); err != nil {
+		return err
+	}
+	s.sessions.CloseUser(e)
+	return nil
+}
+
+// KickUser implements proxy.UserKicker.KickUser().
+func (s *Server) KickUser(ctx context.Context, e string) int {
+	return s.sessions.CloseUser(e)
+}
+
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (s *Server) GetUserByEmail(ctx context.Context, e string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(e)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (s *Server) GetUsersCount(ctx context.Context) int64 {
+	return int64(s.validator.Count())
+}
+
 // Network implements proxy.Inbound.
 func (*Server) Network() []net.Network {
 	return []net.Network{net.Network_TCP, net.Network_UNIX}
@@ -90,10 +166,18 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 		}
 	}
 
+	// Requests on a kept-alive connection may come from different users, each of which must be able to kick it.
+	removeSessions := make(map[string]func())
+	defer func() {
+		for _, remove := range removeSessions {
+			remove()
+		}
+	}()
+
 	reader := bufio.NewReaderSize(readerOnly{conn}, buf.Size)
 
 Start:
-	if err := conn.SetReadDeadline(time.Now().Add(s.policy().Timeouts.Handshake)); err != nil {
+	if err := conn.SetReadDeadline(time.Now().Add(s.policy(s.config.UserLevel).Timeouts.Handshake)); err != nil {
 		newError("failed to set read deadline").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
 
@@ -106,13 +190,30 @@ Start:
 		return trace
 	}
 
-	if len(s.config.Accounts) > 0 {
-		user, pass, ok := parseBasicAuth(request.Header.Get("Proxy-Authorization"))
-		if !ok || !s.config.HasAccount(user, pass) {
+	if s.authRequired || s.validator.Count() > 0 {
+		var user *protocol.MemoryUser
+		if username, password, ok := parseBasicAuth(request.Header.Get("Proxy-Authorization")); ok {
+			user = s.validator.Get(username, password)
+		}
+		if user == nil {
 			return common.Error2(conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"proxy\"\r\n\r\n")))
 		}
+		if err := proxy.CheckUser(s.statsManager, user); err != nil {
+			log.Record(&log.AccessMessage{
+				From:   conn.RemoteAddr(),
+				To:     "",
+				Status: log.AccessRejected,
+				Reason: err,
+				Email:  user.Email,
+			})
+			conn.Write([]byte("HTTP/1.1 403 Forbidden\r\n\r\n"))
+			return newError("user rejected from ", conn.RemoteAddr()).Base(err).AtInfo()
+		}
 		if inbound != nil {
-			inbound.User.Email = user
+			inbound.User = user
+		}
+		if _, found := removeSessions[user.Email]; !found {
+			removeSessions[user.Email] = s.sessions.Add(user.Email, conn)
 		}
 	}
 
@@ -163,7 +264,10 @@ func (s *Server) handleConnect(ctx context.Context, _ *http.Request, reader *buf
 		return newError("failed to write back OK response").Base(err)
 	}
 
-	plcy := s.policy()
+	plcy := s.policy(s.config.UserLevel)
+	if inbound != nil && inbound.User != nil {
+		plcy = s.policy(inbound.User.Level)
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
 
@@ -0,0 +1,98 @@
+// Package loopback is an outbound handler that dispatches the connections again, as if they come from another inbound.
+package loopback
+
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
+
+import (
+	"context"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/transport"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+// Loopback is an outbound handler that hands the connections back to the dispatcher.
+type Loopback struct {
+	config     *Config
+	dispatcher routing.Dispatcher
+}
+
+// New creates a new loopback handler.
+func New(ctx context.Context, config *Config) (*Loopback, error) {
+	l := &Loopback{
+		config: config,
+	}
+	if err := core.RequireFeatures(ctx, func(d routing.Dispatcher) {
+		l.dispatcher = d
+	}); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Process implements proxy.Outbound.
+func (l *Loopback) Process(ctx context.Context, link *transport.Link, _ internet.Dialer) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("target not specified.")
+	}
+	destination := outbound.Target
+
+	newError("looping back to inbound ", l.config.InboundTag, " for ", destination).WriteToLog(session.ExportIDToError(ctx))
+
+	inbound := new(session.Inbound)
+	if originInbound := session.InboundFromContext(ctx); originInbound != nil {
+		*inbound = *originInbound
+	}
+	inbound.Tag = l.config.InboundTag
+	ctx = session.ContextWithInbound(ctx, inbound)
+	ctx = session.ContextWithLoopback(ctx)
+
+	content := new(session.Content)
+	if originContent := session.ContentFromContext(ctx); originContent != nil {
+		*content = *originContent
+		content.Attributes = make(map[string]string, len(originContent.Attributes))
+		for k, v := range originContent.Attributes {
+			content.Attributes[k] = v
+		}
+	}
+	ctx = session.ContextWithContent(ctx, content)
+
+	loopLink, err := l.dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		return newError("failed to dispatch to inbound ", l.config.InboundTag).Base(err)
+	}
+
+	requestDone := func() error {
+		if err := buf.Copy(link.Reader, loopLink.Writer); err != nil {
+			return newError("failed to process request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		if err := buf.Copy(loopLink.Reader, link.Writer); err != nil {
+			return newError("failed to process response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(loopLink.Writer)), task.OnSuccess(responseDone, task.Close(link.Writer))); err != nil {
+		common.Interrupt(loopLink.Reader)
+		common.Interrupt(loopLink.Writer)
+		return newError("connection ends").Base(err)
+	}
+
+	return nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
@@ -1,6 +1,8 @@
 package mtproto
 
 import (
+	"github.com/golang/protobuf/proto"
+
 	"github.com/eagleql/xray-core/common/protocol"
 )
 
@@ -22,3 +24,8 @@ func (a *Account) Equals(another protocol.Account) bool {
 
 	return true
 }
+
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *Account) ToProto() proto.Message {
+	return a
+}
@@ -0,0 +1,288 @@
+package mtproto
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/dice"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+const (
+	recordTypeChangeCipherSpec = 0x14
+	recordTypeHandshake        = 0x16
+	recordTypeApplicationData  = 0x17
+
+	recordHeaderSize = 5
+	maxRecordPayload = 16384
+
+	typeClientHello = 1
+	typeServerHello = 2
+
+	extensionServerName       = 0
+	extensionKeyShare         = 51
+	extensionSupportedVersion = 43
+
+	// helloRandomOffset is the offset of the random in both ClientHello and ServerHello records.
+	helloRandomOffset = recordHeaderSize + 4 + 2
+
+	// fakeTLSTimeSkew is the maximum difference between the time in the ClientHello and the server time.
+	fakeTLSTimeSkew = 2 * time.Minute
+)
+
+// readClientHello reads the TLS record of the ClientHello. It returns all the bytes read, even on error.
+func readClientHello(reader io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if n, err := io.ReadFull(reader, header); err != nil {
+		return header[:n], err
+	}
+	if header[0] != recordTypeHandshake || header[1] != 3 {
+		return header, newError("not a TLS handshake record")
+	}
+	length := int(binary.BigEndian.Uint16(header[3:]))
+	if length > maxRecordPayload {
+		return header, newError("TLS record too large: ", length)
+	}
+	record := make([]byte, recordHeaderSize+length)
+	copy(record, header)
+	n, err := io.ReadFull(reader, record[recordHeaderSize:])
+	return record[:recordHeaderSize+n], err
+}
+
+// verifyClientHello checks the HMAC of the secret embedded in the random of the ClientHello record, as well as the
+// server name. It returns the random and the session ID of the ClientHello.
+func verifyClientHello(record []byte, secret []byte, domain string, now time.Time) ([]byte, []byte, error) {
+	s := cryptobyte.String(record[recordHeaderSize:])
+	var messageType uint8
+	var random []byte
+	var body, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&messageType) || messageType != typeClientHello || !s.ReadUint24LengthPrefixed(&body) ||
+		!body.Skip(2) || !body.ReadBytes(&random, 32) ||
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) || !body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, nil, newError("invalid ClientHello")
+	}
+
+	var serverName string
+	for !extensions.Empty() {
+		var extension uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&extension) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, nil, newError("invalid ClientHello extensions")
+		}
+		if extension != extensionServerName {
+			continue
+		}
+		var names cryptobyte.String
+		if !data.ReadUint16LengthPrefixed(&names) {
+			return nil, nil, newError("invalid server name extension")
+		}
+		for !names.Empty() {
+			var nameType uint8
+			var name cryptobyte.String
+			if !names.ReadUint8(&nameType) || !names.ReadUint16LengthPrefixed(&name) {
+				return nil, nil, newError("invalid server name extension")
+			}
+			if nameType == 0 {
+				serverName = string(name)
+			}
+		}
+	}
+	if !strings.EqualFold(serverName, domain) {
+		return nil, nil, newError("unexpected server name: ", serverName)
+	}
+
+	zeroed := make([]byte, len(record))
+	copy(zeroed, record)
+	copy(zeroed[helloRandomOffset:helloRandomOffset+32], make([]byte, 32))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(zeroed)
+	digest := mac.Sum(nil)
+	for i := 0; i < 28; i++ {
+		if digest[i] != random[i] {
+			return nil, nil, newError("invalid ClientHello digest")
+		}
+	}
+	var timestamp [4]byte
+	for i := range timestamp {
+		timestamp[i] = digest[28+i] ^ random[28+i]
+	}
+	if diff := now.Sub(time.Unix(int64(binary.LittleEndian.Uint32(timestamp[:])), 0)); diff > fakeTLSTimeSkew || diff < -fakeTLSTimeSkew {
+		return nil, nil, newError("invalid ClientHello time, off by ", diff)
+	}
+	return random, []byte(sessionID), nil
+}
+
+// serverHello builds the response to a verified ClientHello, with the HMAC of the secret as the random of the
+// ServerHello.
+func serverHello(secret, clientRandom, sessionID []byte) []byte {
+	privateKey := make([]byte, curve25519.ScalarSize)
+	common.Must2(rand.Read(privateKey))
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	common.Must(err)
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(0x0303)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(typeServerHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(sessionID)
+			})
+			b.AddUint16(0x1301) // TLS_AES_128_GCM_SHA256
+			b.AddUint8(0)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(extensionKeyShare)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16(0x001d) // X25519
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(publicKey)
+					})
+				})
+				b.AddUint16(extensionSupportedVersion)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16(0x0304)
+				})
+			})
+		})
+	})
+	b.AddBytes([]byte{recordTypeChangeCipherSpec, 0x03, 0x03, 0x00, 0x01, 0x01})
+	encrypted := make([]byte, 1024+dice.Roll(3072))
+	common.Must2(rand.Read(encrypted))
+	b.AddUint8(recordTypeApplicationData)
+	b.AddUint16(0x0303)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(encrypted)
+	})
+	response := b.BytesOrPanic()
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(clientRandom)
+	mac.Write(response)
+	copy(response[helloRandomOffset:], mac.Sum(nil))
+	return response
+}
+
+// fakeTLSConn is a connection that carries data in TLS application data records.
+type fakeTLSConn struct {
+	internet.Connection
+	buffer   [maxRecordPayload]byte
+	leftover []byte
+}
+
+// Read implements io.Reader. ChangeCipherSpec records are skipped.
+func (c *fakeTLSConn) Read(b []byte) (int, error) {
+	for len(c.leftover) == 0 {
+		var header [recordHeaderSize]byte
+		if _, err := io.ReadFull(c.Connection, header[:]); err != nil {
+			return 0, err
+		}
+		length := int(binary.BigEndian.Uint16(header[3:]))
+		if length > maxRecordPayload {
+			return 0, newError("TLS record too large: ", length)
+		}
+		if _, err := io.ReadFull(c.Connection, c.buffer[:length]); err != nil {
+			return 0, err
+		}
+		switch header[0] {
+		case recordTypeChangeCipherSpec:
+		case recordTypeApplicationData:
+			c.leftover = c.buffer[:length]
+		default:
+			return 0, newError("unexpected TLS record type: ", header[0])
+		}
+	}
+	n := copy(b, c.leftover)
+	c.leftover = c.leftover[n:]
+	return n, nil
+}
+
+// Write implements io.Writer.
+func (c *fakeTLSConn) Write(b []byte) (int, error) {
+	record := make([]byte, 0, recordHeaderSize+maxRecordPayload)
+	for written := 0; written < len(b); {
+		n := len(b) - written
+		if n > maxRecordPayload {
+			n = maxRecordPayload
+		}
+		record = append(record[:0], recordTypeApplicationData, 0x03, 0x03, byte(n>>8), byte(n))
+		record = append(record, b[written:written+n]...)
+		if _, err := c.Connection.Write(record); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(b), nil
+}
+
+// fakeTLSHandshake performs the server side of the fake TLS handshake. Connections failing the handshake are
+// forwarded to the fallback, in which case an error is returned once the forwarding ends.
+func (s *Server) fakeTLSHandshake(ctx context.Context, conn internet.Connection) (internet.Connection, error) {
+	record, err := readClientHello(conn)
+	var random, sessionID []byte
+	if err == nil {
+		random, sessionID, err = verifyClientHello(record, s.account.Secret, s.account.FakeTlsDomain, time.Now())
+	}
+	if err == nil && !s.replayFilter.Check(random) {
+		err = newError("replayed ClientHello")
+	}
+	if err != nil {
+		conn.SetDeadline(time.Time{})
+		if ferr := s.forward(ctx, conn, record); ferr != nil {
+			return nil, newError("failed to forward to ", s.fallback).Base(ferr)
+		}
+		return nil, newError("fake TLS handshake failed, forwarded to ", s.fallback).Base(err)
+	}
+
+	if _, err := conn.Write(serverHello(s.account.Secret, random, sessionID)); err != nil {
+		return nil, newError("failed to write ServerHello").Base(err)
+	}
+	return &fakeTLSConn{Connection: conn}, nil
+}
+
+// forward copies the connection to and from the fallback, starting with the bytes already read. It ends once the
+// connection is idle for the idle timeout of the user.
+func (s *Server) forward(ctx context.Context, conn internet.Connection, read []byte) error {
+	sPolicy := s.policy.ForLevel(s.user.Level)
+	dialCtx, cancelDial := context.WithTimeout(ctx, sPolicy.Timeouts.Handshake)
+	target, err := internet.DialSystem(dialCtx, s.fallback, nil)
+	cancelDial()
+	if err != nil {
+		return newError("failed to dial ", s.fallback).Base(err)
+	}
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := signal.CancelAfterInactivity(ctx, cancel, sPolicy.Timeouts.ConnectionIdle)
+	request := func() error {
+		if _, err := target.Write(read); err != nil {
+			return err
+		}
+		return buf.Copy(buf.NewReader(conn), buf.NewWriter(target), buf.UpdateActivity(timer))
+	}
+	response := func() error {
+		return buf.Copy(buf.NewReader(target), buf.NewWriter(conn), buf.UpdateActivity(timer))
+	}
+	// Either side closing ends the forwarding, and the copying is interrupted by closing the connections.
+	task.Run(ctx, task.OnSuccess(request, task.Close(target)), task.OnSuccess(response, task.Close(conn)))
+	conn.Close()
+	return nil
+}
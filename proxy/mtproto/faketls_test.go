@@ -0,0 +1,178 @@
+package mtproto
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/eagleql/xray-core/common"
+	v2net "github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/features/policy"
+)
+
+func fakeTLSClientHello(secret []byte, serverName string, timestamp time.Time) []byte {
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(recordTypeHandshake)
+	b.AddUint16(0x0301)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(typeClientHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(bytes.Repeat([]byte{0x42}, 32))
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(0x1301)
+			})
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8(0)
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(extensionServerName)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8(0)
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+							b.AddBytes([]byte(serverName))
+						})
+					})
+				})
+			})
+		})
+	})
+	record := b.BytesOrPanic()
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(record)
+	random := mac.Sum(nil)
+	var ts [4]byte
+	binary.LittleEndian.PutUint32(ts[:], uint32(timestamp.Unix()))
+	for i := range ts {
+		random[28+i] ^= ts[i]
+	}
+	copy(record[helloRandomOffset:], random)
+	return record
+}
+
+func TestFakeTLSClientHello(t *testing.T) {
+	secret := make([]byte, 16)
+	common.Must2(rand.Read(secret))
+	now := time.Now()
+
+	record := fakeTLSClientHello(secret, "www.example.com", now)
+	random, sessionID, err := verifyClientHello(record, secret, "www.example.com", now)
+	common.Must(err)
+	if !bytes.Equal(random, record[helloRandomOffset:helloRandomOffset+32]) {
+		t.Error("expect random ", record[helloRandomOffset:helloRandomOffset+32], " but got ", random)
+	}
+	if !bytes.Equal(sessionID, bytes.Repeat([]byte{0x42}, 32)) {
+		t.Error("unexpected session ID ", sessionID)
+	}
+
+	response := serverHello(secret, random, sessionID)
+	serverRandom := make([]byte, 32)
+	copy(serverRandom, response[helloRandomOffset:])
+	copy(response[helloRandomOffset:helloRandomOffset+32], make([]byte, 32))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(random)
+	mac.Write(response)
+	if expected := mac.Sum(nil); !bytes.Equal(serverRandom, expected) {
+		t.Error("expect server random ", expected, " but got ", serverRandom)
+	}
+
+	wrongSecret := make([]byte, 16)
+	for _, test := range []struct {
+		record []byte
+		secret []byte
+		domain string
+	}{
+		{record, wrongSecret, "www.example.com"},
+		{record, secret, "www.example.org"},
+		{fakeTLSClientHello(secret, "www.example.com", now.Add(-time.Hour)), secret, "www.example.com"},
+	} {
+		if _, _, err := verifyClientHello(test.record, test.secret, test.domain, now); err == nil {
+			t.Error("expect ClientHello to be rejected for ", test.domain)
+		}
+	}
+}
+
+func TestFakeTLSConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	data := make([]byte, maxRecordPayload*2+100)
+	common.Must2(rand.Read(data))
+	go func() {
+		client.Write([]byte{recordTypeChangeCipherSpec, 0x03, 0x03, 0x00, 0x01, 0x01})
+		(&fakeTLSConn{Connection: client}).Write(data)
+	}()
+
+	actual := make([]byte, len(data))
+	common.Must2(io.ReadFull(&fakeTLSConn{Connection: server}, actual))
+	if !bytes.Equal(actual, data) {
+		t.Error("expect data to be read as written")
+	}
+}
+
+// idlePolicy times out idle connections quickly.
+type idlePolicy struct {
+	policy.DefaultManager
+}
+
+func (m idlePolicy) ForLevel(level uint32) policy.Session {
+	p := m.DefaultManager.ForLevel(level)
+	p.Timeouts.ConnectionIdle = 100 * time.Millisecond
+	return p
+}
+
+func TestFakeTLSForwardIdle(t *testing.T) {
+	fallback, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer fallback.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := fallback.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b := make([]byte, 5)
+		io.ReadFull(conn, b)
+		received <- b
+		io.Copy(io.Discard, conn)
+	}()
+
+	s := &Server{
+		user:     &protocol.User{},
+		policy:   idlePolicy{},
+		fallback: v2net.DestinationFromAddr(fallback.Addr()),
+	}
+	client, conn := net.Pipe()
+	defer client.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.forward(context.Background(), conn, []byte("hello"))
+	}()
+
+	if b := <-received; string(b) != "hello" {
+		t.Error("expect the data read to be forwarded, but got ", b)
+	}
+	select {
+	case err := <-done:
+		common.Must(err)
+	case <-time.After(5 * time.Second):
+		t.Error("expect idle forwarding to end")
+	}
+}
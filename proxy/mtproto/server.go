@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/antireplay"
 	"github.com/eagleql/xray-core/common/buf"
 	"github.com/eagleql/xray-core/common/crypto"
 	"github.com/eagleql/xray-core/common/net"
@@ -30,9 +31,11 @@ var (
 )
 
 type Server struct {
-	user    *protocol.User
-	account *Account
-	policy  policy.Manager
+	user         *protocol.User
+	account      *Account
+	policy       policy.Manager
+	fallback     net.Destination
+	replayFilter *antireplay.ReplayFilter
 }
 
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
@@ -52,11 +55,22 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 
 	v := core.MustFromContext(ctx)
 
-	return &Server{
+	s := &Server{
 		user:    user,
 		account: account,
 		policy:  v.GetFeature(policy.ManagerType()).(policy.Manager),
-	}, nil
+	}
+	if account.FakeTlsDomain != "" {
+		s.fallback = net.TCPDestination(net.ParseAddress(account.FakeTlsDomain), 443)
+		if config.Fallback != "" {
+			s.fallback, err = net.ParseDestination("tcp:" + config.Fallback)
+			if err != nil {
+				return nil, newError("invalid fallback ", config.Fallback).Base(err)
+			}
+		}
+		s.replayFilter = antireplay.NewReplayFilter(int64(2 * fakeTLSTimeSkew / time.Second))
+	}
+	return s, nil
 }
 
 func (s *Server) Network() []net.Network {
@@ -65,6 +79,7 @@ func (s *Server) Network() []net.Network {
 
 var ctype1 = []byte{0xef, 0xef, 0xef, 0xef}
 var ctype2 = []byte{0xee, 0xee, 0xee, 0xee}
+var ctype3 = []byte{0xdd, 0xdd, 0xdd, 0xdd}
 
 func isValidConnectionType(c [4]byte) bool {
 	if bytes.Equal(c[:], ctype1) {
@@ -73,6 +88,9 @@ func isValidConnectionType(c [4]byte) bool {
 	if bytes.Equal(c[:], ctype2) {
 		return true
 	}
+	if bytes.Equal(c[:], ctype3) {
+		return true
+	}
 	return false
 }
 
@@ -82,6 +100,13 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 	if err := conn.SetDeadline(time.Now().Add(sPolicy.Timeouts.Handshake)); err != nil {
 		newError("failed to set deadline").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
+	if s.account.FakeTlsDomain != "" {
+		fakeTLSConn, err := s.fakeTLSHandshake(ctx, conn)
+		if err != nil {
+			return err
+		}
+		conn = fakeTLSConn
+	}
 	auth, err := ReadAuthentication(conn)
 	if err != nil {
 		return newError("failed to read authentication header").Base(err)
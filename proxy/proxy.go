@@ -5,12 +5,17 @@
 // 2. Register a config creator through common.RegisterConfig.
 package proxy
 
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
+
 import (
 	"context"
+	"time"
 
+	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
 	"github.com/eagleql/xray-core/transport"
 	"github.com/eagleql/xray-core/transport/internet"
 )
@@ -30,6 +35,15 @@ type Outbound interface {
 	Process(context.Context, *transport.Link, internet.Dialer) error
 }
 
+// PluginInbound is the interface for Inbounds that accept TCP connections through a plugin process, such as a SIP003 plugin.
+type PluginInbound interface {
+	// HasPlugin returns true if the inbound is configured with a plugin.
+	HasPlugin() bool
+
+	// StartPlugin starts the plugin, which accepts connections on remote and forwards them to local.
+	StartPlugin(remote net.Destination, local net.Destination) (common.Closable, error)
+}
+
 // UserManager is the interface for Inbounds and Outbounds that can manage their users.
 type UserManager interface {
 	// AddUser adds a new user.
@@ -39,6 +53,38 @@ type UserManager interface {
 	RemoveUser(context.Context, string) error
 }
 
+// UserLister is the interface for UserManagers that can also look up their users.
+type UserLister interface {
+	// GetUserByEmail returns a user by email, or nil if the user doesn't exist.
+	GetUserByEmail(context.Context, string) *protocol.MemoryUser
+
+	// GetUsers returns all users.
+	GetUsers(context.Context) []*protocol.MemoryUser
+
+	// GetUsersCount returns the number of users.
+	GetUsersCount(context.Context) int64
+}
+
+// CheckUser returns an error if the user has expired or has used up its traffic quota.
+// The traffic of the user is read from its traffic counters in the stats manager, which may be nil.
+func CheckUser(sm stats.Manager, user *protocol.MemoryUser) error {
+	if user.IsExpired(time.Now()) {
+		return newError("user ", user.Email, " expired at ", time.Unix(user.ExpiryTime, 0).Format(time.RFC3339))
+	}
+	if user.TotalTrafficLimit > 0 && sm != nil && len(user.Email) > 0 {
+		if traffic := stats.GetUserTraffic(sm, user.Email); user.IsOverQuota(traffic) {
+			return newError("user ", user.Email, " exceeds traffic limit: ", traffic, "/", user.TotalTrafficLimit)
+		}
+	}
+	return nil
+}
+
+// UserKicker is the interface for Inbounds that can close the active connections of their users.
+type UserKicker interface {
+	// KickUser closes all active connections of a user by email, and returns the number of connections closed.
+	KickUser(context.Context, string) int
+}
+
 type GetInbound interface {
 	GetInbound() Inbound
 }
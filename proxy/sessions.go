@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+type userSession struct {
+	closer io.Closer
+}
+
+// UserSessions tracks the active connections of users by email, so that they can be closed when the user is removed.
+type UserSessions struct {
+	access   sync.Mutex
+	sessions map[string]map[*userSession]struct{}
+}
+
+// NewUserSessions creates a new UserSessions.
+func NewUserSessions() *UserSessions {
+	return &UserSessions{
+		sessions: make(map[string]map[*userSession]struct{}),
+	}
+}
+
+// Add registers a connection of the user with the given email. It returns a function that unregisters the connection,
+// which must be called when the connection ends. Connections of users without email are not tracked.
+func (s *UserSessions) Add(email string, closer io.Closer) func() {
+	if email == "" {
+		return func() {}
+	}
+	email = strings.ToLower(email)
+	us := &userSession{closer: closer}
+
+	s.access.Lock()
+	if s.sessions[email] == nil {
+		s.sessions[email] = make(map[*userSession]struct{})
+	}
+	s.sessions[email][us] = struct{}{}
+	s.access.Unlock()
+
+	return func() {
+		s.access.Lock()
+		defer s.access.Unlock()
+
+		if sessions, found := s.sessions[email]; found {
+			delete(sessions, us)
+			if len(sessions) == 0 {
+				delete(s.sessions, email)
+			}
+		}
+	}
+}
+
+// Count returns the number of active connections of the user.
+func (s *UserSessions) Count(email string) int {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	return len(s.sessions[strings.ToLower(email)])
+}
+
+// CloseUser closes all active connections of the user, and returns the number of connections closed.
+func (s *UserSessions) CloseUser(email string) int {
+	email = strings.ToLower(email)
+
+	s.access.Lock()
+	sessions := s.sessions[email]
+	delete(s.sessions, email)
+	s.access.Unlock()
+
+	for us := range sessions {
+		us.closer.Close()
+	}
+	return len(sessions)
+}
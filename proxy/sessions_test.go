@@ -0,0 +1,42 @@
+package proxy_test
+
+import (
+	"testing"
+
+	. "github.com/eagleql/xray-core/proxy"
+)
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestUserSessions(t *testing.T) {
+	sessions := NewUserSessions()
+
+	c1 := new(testCloser)
+	c2 := new(testCloser)
+	c3 := new(testCloser)
+	sessions.Add("love@example.com", c1)
+	remove := sessions.Add("Love@example.com", c2)
+	sessions.Add("test@example.com", c3)
+
+	if n := sessions.Count("love@example.com"); n != 2 {
+		t.Error("expect 2 sessions, but got ", n)
+	}
+
+	remove()
+	if n := sessions.CloseUser("love@example.com"); n != 1 {
+		t.Error("expect 1 session to be closed, but got ", n)
+	}
+	if !c1.closed || c2.closed || c3.closed {
+		t.Error("unexpected closed connections: ", c1.closed, c2.closed, c3.closed)
+	}
+	if n := sessions.Count("love@example.com"); n != 0 {
+		t.Error("expect no sessions, but got ", n)
+	}
+}
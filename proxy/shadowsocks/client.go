@@ -2,12 +2,14 @@ package shadowsocks
 
 import (
 	"context"
+	"sync"
 
 	"github.com/eagleql/xray-core/common"
 	"github.com/eagleql/xray-core/common/buf"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/retry"
+	"github.com/eagleql/xray-core/common/serial"
 	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/common/signal"
 	"github.com/eagleql/xray-core/common/task"
@@ -19,19 +21,27 @@ import (
 
 // Client is a inbound handler for Shadowsocks protocol
 type Client struct {
+	config        *ClientConfig
+	servers       []*protocol.ServerSpec
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
+
+	access      sync.RWMutex
+	plugins     []*Plugin
+	pluginLocal map[net.Destination]net.Destination
 }
 
 // NewClient create a new Shadowsocks client.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	serverList := protocol.NewServerList()
+	var servers []*protocol.ServerSpec
 	for _, rec := range config.Server {
 		s, err := protocol.NewServerSpecFromPB(rec)
 		if err != nil {
 			return nil, newError("failed to parse server spec").Base(err)
 		}
 		serverList.AddServer(s)
+		servers = append(servers, s)
 	}
 	if serverList.Size() == 0 {
 		return nil, newError("0 server")
@@ -39,12 +49,73 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 
 	v := core.MustFromContext(ctx)
 	client := &Client{
+		config:        config,
+		servers:       servers,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 	}
 	return client, nil
 }
 
+// Start implements common.Runnable. It starts a SIP003 plugin for each server, if configured.
+func (c *Client) Start() error {
+	if c.config.Plugin == "" {
+		return nil
+	}
+
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.pluginLocal = make(map[net.Destination]net.Destination)
+	for _, server := range c.servers {
+		remote := server.Destination()
+		if _, found := c.pluginLocal[remote]; found {
+			continue
+		}
+		plugin, local, err := startLocalPlugin(c.config.Plugin, c.config.PluginOpts, c.config.PluginArgs, remote)
+		if err != nil {
+			return err
+		}
+		c.plugins = append(c.plugins, plugin)
+		c.pluginLocal[remote] = local
+	}
+	return nil
+}
+
+// Close implements common.Closable. It stops all the plugins.
+func (c *Client) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	var errors []interface{}
+	for _, plugin := range c.plugins {
+		if err := plugin.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	c.plugins = nil
+	c.pluginLocal = nil
+	if len(errors) > 0 {
+		return newError("failed to close plugins").Base(newError(serial.Concat(errors...)))
+	}
+	return nil
+}
+
+// dialDestination returns the destination to dial for server, which is the local port of its plugin for TCP.
+func (c *Client) dialDestination(server *protocol.ServerSpec, network net.Network) net.Destination {
+	dest := server.Destination()
+	if network == net.Network_TCP {
+		c.access.RLock()
+		local, found := c.pluginLocal[dest]
+		c.access.RUnlock()
+		if found {
+			return local
+		}
+	}
+	dest.Network = network
+	return dest
+}
+
 // Process implements OutboundHandler.Process().
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbound := session.OutboundFromContext(ctx)
@@ -59,9 +130,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 
 	err := retry.ExponentialBackoff(5, 100).On(func() error {
 		server = c.serverPicker.PickServer()
-		dest := server.Destination()
-		dest.Network = network
-		rawConn, err := dialer.Dial(ctx, dest)
+		rawConn, err := dialer.Dial(ctx, c.dialDestination(server, network))
 		if err != nil {
 			return err
 		}
@@ -88,10 +157,11 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	}
 
 	user := server.PickUser()
-	_, ok := user.Account.(*MemoryAccount)
+	account, ok := user.Account.(*MemoryAccount)
 	if !ok {
 		return newError("user account is not valid")
 	}
+	_, is2022 := account.Cipher.(*Cipher2022)
 	request.User = user
 
 	sessionPolicy := c.policyManager.ForLevel(user.Level)
@@ -117,7 +187,12 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		responseDone := func() error {
 			defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
-			responseReader, err := ReadTCPResponse(user, conn)
+			var responseReader buf.Reader
+			if w, ok := bodyWriter.(*writer2022); ok {
+				responseReader, err = readTCPResponse2022(user, w.salt, conn)
+			} else {
+				responseReader, err = ReadTCPResponse(user, conn)
+			}
 			if err != nil {
 				return err
 			}
@@ -134,6 +209,10 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	}
 
 	if request.Command == protocol.RequestCommandUDP {
+		var session *UDPSession2022
+		if is2022 {
+			session = NewUDPSession2022()
+		}
 
 		requestDone := func() error {
 			defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
@@ -141,6 +220,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 			writer := &UDPWriter{
 				Writer:  conn,
 				Request: request,
+				Session: session,
 			}
 
 			if err := buf.Copy(link.Reader, writer, buf.UpdateActivity(timer)); err != nil {
@@ -153,8 +233,9 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 			defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
 			reader := &UDPReader{
-				Reader: conn,
-				User:   user,
+				Reader:  conn,
+				User:    user,
+				Session: session,
 			}
 
 			if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
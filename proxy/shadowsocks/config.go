@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/golang/protobuf/proto"
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
 
@@ -21,8 +22,12 @@ import (
 
 // MemoryAccount is an account type converted from Account.
 type MemoryAccount struct {
-	Cipher Cipher
-	Key    []byte
+	Cipher     Cipher
+	CipherType CipherType
+	Key        []byte
+	Password   string
+	// IdentityKeys are the identity PSKs of Shadowsocks 2022 servers before the one holding Key.
+	IdentityKeys [][]byte
 }
 
 // Equals implements protocol.Account.Equals().
@@ -33,6 +38,14 @@ func (a *MemoryAccount) Equals(another protocol.Account) bool {
 	return false
 }
 
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *MemoryAccount) ToProto() proto.Message {
+	return &Account{
+		CipherType: a.CipherType,
+		Password:   a.Password,
+	}
+}
+
 func (a *MemoryAccount) GetCipherName() string {
 	switch a.Cipher.(type) {
 	case *AesCfb:
@@ -53,6 +66,14 @@ func (a *MemoryAccount) GetCipherName() string {
 		}
 	case *NoneCipher:
 		return "NONE"
+	case *Cipher2022:
+		switch reflect.ValueOf(a.Cipher.(*Cipher2022).AEADAuthCreator).Pointer() {
+		case reflect.ValueOf(createAesGcm).Pointer():
+			keyBytes := a.Cipher.(*Cipher2022).KeyBytes
+			return "2022_BLAKE3_AES_" + strconv.FormatInt(int64(keyBytes*8), 10) + "_GCM"
+		case reflect.ValueOf(createChacha20Poly1305).Pointer():
+			return "2022_BLAKE3_CHACHA20_POLY1305"
+		}
 	}
 
 	return ""
@@ -102,6 +123,22 @@ func (a *Account) getCipher() (Cipher, error) {
 		}, nil
 	case CipherType_NONE:
 		return NoneCipher{}, nil
+	case CipherType_BLAKE3_AES_128_GCM:
+		return &Cipher2022{
+			KeyBytes:        16,
+			AEADAuthCreator: createAesGcm,
+		}, nil
+	case CipherType_BLAKE3_AES_256_GCM:
+		return &Cipher2022{
+			KeyBytes:        32,
+			AEADAuthCreator: createAesGcm,
+		}, nil
+	case CipherType_BLAKE3_CHACHA20_POLY1305:
+		return &Cipher2022{
+			KeyBytes:        32,
+			AEADAuthCreator: createChacha20Poly1305,
+			ChaCha:          true,
+		}, nil
 	default:
 		return nil, newError("Unsupported cipher.")
 	}
@@ -113,9 +150,24 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	if err != nil {
 		return nil, newError("failed to get cipher").Base(err)
 	}
+	if c, ok := cipher.(*Cipher2022); ok {
+		keys, err := c.parseKeys(a.Password)
+		if err != nil {
+			return nil, err
+		}
+		return &MemoryAccount{
+			Cipher:       cipher,
+			CipherType:   a.CipherType,
+			Key:          keys[len(keys)-1],
+			Password:     a.Password,
+			IdentityKeys: keys[:len(keys)-1],
+		}, nil
+	}
 	return &MemoryAccount{
-		Cipher: cipher,
-		Key:    passwordToCipherKey([]byte(a.Password), cipher.KeySize()),
+		Cipher:     cipher,
+		CipherType: a.CipherType,
+		Key:        passwordToCipherKey([]byte(a.Password), cipher.KeySize()),
+		Password:   a.Password,
 	}, nil
 }
 
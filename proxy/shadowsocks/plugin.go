@@ -0,0 +1,155 @@
+package shadowsocks
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// Plugin is a running SIP003 plugin process.
+type Plugin struct {
+	access sync.Mutex
+	cmd    *exec.Cmd
+	closed bool
+	done   chan struct{}
+}
+
+// StartPlugin starts the SIP003 plugin at path. The plugin accepts connections on local and forwards them to remote
+// on the client side, and the other way around on the server side.
+func StartPlugin(path string, options string, args []string, remote net.Destination, local net.Destination) (*Plugin, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(),
+		"SS_REMOTE_HOST="+pluginHost(remote.Address),
+		"SS_REMOTE_PORT="+remote.Port.String(),
+		"SS_LOCAL_HOST="+pluginHost(local.Address),
+		"SS_LOCAL_PORT="+local.Port.String(),
+		"SS_PLUGIN_OPTIONS="+options,
+	)
+	cmd.Stdout = &pluginLogWriter{name: path}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return nil, newError("failed to start plugin ", path).Base(err)
+	}
+	newError("plugin ", path, " started for ", remote, " on ", local).AtInfo().WriteToLog()
+
+	p := &Plugin{
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+	go func() {
+		err := cmd.Wait()
+		p.access.Lock()
+		closed := p.closed
+		p.access.Unlock()
+		if !closed {
+			newError("plugin ", path, " exited unexpectedly").Base(err).AtError().WriteToLog()
+		}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Close implements common.Closable. It kills the plugin process and waits for it to exit.
+func (p *Plugin) Close() error {
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.access.Unlock()
+
+	if err := p.cmd.Process.Kill(); err != nil && err != os.ErrProcessDone {
+		return newError("failed to kill plugin ", p.cmd.Path).Base(err)
+	}
+	<-p.done
+	return nil
+}
+
+// pluginHost returns the host of address as SIP003 plugins expect it, that is, without brackets around IPv6 addresses.
+func pluginHost(address net.Address) string {
+	if address.Family().IsDomain() {
+		return address.Domain()
+	}
+	return address.IP().String()
+}
+
+type pluginLogWriter struct {
+	name string
+}
+
+func (w *pluginLogWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\r\n"), "\n") {
+		newError("[", w.name, "] ", line).AtInfo().WriteToLog()
+	}
+	return len(b), nil
+}
+
+const (
+	pluginStartAttempts = 5
+	pluginStartTimeout  = 2 * time.Second
+)
+
+// pickLocalPort returns a TCP port on the loopback address that is free at the moment.
+var pickLocalPort = func() (net.Port, error) {
+	listener, err := net.Listen("tcp", net.LocalHostIP.String()+":0")
+	if err != nil {
+		return 0, newError("failed to pick a local port").Base(err)
+	}
+	defer listener.Close()
+	return net.Port(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+// startLocalPlugin starts the SIP003 plugin for remote on a free local port, and returns the port as local. As the
+// port may be taken by others before the plugin binds it, the plugin is started again on another port if it exits
+// before accepting connections.
+func startLocalPlugin(path string, options string, args []string, remote net.Destination) (*Plugin, net.Destination, error) {
+	for i := 0; i < pluginStartAttempts; i++ {
+		port, err := pickLocalPort()
+		if err != nil {
+			return nil, net.Destination{}, err
+		}
+		local := net.TCPDestination(net.LocalHostIP, port)
+		plugin, err := StartPlugin(path, options, args, remote, local)
+		if err != nil {
+			return nil, net.Destination{}, err
+		}
+		if plugin.waitListening(local) {
+			return plugin, local, nil
+		}
+		plugin.Close()
+		newError("plugin ", path, " exited on ", local, ", retrying on another port").AtWarning().WriteToLog()
+	}
+	return nil, net.Destination{}, newError("failed to start plugin ", path, " after ", pluginStartAttempts, " attempts")
+}
+
+// waitListening waits until the plugin binds local, and returns false if it exits before. Connecting to local would
+// open a connection through the plugin to the server, so whether it is bound is told by failing to listen on it.
+// The plugin is assumed to be slow to start if it neither binds local nor exits in pluginStartTimeout.
+func (p *Plugin) waitListening(local net.Destination) bool {
+	deadline := time.Now().Add(pluginStartTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-p.done:
+			return false
+		default:
+		}
+		listener, err := net.Listen("tcp", local.NetAddr())
+		if err != nil {
+			break
+		}
+		// The plugin may fail to bind local while it is held here, in which case it exits and is started again.
+		listener.Close()
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
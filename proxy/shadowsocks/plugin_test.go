@@ -0,0 +1,149 @@
+package shadowsocks
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/testing/servers/tcp"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("XRAY_TEST_SIP003_PLUGIN") == "1" {
+		runDummyPlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runDummyPlugin is a SIP003 plugin that sends its options to each connection and then relays it to the remote.
+func runDummyPlugin() {
+	if os.Getenv("SS_LOCAL_PORT") == os.Getenv("XRAY_TEST_SIP003_TAKEN_PORT") {
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", os.Getenv("SS_LOCAL_HOST")+":"+os.Getenv("SS_LOCAL_PORT"))
+	common.Must(err)
+	remote := os.Getenv("SS_REMOTE_HOST") + ":" + os.Getenv("SS_REMOTE_PORT")
+	for {
+		conn, err := listener.Accept()
+		common.Must(err)
+		go func() {
+			defer conn.Close()
+			common.Must2(conn.Write([]byte(os.Getenv("SS_PLUGIN_OPTIONS"))))
+			remoteConn, err := net.Dial("tcp", remote)
+			if err != nil {
+				return
+			}
+			defer remoteConn.Close()
+			go io.Copy(remoteConn, conn)
+			io.Copy(conn, remoteConn)
+		}()
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	port, err := pickLocalPort()
+	common.Must(err)
+	local := net.TCPDestination(net.LocalHostIP, port)
+
+	common.Must(os.Setenv("XRAY_TEST_SIP003_PLUGIN", "1"))
+	defer os.Unsetenv("XRAY_TEST_SIP003_PLUGIN")
+	plugin, err := StartPlugin(os.Args[0], "obfs=http", nil, dest, local)
+	common.Must(err)
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", local.NetAddr()); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	common.Must(err)
+
+	common.Must2(conn.Write([]byte("payload")))
+	response := make([]byte, len("obfs=httppayload"))
+	common.Must2(io.ReadFull(conn, response))
+	if string(response) != "obfs=httppayload" {
+		t.Error("unexpected response: ", string(response))
+	}
+	conn.Close()
+
+	common.Must(plugin.Close())
+	if conn, err := net.Dial("tcp", local.NetAddr()); err == nil {
+		conn.Close()
+		t.Error("expect plugin to be stopped")
+	}
+}
+
+func TestStartLocalPluginRetry(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	pick := pickLocalPort
+	defer func() { pickLocalPort = pick }()
+	takenPort, err := pick()
+	common.Must(err)
+	picked := 0
+	pickLocalPort = func() (net.Port, error) {
+		picked++
+		if picked == 1 {
+			return takenPort, nil
+		}
+		return pick()
+	}
+
+	common.Must(os.Setenv("XRAY_TEST_SIP003_PLUGIN", "1"))
+	defer os.Unsetenv("XRAY_TEST_SIP003_PLUGIN")
+	common.Must(os.Setenv("XRAY_TEST_SIP003_TAKEN_PORT", takenPort.String()))
+	defer os.Unsetenv("XRAY_TEST_SIP003_TAKEN_PORT")
+	plugin, local, err := startLocalPlugin(os.Args[0], "obfs=http", nil, dest)
+	common.Must(err)
+	defer plugin.Close()
+
+	if picked != 2 || local.Port == takenPort {
+		t.Error("expect plugin to be restarted on another port, but got ", local, " after ", picked, " attempts")
+	}
+	conn, err := net.Dial("tcp", local.NetAddr())
+	common.Must(err)
+	conn.Close()
+}
+
+func TestStartLocalPluginWithoutProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", net.LocalHostIP.String()+":0")
+	common.Must(err)
+	defer listener.Close()
+	accepted := make(chan struct{}, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+			accepted <- struct{}{}
+		}
+	}()
+	dest := net.TCPDestination(net.LocalHostIP, net.Port(listener.Addr().(*net.TCPAddr).Port))
+
+	common.Must(os.Setenv("XRAY_TEST_SIP003_PLUGIN", "1"))
+	defer os.Unsetenv("XRAY_TEST_SIP003_PLUGIN")
+	plugin, _, err := startLocalPlugin(os.Args[0], "obfs=http", nil, dest)
+	common.Must(err)
+	defer plugin.Close()
+
+	select {
+	case <-accepted:
+		t.Error("expect no connection through the plugin to the server while starting it")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
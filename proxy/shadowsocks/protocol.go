@@ -80,6 +80,13 @@ func ReadTCPSession(validator *Validator, reader io.Reader) (*protocol.RequestHe
 		readSizeRemain -= int(buffer.Len())
 		DrainConnN(reader, readSizeRemain)
 		return nil, nil, newError("invalid user")
+	} else if validator.is2022() {
+		request, r, err := readTCPSession2022(validator, reader)
+		if err != nil {
+			DrainConnN(reader, readSizeRemain)
+			return nil, nil, err
+		}
+		return request, r, nil
 	} else if count > 1 {
 		var aead cipher.AEAD
 
@@ -168,6 +175,9 @@ func DrainConnN(reader io.Reader, n int) error {
 func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (buf.Writer, error) {
 	user := request.User
 	account := user.Account.(*MemoryAccount)
+	if _, ok := account.Cipher.(*Cipher2022); ok {
+		return writeTCPRequest2022(request, writer)
+	}
 
 	var iv []byte
 	if account.Cipher.IVSize() > 0 {
@@ -308,6 +318,8 @@ func DecodeUDPPacket(validator *Validator, payload *buf.Buffer) (*protocol.Reque
 type UDPReader struct {
 	Reader io.Reader
 	User   *protocol.MemoryUser
+	// Session is required for Shadowsocks 2022, and must be the same one as the UDPWriter.
+	Session *UDPSession2022
 }
 
 func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
@@ -320,7 +332,13 @@ func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 	validator := new(Validator)
 	validator.Add(v.User)
 
-	u, payload, err := DecodeUDPPacket(validator, buffer)
+	var u *protocol.RequestHeader
+	var payload *buf.Buffer
+	if v.Session != nil {
+		u, payload, err = decodeUDPPacket2022(validator, v.Session, headerTypeServer2022, buffer)
+	} else {
+		u, payload, err = DecodeUDPPacket(validator, buffer)
+	}
 	if err != nil {
 		buffer.Release()
 		return nil, err
@@ -333,6 +351,8 @@ func (v *UDPReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 type UDPWriter struct {
 	Writer  io.Writer
 	Request *protocol.RequestHeader
+	// Session is required for Shadowsocks 2022, and must be the same one as the UDPReader.
+	Session *UDPSession2022
 }
 
 func (w *UDPWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
@@ -350,7 +370,13 @@ func (w *UDPWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 				Port:    b.UDP.Port,
 			}
 		}
-		packet, err := EncodeUDPPacket(request, b.Bytes())
+		var packet *buf.Buffer
+		var err error
+		if w.Session != nil {
+			packet, err = encodeUDPPacket2022(request, w.Session, headerTypeClient2022, b.Bytes())
+		} else {
+			packet, err = EncodeUDPPacket(request, b.Bytes())
+		}
 		b.Release()
 		if err != nil {
 			buf.ReleaseMulti(mb)
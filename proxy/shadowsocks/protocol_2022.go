@@ -0,0 +1,761 @@
+package shadowsocks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/blake3"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/bytespool"
+	"github.com/eagleql/xray-core/common/crypto"
+	"github.com/eagleql/xray-core/common/dice"
+	"github.com/eagleql/xray-core/common/protocol"
+)
+
+const (
+	sessionSubkeyContext2022  = "shadowsocks 2022 session subkey"
+	identitySubkeyContext2022 = "shadowsocks 2022 identity subkey"
+
+	headerTypeClient2022 = 0
+	headerTypeServer2022 = 1
+
+	maxTimeDiff2022    = 30
+	saltLifetime2022   = 60 * time.Second
+	maxPaddingLength   = 900
+	identityHeaderSize = aes.BlockSize
+	separateHeaderSize = aes.BlockSize
+	replayWindowSize   = 64
+	xchachaNonceSize   = chacha20poly1305.NonceSizeX
+	aeadOverhead2022   = 16
+)
+
+// Cipher2022 represents the Shadowsocks 2022 ciphers, whose session keys are derived from pre-shared keys with BLAKE3.
+type Cipher2022 struct {
+	KeyBytes        int32
+	AEADAuthCreator func(key []byte) cipher.AEAD
+	// ChaCha is set for 2022-blake3-chacha20-poly1305, whose UDP packets are sealed by XChaCha20-Poly1305 instead of
+	// having a separate header, and which doesn't support identity headers.
+	ChaCha bool
+}
+
+func (*Cipher2022) IsAEAD() bool {
+	return true
+}
+
+func (c *Cipher2022) KeySize() int32 {
+	return c.KeyBytes
+}
+
+// IVSize returns the size of salt, which is the same as the key.
+func (c *Cipher2022) IVSize() int32 {
+	return c.KeyBytes
+}
+
+// parseKeys decodes the colon separated base64 keys in password. The last key is the PSK of the user, and the
+// preceding ones are identity PSKs.
+func (c *Cipher2022) parseKeys(password string) ([][]byte, error) {
+	parts := strings.Split(password, ":")
+	if len(parts) > 1 && c.ChaCha {
+		return nil, newError("identity PSK is not supported by 2022-blake3-chacha20-poly1305")
+	}
+	keys := make([][]byte, 0, len(parts))
+	for _, part := range parts {
+		key, err := decodeKey2022(part, c.KeyBytes)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func decodeKey2022(s string, keyBytes int32) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, newError("failed to decode Shadowsocks 2022 key in base64").Base(err)
+	}
+	if int32(len(key)) != keyBytes {
+		return nil, newError("Shadowsocks 2022 key must be ", keyBytes, " bytes, but got ", len(key))
+	}
+	return key, nil
+}
+
+func (c *Cipher2022) createAuthenticator(key []byte, salt []byte) *crypto.AEADAuthenticator {
+	return &crypto.AEADAuthenticator{
+		AEAD:           c.AEADAuthCreator(deriveKey2022(sessionSubkeyContext2022, key, salt)),
+		NonceGenerator: crypto.GenerateInitialAEADNonce(),
+	}
+}
+
+func (c *Cipher2022) NewEncryptionWriter(key []byte, iv []byte, writer io.Writer) (buf.Writer, error) {
+	return &writer2022{
+		auth:   c.createAuthenticator(key, iv),
+		writer: buf.NewWriter(writer),
+	}, nil
+}
+
+func (c *Cipher2022) NewDecryptionReader(key []byte, iv []byte, reader io.Reader) (buf.Reader, error) {
+	return newReader2022(c.createAuthenticator(key, iv), reader), nil
+}
+
+func (*Cipher2022) EncodePacket(key []byte, b *buf.Buffer) error {
+	return newError("Shadowsocks 2022 packet requires a session")
+}
+
+func (*Cipher2022) DecodePacket(key []byte, b *buf.Buffer) error {
+	return newError("Shadowsocks 2022 packet requires a session")
+}
+
+func deriveKey2022(context string, key []byte, salt []byte) []byte {
+	material := make([]byte, 0, len(key)+len(salt))
+	material = append(material, key...)
+	material = append(material, salt...)
+	subkey := make([]byte, len(key))
+	blake3.DeriveKey(subkey, context, material)
+	return subkey
+}
+
+// identityHash returns the plaintext of the identity header that refers to key.
+func identityHash(key []byte) [identityHeaderSize]byte {
+	var h [identityHeaderSize]byte
+	sum := blake3.Sum256(key)
+	copy(h[:], sum[:])
+	return h
+}
+
+func writeTimestamp(b []byte) {
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Unix()))
+}
+
+func checkTimestamp(b []byte) error {
+	diff := time.Now().Unix() - int64(binary.BigEndian.Uint64(b))
+	if diff > maxTimeDiff2022 || diff < -maxTimeDiff2022 {
+		return newError("timestamp is off by ", diff, " seconds")
+	}
+	return nil
+}
+
+// saltFilter rejects the salts seen in the last saltLifetime2022.
+type saltFilter struct {
+	sync.Mutex
+	salts     map[string]time.Time
+	lastClean time.Time
+}
+
+// Check returns false if the salt has been seen.
+func (f *saltFilter) Check(salt []byte) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	now := time.Now()
+	if f.salts == nil {
+		f.salts = make(map[string]time.Time)
+		f.lastClean = now
+	}
+	if now.Sub(f.lastClean) > saltLifetime2022 {
+		for s, t := range f.salts {
+			if now.Sub(t) > saltLifetime2022 {
+				delete(f.salts, s)
+			}
+		}
+		f.lastClean = now
+	}
+	if t, found := f.salts[string(salt)]; found && now.Sub(t) <= saltLifetime2022 {
+		return false
+	}
+	f.salts[string(salt)] = now
+	return true
+}
+
+// reader2022 reads the chunks of a Shadowsocks 2022 stream.
+type reader2022 struct {
+	auth      *crypto.AEADAuthenticator
+	reader    io.Reader
+	sizeBytes []byte
+	// salt is the salt of the stream, which is sent back in the response of a request.
+	salt []byte
+	// buffer is the initial payload in the header of a request.
+	buffer buf.MultiBuffer
+	// size is the size of the next chunk given by the header of a response.
+	size    int32
+	hasSize bool
+}
+
+func newReader2022(auth *crypto.AEADAuthenticator, reader io.Reader) *reader2022 {
+	return &reader2022{
+		auth:      auth,
+		reader:    reader,
+		sizeBytes: make([]byte, 2+auth.Overhead()),
+	}
+}
+
+func (r *reader2022) readChunk(size int32) ([]byte, error) {
+	b := bytespool.Alloc(size + int32(r.auth.Overhead()))
+	if _, err := io.ReadFull(r.reader, b[:size+int32(r.auth.Overhead())]); err != nil {
+		bytespool.Free(b)
+		return nil, err
+	}
+	p, err := r.auth.Open(b[:0], b[:size+int32(r.auth.Overhead())])
+	if err != nil {
+		bytespool.Free(b)
+		return nil, newError("failed to decrypt chunk").Base(err)
+	}
+	return p, nil
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *reader2022) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	if !r.buffer.IsEmpty() {
+		mb := r.buffer
+		r.buffer = nil
+		return mb, nil
+	}
+
+	size := r.size
+	if r.hasSize {
+		r.hasSize = false
+	} else {
+		if _, err := io.ReadFull(r.reader, r.sizeBytes); err != nil {
+			return nil, err
+		}
+		b, err := r.auth.Open(r.sizeBytes[:0], r.sizeBytes)
+		if err != nil {
+			return nil, newError("failed to decrypt chunk size").Base(err)
+		}
+		size = int32(binary.BigEndian.Uint16(b))
+	}
+	if size == 0 {
+		return nil, newError("empty chunk")
+	}
+
+	p, err := r.readChunk(size)
+	if err != nil {
+		return nil, err
+	}
+	defer bytespool.Free(p)
+	return buf.MergeBytes(nil, p), nil
+}
+
+// writer2022 writes the chunks of a Shadowsocks 2022 stream.
+type writer2022 struct {
+	auth   *crypto.AEADAuthenticator
+	writer buf.Writer
+	// salt is the salt of the stream, which is checked in the response of a request.
+	salt []byte
+	// header is the fixed-length header of a response without the size of the first chunk, which is sent together
+	// with the first chunk.
+	header []byte
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *writer2022) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	defer buf.ReleaseMulti(mb)
+
+	overhead := int32(w.auth.Overhead())
+	mb2Write := make(buf.MultiBuffer, 0, len(mb)+1)
+	for !mb.IsEmpty() {
+		eb := buf.New()
+		headerSize := int32(len(w.header)) + 2 + overhead
+		size := mb.Len()
+		if max := buf.Size - headerSize - overhead; size > max {
+			size = max
+		}
+
+		header := eb.Extend(headerSize)
+		if w.header != nil {
+			copy(header, w.header)
+			w.header = nil
+		}
+		binary.BigEndian.PutUint16(header[len(header)-int(overhead)-2:], uint16(size))
+		if _, err := w.auth.Seal(header[:0], header[:len(header)-int(overhead)]); err != nil {
+			eb.Release()
+			buf.ReleaseMulti(mb2Write)
+			return err
+		}
+
+		payload := eb.Extend(size + overhead)
+		mb, _ = buf.SplitBytes(mb, payload[:size])
+		if _, err := w.auth.Seal(payload[:0], payload[:size]); err != nil {
+			eb.Release()
+			buf.ReleaseMulti(mb2Write)
+			return err
+		}
+		mb2Write = append(mb2Write, eb)
+	}
+	if mb2Write.IsEmpty() {
+		return nil
+	}
+	return w.writer.WriteMultiBuffer(mb2Write)
+}
+
+func readTCPSession2022(validator *Validator, reader io.Reader) (*protocol.RequestHeader, buf.Reader, error) {
+	user := validator.anyUser()
+	if user == nil {
+		return nil, nil, newError("invalid user")
+	}
+	c := user.Account.(*MemoryAccount).Cipher.(*Cipher2022)
+
+	salt := make([]byte, c.KeyBytes)
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return nil, nil, newError("failed to read salt").Base(err)
+	}
+	if len(validator.Key) > 0 {
+		var identity [identityHeaderSize]byte
+		if _, err := io.ReadFull(reader, identity[:]); err != nil {
+			return nil, nil, newError("failed to read identity header").Base(err)
+		}
+		block, err := aes.NewCipher(deriveKey2022(identitySubkeyContext2022, validator.Key, salt))
+		common.Must(err)
+		block.Decrypt(identity[:], identity[:])
+		if user = validator.getByIdentity(identity); user == nil {
+			return nil, nil, newError("invalid user")
+		}
+	}
+	account := user.Account.(*MemoryAccount)
+
+	auth := c.createAuthenticator(account.Key, salt)
+	r := newReader2022(auth, reader)
+	r.salt = salt
+
+	header, err := r.readChunk(1 + 8 + 2)
+	if err != nil {
+		return nil, nil, newError("failed to read header").Base(err)
+	}
+	defer bytespool.Free(header)
+	if header[0] != headerTypeClient2022 {
+		return nil, nil, newError("invalid header type ", header[0])
+	}
+	if err := checkTimestamp(header[1:9]); err != nil {
+		return nil, nil, err
+	}
+	if !validator.salts.Check(salt) {
+		return nil, nil, newError("replayed salt")
+	}
+
+	size := int32(binary.BigEndian.Uint16(header[9:]))
+	variableHeader, err := r.readChunk(size)
+	if err != nil {
+		return nil, nil, newError("failed to read variable-length header").Base(err)
+	}
+	defer bytespool.Free(variableHeader)
+	headerReader := bytes.NewReader(variableHeader)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandTCP,
+	}
+	request.Address, request.Port, err = addrParser.ReadAddressPort(nil, headerReader)
+	if err != nil {
+		return nil, nil, newError("failed to read address").Base(err)
+	}
+
+	var paddingLength [2]byte
+	if _, err := io.ReadFull(headerReader, paddingLength[:]); err != nil {
+		return nil, nil, newError("failed to read padding length").Base(err)
+	}
+	padding := int64(binary.BigEndian.Uint16(paddingLength[:]))
+	if padding > maxPaddingLength || padding > int64(headerReader.Len()) {
+		return nil, nil, newError("invalid padding length ", padding)
+	}
+	headerReader.Seek(padding, io.SeekCurrent)
+	if headerReader.Len() > 0 {
+		r.buffer = buf.MergeBytes(nil, variableHeader[len(variableHeader)-headerReader.Len():])
+	}
+
+	return request, r, nil
+}
+
+func writeTCPRequest2022(request *protocol.RequestHeader, writer io.Writer) (buf.Writer, error) {
+	account := request.User.Account.(*MemoryAccount)
+	c := account.Cipher.(*Cipher2022)
+
+	header := buf.New()
+	defer header.Release()
+
+	salt := header.Extend(c.KeyBytes)
+	common.Must2(rand.Read(salt))
+	for i, key := range account.IdentityKeys {
+		next := account.Key
+		if i+1 < len(account.IdentityKeys) {
+			next = account.IdentityKeys[i+1]
+		}
+		block, err := aes.NewCipher(deriveKey2022(identitySubkeyContext2022, key, salt))
+		common.Must(err)
+		identity := identityHash(next)
+		block.Encrypt(header.Extend(identityHeaderSize), identity[:])
+	}
+
+	variableHeader := buf.New()
+	defer variableHeader.Release()
+	if err := addrParser.WriteAddressPort(variableHeader, request.Address, request.Port); err != nil {
+		return nil, newError("failed to write address").Base(err)
+	}
+	// There is no initial payload in the header, so padding is required.
+	padding := int32(dice.Roll(maxPaddingLength) + 1)
+	binary.BigEndian.PutUint16(variableHeader.Extend(2), uint16(padding))
+	common.Must2(rand.Read(variableHeader.Extend(padding)))
+
+	auth := c.createAuthenticator(account.Key, salt)
+	overhead := int32(auth.Overhead())
+
+	fixedHeader := header.Extend(1 + 8 + 2 + overhead)
+	fixedHeader[0] = headerTypeClient2022
+	writeTimestamp(fixedHeader[1:9])
+	binary.BigEndian.PutUint16(fixedHeader[9:11], uint16(variableHeader.Len()))
+	common.Must2(auth.Seal(fixedHeader[:0], fixedHeader[:11]))
+	common.Must2(auth.Seal(header.Extend(variableHeader.Len() + overhead)[:0], variableHeader.Bytes()))
+
+	if err := buf.WriteAllBytes(writer, header.Bytes()); err != nil {
+		return nil, newError("failed to write header").Base(err)
+	}
+
+	return &writer2022{
+		auth:   auth,
+		writer: buf.NewWriter(writer),
+		salt:   append([]byte(nil), salt...),
+	}, nil
+}
+
+func readTCPResponse2022(user *protocol.MemoryUser, requestSalt []byte, reader io.Reader) (buf.Reader, error) {
+	account := user.Account.(*MemoryAccount)
+	c := account.Cipher.(*Cipher2022)
+
+	salt := make([]byte, c.KeyBytes)
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return nil, newError("failed to read salt").Base(err)
+	}
+
+	r := newReader2022(c.createAuthenticator(account.Key, salt), reader)
+	header, err := r.readChunk(1 + 8 + c.KeyBytes + 2)
+	if err != nil {
+		return nil, newError("failed to read header").Base(err)
+	}
+	defer bytespool.Free(header)
+	if header[0] != headerTypeServer2022 {
+		return nil, newError("invalid header type ", header[0])
+	}
+	if err := checkTimestamp(header[1:9]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[9:9+c.KeyBytes], requestSalt) {
+		return nil, newError("mismatched request salt")
+	}
+	r.size = int32(binary.BigEndian.Uint16(header[9+c.KeyBytes:]))
+	r.hasSize = true
+	return r, nil
+}
+
+func writeTCPResponse2022(request *protocol.RequestHeader, requestSalt []byte, writer io.Writer) (buf.Writer, error) {
+	account := request.User.Account.(*MemoryAccount)
+	c := account.Cipher.(*Cipher2022)
+
+	salt := make([]byte, c.KeyBytes)
+	common.Must2(rand.Read(salt))
+	if err := buf.WriteAllBytes(writer, salt); err != nil {
+		return nil, newError("failed to write salt").Base(err)
+	}
+
+	header := make([]byte, 1+8+c.KeyBytes)
+	header[0] = headerTypeServer2022
+	writeTimestamp(header[1:9])
+	copy(header[9:], requestSalt)
+
+	return &writer2022{
+		auth:   c.createAuthenticator(account.Key, salt),
+		writer: buf.NewWriter(writer),
+		header: header,
+	}, nil
+}
+
+// replayWindow rejects the packet IDs seen or older than the window.
+type replayWindow struct {
+	last   uint64
+	bitmap uint64
+}
+
+// Check returns false if the packet ID should be rejected.
+func (w *replayWindow) Check(id uint64) bool {
+	if id > w.last {
+		if shift := id - w.last; shift < replayWindowSize {
+			w.bitmap <<= shift
+		} else {
+			w.bitmap = 0
+		}
+		w.bitmap |= 1
+		w.last = id
+		return true
+	}
+	diff := w.last - id
+	if diff >= replayWindowSize {
+		return false
+	}
+	if w.bitmap&(1<<diff) != 0 {
+		return false
+	}
+	w.bitmap |= 1 << diff
+	return true
+}
+
+// sessionFilter keeps a replayWindow for each session ID of the other side, until the session is idle for
+// saltLifetime2022, after which all of its packets are rejected by their timestamps.
+type sessionFilter struct {
+	sync.Mutex
+	windows   map[[8]byte]*sessionWindow
+	lastClean time.Time
+}
+
+type sessionWindow struct {
+	replayWindow
+	lastSeen time.Time
+}
+
+// Check returns false if the packet of the session should be rejected.
+func (f *sessionFilter) Check(sessionID []byte, packetID uint64) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	now := time.Now()
+	if f.windows == nil {
+		f.windows = make(map[[8]byte]*sessionWindow)
+		f.lastClean = now
+	}
+	if now.Sub(f.lastClean) > saltLifetime2022 {
+		for id, w := range f.windows {
+			if now.Sub(w.lastSeen) > saltLifetime2022 {
+				delete(f.windows, id)
+			}
+		}
+		f.lastClean = now
+	}
+	var id [8]byte
+	copy(id[:], sessionID)
+	w, found := f.windows[id]
+	if !found {
+		w = new(sessionWindow)
+		f.windows[id] = w
+	}
+	w.lastSeen = now
+	return w.Check(packetID)
+}
+
+// UDPSession2022 is a Shadowsocks 2022 UDP session, which is shared by the packets sent and received by one side
+// of an association.
+type UDPSession2022 struct {
+	sync.Mutex
+	id       [8]byte
+	packetID uint64
+	// remoteID is the latest session ID of the other side.
+	remoteID [8]byte
+	// filter rejects the replayed packets from the server. The ones from clients are filtered by the Validator, as
+	// a client session isn't bound to a source address.
+	filter sessionFilter
+}
+
+// NewUDPSession2022 creates a UDPSession2022 with a random session ID.
+func NewUDPSession2022() *UDPSession2022 {
+	s := new(UDPSession2022)
+	common.Must2(rand.Read(s.id[:]))
+	return s
+}
+
+func (s *UDPSession2022) setRemoteID(remoteID []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	copy(s.remoteID[:], remoteID)
+}
+
+func (s *UDPSession2022) getRemoteID() []byte {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]byte(nil), s.remoteID[:]...)
+}
+
+// encodeUDPPacket2022 encodes a packet sent by the client if headerType is headerTypeClient2022, or by the server.
+func encodeUDPPacket2022(request *protocol.RequestHeader, session *UDPSession2022, headerType byte, payload []byte) (*buf.Buffer, error) {
+	account := request.User.Account.(*MemoryAccount)
+	c := account.Cipher.(*Cipher2022)
+
+	buffer := buf.New()
+	if c.ChaCha {
+		common.Must2(rand.Read(buffer.Extend(xchachaNonceSize)))
+	}
+	separateHeader := buffer.Extend(separateHeaderSize)
+	copy(separateHeader, session.id[:])
+	binary.BigEndian.PutUint64(separateHeader[8:], atomic.AddUint64(&session.packetID, 1)-1)
+
+	var blockKey []byte
+	if !c.ChaCha {
+		blockKey = account.Key
+		if headerType == headerTypeClient2022 && len(account.IdentityKeys) > 0 {
+			blockKey = account.IdentityKeys[0]
+			for i, key := range account.IdentityKeys {
+				next := account.Key
+				if i+1 < len(account.IdentityKeys) {
+					next = account.IdentityKeys[i+1]
+				}
+				identity := identityHash(next)
+				for j := range identity {
+					identity[j] ^= separateHeader[j]
+				}
+				block, err := aes.NewCipher(key)
+				common.Must(err)
+				block.Encrypt(buffer.Extend(identityHeaderSize), identity[:])
+			}
+		}
+	}
+
+	bodyStart := buffer.Len()
+	buffer.WriteByte(headerType)
+	writeTimestamp(buffer.Extend(8))
+	if headerType == headerTypeServer2022 {
+		buffer.Write(session.getRemoteID())
+	}
+	buffer.Write([]byte{0, 0})
+	if err := addrParser.WriteAddressPort(buffer, request.Address, request.Port); err != nil {
+		buffer.Release()
+		return nil, newError("failed to write address").Base(err)
+	}
+	if buffer.Len()+int32(len(payload))+aeadOverhead2022 > buf.Size {
+		buffer.Release()
+		return nil, newError("payload too large: ", len(payload))
+	}
+	buffer.Write(payload)
+
+	if c.ChaCha {
+		aead, err := chacha20poly1305.NewX(account.Key)
+		common.Must(err)
+		nonce := buffer.BytesTo(xchachaNonceSize)
+		plaintext := buffer.BytesFrom(xchachaNonceSize)
+		buffer.Extend(int32(aead.Overhead()))
+		aead.Seal(plaintext[:0], nonce, plaintext, nil)
+		return buffer, nil
+	}
+
+	aead := c.AEADAuthCreator(deriveKey2022(sessionSubkeyContext2022, account.Key, separateHeader[:8]))
+	plaintext := buffer.BytesFrom(bodyStart)
+	buffer.Extend(int32(aead.Overhead()))
+	aead.Seal(plaintext[:0], separateHeader[4:16], plaintext, nil)
+	block, err := aes.NewCipher(blockKey)
+	common.Must(err)
+	block.Encrypt(separateHeader, separateHeader)
+	return buffer, nil
+}
+
+// decodeUDPPacket2022 decodes a packet sent by the client if headerType is headerTypeClient2022, or by the server.
+func decodeUDPPacket2022(validator *Validator, session *UDPSession2022, headerType byte, payload *buf.Buffer) (*protocol.RequestHeader, *buf.Buffer, error) {
+	user := validator.anyUser()
+	if user == nil {
+		return nil, nil, newError("invalid user")
+	}
+	c := user.Account.(*MemoryAccount).Cipher.(*Cipher2022)
+
+	var separateHeader [separateHeaderSize]byte
+	if c.ChaCha {
+		if payload.Len() <= xchachaNonceSize+separateHeaderSize+aeadOverhead2022 {
+			return nil, nil, newError("insufficient data: ", payload.Len())
+		}
+		aead, err := chacha20poly1305.NewX(user.Account.(*MemoryAccount).Key)
+		common.Must(err)
+		plaintext, err := aead.Open(payload.BytesFrom(xchachaNonceSize)[:0], payload.BytesTo(xchachaNonceSize), payload.BytesFrom(xchachaNonceSize), nil)
+		if err != nil {
+			return nil, nil, newError("failed to decrypt UDP payload").Base(err)
+		}
+		payload.Resize(xchachaNonceSize, xchachaNonceSize+int32(len(plaintext)))
+		copy(separateHeader[:], payload.BytesTo(separateHeaderSize))
+		payload.Advance(separateHeaderSize)
+	} else {
+		if payload.Len() <= separateHeaderSize+aeadOverhead2022 {
+			return nil, nil, newError("insufficient data: ", payload.Len())
+		}
+		blockKey := user.Account.(*MemoryAccount).Key
+		if headerType == headerTypeClient2022 && len(validator.Key) > 0 {
+			blockKey = validator.Key
+		}
+		block, err := aes.NewCipher(blockKey)
+		common.Must(err)
+		block.Decrypt(separateHeader[:], payload.BytesTo(separateHeaderSize))
+		payload.Advance(separateHeaderSize)
+
+		if headerType == headerTypeClient2022 && len(validator.Key) > 0 {
+			if payload.Len() <= identityHeaderSize {
+				return nil, nil, newError("insufficient data: ", payload.Len())
+			}
+			var identity [identityHeaderSize]byte
+			block.Decrypt(identity[:], payload.BytesTo(identityHeaderSize))
+			for i := range identity {
+				identity[i] ^= separateHeader[i]
+			}
+			payload.Advance(identityHeaderSize)
+			if user = validator.getByIdentity(identity); user == nil {
+				return nil, nil, newError("invalid user")
+			}
+		}
+
+		aead := c.AEADAuthCreator(deriveKey2022(sessionSubkeyContext2022, user.Account.(*MemoryAccount).Key, separateHeader[:8]))
+		plaintext, err := aead.Open(payload.BytesTo(0), separateHeader[4:16], payload.Bytes(), nil)
+		if err != nil {
+			return nil, nil, newError("failed to decrypt UDP payload").Base(err)
+		}
+		payload.Resize(0, int32(len(plaintext)))
+	}
+
+	headerSize := int32(1 + 8 + 2)
+	if headerType == headerTypeServer2022 {
+		headerSize += 8
+	}
+	if payload.Len() < headerSize {
+		return nil, nil, newError("insufficient data: ", payload.Len())
+	}
+	if payload.Byte(0) != headerType {
+		return nil, nil, newError("invalid header type ", payload.Byte(0))
+	}
+	if err := checkTimestamp(payload.BytesRange(1, 9)); err != nil {
+		return nil, nil, err
+	}
+	if headerType == headerTypeServer2022 && !bytes.Equal(payload.BytesRange(9, 17), session.id[:]) {
+		return nil, nil, newError("mismatched session ID")
+	}
+	padding := int32(binary.BigEndian.Uint16(payload.BytesRange(headerSize-2, headerSize)))
+	if padding > maxPaddingLength || headerSize+padding > payload.Len() {
+		return nil, nil, newError("invalid padding length ", padding)
+	}
+	payload.Advance(headerSize + padding)
+
+	filter := &session.filter
+	if headerType == headerTypeClient2022 {
+		filter = &validator.sessions
+	}
+	if !filter.Check(separateHeader[:8], binary.BigEndian.Uint64(separateHeader[8:])) {
+		return nil, nil, newError("replayed packet")
+	}
+	session.setRemoteID(separateHeader[:8])
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandUDP,
+	}
+	addr, port, err := addrParser.ReadAddressPort(nil, payload)
+	if err != nil {
+		return nil, nil, newError("failed to parse address").Base(err)
+	}
+	request.Address = addr
+	request.Port = port
+
+	return request, payload, nil
+}
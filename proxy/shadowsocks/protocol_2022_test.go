@@ -0,0 +1,243 @@
+package shadowsocks
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+)
+
+func newKey2022(size int) string {
+	key := make([]byte, size)
+	common.Must2(rand.Read(key))
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newBuffer(b []byte) *buf.Buffer {
+	buffer := buf.New()
+	common.Must2(buffer.Write(b))
+	return buffer
+}
+
+func newUser2022(password string, cipherType CipherType) *protocol.MemoryUser {
+	account, err := (&Account{Password: password, CipherType: cipherType}).AsAccount()
+	common.Must(err)
+	return &protocol.MemoryUser{Email: password, Account: account}
+}
+
+func TestTCPSession2022(t *testing.T) {
+	serverKey := newKey2022(16)
+	userKey := newKey2022(16)
+	chachaKey := newKey2022(32)
+
+	cases := []struct {
+		client    *protocol.MemoryUser
+		users     []*protocol.MemoryUser
+		serverKey string
+	}{
+		{
+			client: newUser2022(userKey, CipherType_BLAKE3_AES_128_GCM),
+			users:  []*protocol.MemoryUser{newUser2022(userKey, CipherType_BLAKE3_AES_128_GCM)},
+		},
+		{
+			client: newUser2022(serverKey+":"+userKey, CipherType_BLAKE3_AES_128_GCM),
+			users: []*protocol.MemoryUser{
+				newUser2022(newKey2022(16), CipherType_BLAKE3_AES_128_GCM),
+				newUser2022(userKey, CipherType_BLAKE3_AES_128_GCM),
+			},
+			serverKey: serverKey,
+		},
+		{
+			client: newUser2022(chachaKey, CipherType_BLAKE3_CHACHA20_POLY1305),
+			users:  []*protocol.MemoryUser{newUser2022(chachaKey, CipherType_BLAKE3_CHACHA20_POLY1305)},
+		},
+	}
+
+	for _, c := range cases {
+		validator := new(Validator)
+		if c.serverKey != "" {
+			validator.Key, _ = base64.StdEncoding.DecodeString(c.serverKey)
+		}
+		for _, u := range c.users {
+			common.Must(validator.Add(u))
+		}
+
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandTCP,
+			Address: net.DomainAddress("example.com"),
+			Port:    443,
+			User:    c.client,
+		}
+		cache := buf.New()
+		defer cache.Release()
+		writer, err := WriteTCPRequest(request, cache)
+		common.Must(err)
+		payload := buf.New()
+		common.Must2(payload.WriteString("request payload"))
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{payload}))
+		requestData := append([]byte(nil), cache.Bytes()...)
+
+		decodedRequest, reader, err := ReadTCPSession(validator, cache)
+		common.Must(err)
+		if decodedRequest.Destination() != request.Destination() {
+			t.Error("expect destination ", request.Destination(), " but got ", decodedRequest.Destination())
+		}
+		if !decodedRequest.User.Account.Equals(c.users[len(c.users)-1].Account) {
+			t.Error("unexpected user ", decodedRequest.User.Email)
+		}
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if mb.String() != "request payload" {
+			t.Error("unexpected request payload: ", mb.String())
+		}
+
+		cache.Clear()
+		responseWriter, err := writeTCPResponse2022(decodedRequest, reader.(*reader2022).salt, cache)
+		common.Must(err)
+		payload = buf.New()
+		common.Must2(payload.WriteString("response payload"))
+		common.Must(responseWriter.WriteMultiBuffer(buf.MultiBuffer{payload}))
+
+		responseReader, err := readTCPResponse2022(c.client, writer.(*writer2022).salt, cache)
+		common.Must(err)
+		mb, err = responseReader.ReadMultiBuffer()
+		common.Must(err)
+		if mb.String() != "response payload" {
+			t.Error("unexpected response payload: ", mb.String())
+		}
+
+		if _, _, err := ReadTCPSession(validator, newBuffer(requestData)); err == nil {
+			t.Error("expect replayed request to be rejected")
+		}
+	}
+}
+
+func TestUDPSession2022(t *testing.T) {
+	serverKey := newKey2022(32)
+	userKey := newKey2022(32)
+
+	cases := []struct {
+		client    *protocol.MemoryUser
+		user      *protocol.MemoryUser
+		serverKey string
+	}{
+		{
+			client:    newUser2022(serverKey+":"+userKey, CipherType_BLAKE3_AES_256_GCM),
+			user:      newUser2022(userKey, CipherType_BLAKE3_AES_256_GCM),
+			serverKey: serverKey,
+		},
+		{
+			client: newUser2022(userKey, CipherType_BLAKE3_CHACHA20_POLY1305),
+			user:   newUser2022(userKey, CipherType_BLAKE3_CHACHA20_POLY1305),
+		},
+	}
+
+	for _, c := range cases {
+		validator := new(Validator)
+		if c.serverKey != "" {
+			validator.Key, _ = base64.StdEncoding.DecodeString(c.serverKey)
+			common.Must(validator.Add(newUser2022(newKey2022(32), CipherType_BLAKE3_AES_256_GCM)))
+		}
+		common.Must(validator.Add(c.user))
+
+		clientSession := NewUDPSession2022()
+		serverSession := NewUDPSession2022()
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandUDP,
+			Address: net.LocalHostIP,
+			Port:    53,
+			User:    c.client,
+		}
+
+		packet, err := encodeUDPPacket2022(request, clientSession, headerTypeClient2022, []byte("request payload"))
+		common.Must(err)
+		replayed := append([]byte(nil), packet.Bytes()...)
+		decodedRequest, payload, err := decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, packet)
+		common.Must(err)
+		if decodedRequest.Destination() != request.Destination() {
+			t.Error("expect destination ", request.Destination(), " but got ", decodedRequest.Destination())
+		}
+		if payload.String() != "request payload" {
+			t.Error("unexpected request payload: ", payload.String())
+		}
+		if _, _, err := decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, newBuffer(replayed)); err == nil {
+			t.Error("expect replayed packet to be rejected")
+		}
+
+		decodedRequest.User = c.user
+		packet, err = encodeUDPPacket2022(decodedRequest, serverSession, headerTypeServer2022, []byte("response payload"))
+		common.Must(err)
+		clientValidator := new(Validator)
+		common.Must(clientValidator.Add(c.client))
+		_, payload, err = decodeUDPPacket2022(clientValidator, clientSession, headerTypeServer2022, packet)
+		common.Must(err)
+		if payload.String() != "response payload" {
+			t.Error("unexpected response payload: ", payload.String())
+		}
+	}
+}
+
+func TestUDPSession2022Replay(t *testing.T) {
+	user := newUser2022(newKey2022(32), CipherType_BLAKE3_AES_256_GCM)
+	validator := new(Validator)
+	common.Must(validator.Add(user))
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandUDP,
+		Address: net.LocalHostIP,
+		Port:    53,
+		User:    user,
+	}
+
+	packet, err := encodeUDPPacket2022(request, NewUDPSession2022(), headerTypeClient2022, []byte("request payload"))
+	common.Must(err)
+	replayed := append([]byte(nil), packet.Bytes()...)
+	serverSession := NewUDPSession2022()
+	_, _, err = decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, packet)
+	common.Must(err)
+
+	packet, err = encodeUDPPacket2022(request, NewUDPSession2022(), headerTypeClient2022, []byte("request payload"))
+	common.Must(err)
+	_, _, err = decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, packet)
+	common.Must(err)
+
+	if _, _, err := decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, newBuffer(replayed)); err == nil {
+		t.Error("expect packet replayed after the client session changed to be rejected")
+	}
+	if _, _, err := decodeUDPPacket2022(validator, NewUDPSession2022(), headerTypeClient2022, newBuffer(replayed)); err == nil {
+		t.Error("expect packet replayed from another source to be rejected")
+	}
+
+	common.Must(validator.Del(user.Email))
+	if _, _, err := decodeUDPPacket2022(validator, serverSession, headerTypeClient2022, newBuffer(replayed)); err == nil {
+		t.Error("expect packet to be rejected without users")
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	var w replayWindow
+	for _, c := range []struct {
+		id     uint64
+		accept bool
+	}{
+		{0, true},
+		{0, false},
+		{2, true},
+		{1, true},
+		{1, false},
+		{100, true},
+		{37, true},
+		{37, false},
+		{36, false},
+	} {
+		if w.Check(c.id) != c.accept {
+			t.Error("expect ", c.accept, " for packet ", c.id)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package shadowsocks
 
 import (
 	"context"
+	"encoding/base64"
 	"time"
 
 	"github.com/eagleql/xray-core/common"
@@ -16,6 +17,8 @@ import (
 	"github.com/eagleql/xray-core/core"
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
 	"github.com/eagleql/xray-core/transport/internet"
 	"github.com/eagleql/xray-core/transport/internet/udp"
 )
@@ -23,13 +26,22 @@ import (
 type Server struct {
 	config        *ServerConfig
 	validator     *Validator
+	sessions      *proxy.UserSessions
 	policyManager policy.Manager
+	statsManager  stats.Manager
 	cone          bool
 }
 
 // NewServer create a new Shadowsocks server.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	validator := new(Validator)
+	if config.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(config.Key)
+		if err != nil {
+			return nil, newError("failed to decode identity PSK in base64").Base(err).AtError()
+		}
+		validator.Key = key
+	}
 	for _, user := range config.Users {
 		u, err := user.ToMemoryUser()
 		if err != nil {
@@ -45,13 +57,25 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	s := &Server{
 		config:        config,
 		validator:     validator,
+		sessions:      proxy.NewUserSessions(),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:  v.GetFeature(stats.ManagerType()).(stats.Manager),
 		cone:          ctx.Value("cone").(bool),
 	}
 
 	return s, nil
 }
 
+// HasPlugin implements proxy.PluginInbound.HasPlugin().
+func (s *Server) HasPlugin() bool {
+	return s.config.Plugin != ""
+}
+
+// StartPlugin implements proxy.PluginInbound.StartPlugin().
+func (s *Server) StartPlugin(remote net.Destination, local net.Destination) (common.Closable, error) {
+	return StartPlugin(s.config.Plugin, s.config.PluginOpts, s.config.PluginArgs, remote, local)
+}
+
 // AddUser implements proxy.UserManager.AddUser().
 func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 	return s.validator.Add(u)
@@ -59,7 +83,31 @@ func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (s *Server) RemoveUser(ctx context.Context, e string) error {
-	return s.validator.Del(e)
+	if err := s.validator.Del(e); err != nil {
+		return err
+	}
+	s.sessions.CloseUser(e)
+	return nil
+}
+
+// KickUser implements proxy.UserKicker.KickUser().
+func (s *Server) KickUser(ctx context.Context, e string) int {
+	return s.sessions.CloseUser(e)
+}
+
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (s *Server) GetUserByEmail(ctx context.Context, e string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(e)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (s *Server) GetUsersCount(ctx context.Context) int64 {
+	return int64(s.validator.Count())
 }
 
 func (s *Server) Network() []net.Network {
@@ -82,6 +130,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 }
 
 func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	var session2022 *UDPSession2022
+	if s.validator.is2022() {
+		session2022 = NewUDPSession2022()
+	}
+
 	udpServer := udp.NewDispatcher(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
 		request := protocol.RequestHeaderFromContext(ctx)
 		if request == nil {
@@ -98,7 +151,13 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 			}
 		}
 
-		data, err := EncodeUDPPacket(request, payload.Bytes())
+		var data *buf.Buffer
+		var err error
+		if session2022 != nil {
+			data, err = encodeUDPPacket2022(request, session2022, headerTypeServer2022, payload.Bytes())
+		} else {
+			data, err = EncodeUDPPacket(request, payload.Bytes())
+		}
 		payload.Release()
 		if err != nil {
 			newError("failed to encode UDP packet").Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
@@ -118,6 +177,14 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 		inbound.User, _ = s.validator.GetOnlyUser()
 	}
 
+	// Packets of one session may come from different users, each of which must be able to kick it.
+	removeSessions := make(map[string]func())
+	defer func() {
+		for _, remove := range removeSessions {
+			remove()
+		}
+	}()
+
 	var dest *net.Destination
 
 	reader := buf.NewPacketReader(conn)
@@ -132,7 +199,12 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 			var data *buf.Buffer
 			var err error
 
-			if inbound.User != nil {
+			if session2022 != nil {
+				request, data, err = decodeUDPPacket2022(s.validator, session2022, headerTypeClient2022, payload)
+				if err == nil {
+					inbound.User = request.User
+				}
+			} else if inbound.User != nil {
 				validator := new(Validator)
 				validator.Add(inbound.User)
 				request, data, err = DecodeUDPPacket(validator, payload)
@@ -143,6 +215,10 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 				}
 			}
 
+			if err == nil {
+				err = proxy.CheckUser(s.statsManager, request.User)
+			}
+
 			if err != nil {
 				if inbound.Source.IsValid() {
 					newError("dropping invalid UDP packet from: ", inbound.Source).Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -157,6 +233,10 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 				continue
 			}
 
+			if _, found := removeSessions[request.User.Email]; !found {
+				removeSessions[request.User.Email] = s.sessions.Add(request.User.Email, conn)
+			}
+
 			destination := request.Destination()
 
 			currentPacketCtx := ctx
@@ -202,6 +282,18 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 		})
 		return newError("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
+
+	if err := proxy.CheckUser(s.statsManager, request.User); err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return newError("user rejected from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer s.sessions.Add(request.User.Email, conn)()
 	conn.SetReadDeadline(time.Time{})
 
 	inbound := session.InboundFromContext(ctx)
@@ -234,7 +326,12 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
 
 		bufferedWriter := buf.NewBufferedWriter(buf.NewWriter(conn))
-		responseWriter, err := WriteTCPResponse(request, bufferedWriter)
+		var responseWriter buf.Writer
+		if r, ok := bodyReader.(*reader2022); ok {
+			responseWriter, err = writeTCPResponse2022(request, r.salt, bufferedWriter)
+		} else {
+			responseWriter, err = WriteTCPResponse(request, bufferedWriter)
+		}
 		if err != nil {
 			return newError("failed to write response").Base(err)
 		}
@@ -13,6 +13,14 @@ type Validator struct {
 	// Considering email's usage here, map + sync.Mutex/RWMutex may have better performance.
 	email sync.Map
 	users sync.Map
+	// identities maps the identity hashes of Shadowsocks 2022 users to them.
+	identities sync.Map
+	salts      saltFilter
+	// sessions filters the UDP packets of Shadowsocks 2022 clients.
+	sessions sessionFilter
+
+	// Key is the identity PSK of a Shadowsocks 2022 server with multiple users.
+	Key []byte
 }
 
 // Add a Shadowsocks user, Email must be empty or unique.
@@ -23,6 +31,23 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 		return newError("The cipher do not support Single-port Multi-user")
 	}
 
+	c, is2022 := account.Cipher.(*Cipher2022)
+	if v.Count() > 0 && v.is2022() != is2022 {
+		return newError("Shadowsocks 2022 ciphers can't be used with other ciphers")
+	}
+	if is2022 {
+		if len(v.Key) > 0 {
+			if c.ChaCha {
+				return newError("identity PSK is not supported by 2022-blake3-chacha20-poly1305")
+			}
+			if len(v.Key) != len(account.Key) {
+				return newError("key of user ", u.Email, " has a different size from the identity PSK")
+			}
+		} else if v.Count() > 0 {
+			return newError("Shadowsocks 2022 with multiple users requires an identity PSK")
+		}
+	}
+
 	if u.Email != "" {
 		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
 		if loaded {
@@ -31,9 +56,31 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 	}
 
 	v.users.Store(string(account.Key)+"&"+account.GetCipherName(), u)
+	if is2022 {
+		v.identities.Store(identityHash(account.Key), u)
+	}
+	return nil
+}
+
+// GetByEmail gets a Shadowsocks user with a non-empty Email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(e string) *protocol.MemoryUser {
+	u, _ := v.email.Load(strings.ToLower(e))
+	if u != nil {
+		return u.(*protocol.MemoryUser)
+	}
 	return nil
 }
 
+// GetAll gets all Shadowsocks users.
+func (v *Validator) GetAll() []*protocol.MemoryUser {
+	var users []*protocol.MemoryUser
+	v.users.Range(func(_, u interface{}) bool {
+		users = append(users, u.(*protocol.MemoryUser))
+		return true
+	})
+	return users
+}
+
 // Del a Shadowsocks user with a non-empty Email.
 func (v *Validator) Del(e string) error {
 	if e == "" {
@@ -47,6 +94,9 @@ func (v *Validator) Del(e string) error {
 	account := u.(*protocol.MemoryUser).Account.(*MemoryAccount)
 	v.email.Delete(le)
 	v.users.Delete(string(account.Key) + "&" + account.GetCipherName())
+	if _, ok := account.Cipher.(*Cipher2022); ok {
+		v.identities.Delete(identityHash(account.Key))
+	}
 	return nil
 }
 
@@ -111,3 +161,30 @@ func (v *Validator) GetOnlyUser() (u *protocol.MemoryUser, ivLen int32) {
 
 	return
 }
+
+// anyUser returns one of the users, or nil if there are none.
+func (v *Validator) anyUser() (u *protocol.MemoryUser) {
+	v.users.Range(func(_, user interface{}) bool {
+		u = user.(*protocol.MemoryUser)
+		return false
+	})
+	return
+}
+
+// is2022 returns whether the users use Shadowsocks 2022 ciphers.
+func (v *Validator) is2022() (is2022 bool) {
+	v.users.Range(func(_, user interface{}) bool {
+		_, is2022 = user.(*protocol.MemoryUser).Account.(*MemoryAccount).Cipher.(*Cipher2022)
+		return false
+	})
+	return
+}
+
+// getByIdentity gets a Shadowsocks 2022 user by the plaintext of identity header, nil if user doesn't exist.
+func (v *Validator) getByIdentity(identity [identityHeaderSize]byte) *protocol.MemoryUser {
+	u, _ := v.identities.Load(identity)
+	if u != nil {
+		return u.(*protocol.MemoryUser)
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package socks
 
-import "github.com/eagleql/xray-core/common/protocol"
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/eagleql/xray-core/common/protocol"
+)
 
 func (a *Account) Equals(another protocol.Account) bool {
 	if account, ok := another.(*Account); ok {
@@ -9,17 +13,11 @@ func (a *Account) Equals(another protocol.Account) bool {
 	return false
 }
 
-func (a *Account) AsAccount() (protocol.Account, error) {
-	return a, nil
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *Account) ToProto() proto.Message {
+	return a
 }
 
-func (c *ServerConfig) HasAccount(username, password string) bool {
-	if c.Accounts == nil {
-		return false
-	}
-	storedPassed, found := c.Accounts[username]
-	if !found {
-		return false
-	}
-	return storedPassed == password
+func (a *Account) AsAccount() (protocol.Account, error) {
+	return a, nil
 }
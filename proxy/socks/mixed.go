@@ -0,0 +1,75 @@
+package socks
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/proxy"
+	"github.com/eagleql/xray-core/proxy/http"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+// newHTTPServer creates the HTTP server of a mixed SOCKS server, with the same users if password is required.
+func newHTTPServer(ctx context.Context, config *ServerConfig, validator *proxy.PasswordValidator) (*http.Server, error) {
+	httpConfig := &http.ServerConfig{
+		Timeout:   config.Timeout,
+		UserLevel: config.UserLevel,
+	}
+	if config.AuthType == AuthType_PASSWORD {
+		for _, u := range validator.GetAll() {
+			user := toHTTPUser(u)
+			httpConfig.Users = append(httpConfig.Users, &protocol.User{
+				Email:   user.Email,
+				Level:   user.Level,
+				Account: serial.ToTypedMessage(user.Account.(*http.Account)),
+			})
+		}
+	}
+	// Like SOCKS, HTTP requires authentication with password, even if there are no users yet.
+	return http.NewServerWithAuth(ctx, httpConfig, config.AuthType == AuthType_PASSWORD)
+}
+
+// toHTTPUser converts a SOCKS user to an HTTP user with the same username and password.
+func toHTTPUser(u *protocol.MemoryUser) *protocol.MemoryUser {
+	account := u.Account.(*Account)
+	return &protocol.MemoryUser{
+		Email: u.Email,
+		Level: u.Level,
+		Account: &http.Account{
+			Username: account.Username,
+			Password: account.Password,
+		},
+	}
+}
+
+// peekedConn is a connection whose first bytes have been read, and are returned again by Read.
+type peekedConn struct {
+	internet.Connection
+	peeked []byte
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	if len(c.peeked) > 0 {
+		n := copy(b, c.peeked)
+		c.peeked = c.peeked[n:]
+		return n, nil
+	}
+	return c.Connection.Read(b)
+}
+
+// processMixed reads the first byte of the connection to tell SOCKS from HTTP.
+func (s *Server) processMixed(ctx context.Context, conn internet.Connection) (internet.Connection, bool, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(s.policy(s.config.UserLevel).Timeouts.Handshake)); err != nil {
+		newError("failed to set deadline").Base(err).WriteToLog(session.ExportIDToError(ctx))
+	}
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(conn, first); err != nil {
+		return nil, false, newError("failed to read first byte").Base(err)
+	}
+	isSocks := first[0] == socks5Version || first[0] == socks4Version
+	return &peekedConn{Connection: conn, peeked: first}, isSocks, nil
+}
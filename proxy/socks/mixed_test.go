@@ -0,0 +1,40 @@
+package socks_test
+
+import (
+	"bufio"
+	"context"
+	gonet "net"
+	"net/http"
+	"testing"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/core"
+	. "github.com/eagleql/xray-core/proxy/socks"
+)
+
+const xrayKey core.XrayKey = 1
+
+func TestMixedHTTPAuthWithoutUsers(t *testing.T) {
+	v, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.WithValue(context.Background(), xrayKey, v), "cone", true)
+	server, err := NewServer(ctx, &ServerConfig{
+		AuthType: AuthType_PASSWORD,
+		Mixed:    true,
+	})
+	common.Must(err)
+
+	client, conn := gonet.Pipe()
+	defer client.Close()
+	go func() {
+		server.Process(ctx, net.Network_TCP, conn, nil)
+		conn.Close()
+	}()
+	common.Must2(client.Write([]byte("GET http://example.com/ HTTP/1.1\r\nHost: example.com\r\n\r\n")))
+	response, err := http.ReadResponse(bufio.NewReader(client), nil)
+	common.Must(err)
+	if response.StatusCode != http.StatusProxyAuthRequired {
+		t.Error("expect HTTP to require authentication like SOCKS, but got ", response.Status)
+	}
+}
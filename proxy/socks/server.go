@@ -18,6 +18,9 @@ import (
 	"github.com/eagleql/xray-core/features"
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
+	"github.com/eagleql/xray-core/proxy/http"
 	"github.com/eagleql/xray-core/transport/internet"
 	"github.com/eagleql/xray-core/transport/internet/udp"
 )
@@ -25,28 +28,117 @@ import (
 // Server is a SOCKS 5 proxy server
 type Server struct {
 	config        *ServerConfig
+	validator     *proxy.PasswordValidator
+	sessions      *proxy.UserSessions
 	policyManager policy.Manager
+	statsManager  stats.Manager
 	cone          bool
+	// http is the HTTP server on the same port in mixed mode, nil otherwise.
+	http *http.Server
 }
 
 // NewServer creates a new Server object.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	validator := new(proxy.PasswordValidator)
+	for username, password := range config.Accounts {
+		u := &protocol.MemoryUser{
+			Email:   username,
+			Level:   config.UserLevel,
+			Account: &Account{Username: username, Password: password},
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, newError("failed to add account").Base(err).AtError()
+		}
+	}
+	for _, user := range config.Users {
+		u, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, newError("failed to get SOCKS user").Base(err).AtError()
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, newError("failed to add user").Base(err).AtError()
+		}
+	}
+
 	v := core.MustFromContext(ctx)
 	s := &Server{
 		config:        config,
+		validator:     validator,
+		sessions:      proxy.NewUserSessions(),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:  v.GetFeature(stats.ManagerType()).(stats.Manager),
 		cone:          ctx.Value("cone").(bool),
 	}
+	if config.Mixed {
+		httpServer, err := newHTTPServer(ctx, config, validator)
+		if err != nil {
+			return nil, newError("failed to create HTTP server").Base(err)
+		}
+		s.http = httpServer
+	}
 	return s, nil
 }
 
-func (s *Server) policy() policy.Session {
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	if _, ok := u.Account.(*Account); !ok {
+		return newError("account of user ", u.Email, " is not a SOCKS account")
+	}
+	if err := s.validator.Add(u); err != nil {
+		return err
+	}
+	if s.http != nil && s.config.AuthType == AuthType_PASSWORD {
+		if err := s.http.AddUser(ctx, toHTTPUser(u)); err != nil {
+			s.validator.Del(u.Email)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	if err := s.validator.Del(e); err != nil {
+		return err
+	}
+	s.sessions.CloseUser(e)
+	if s.http != nil && s.http.GetUserByEmail(ctx, e) != nil {
+		return s.http.RemoveUser(ctx, e)
+	}
+	return nil
+}
+
+// KickUser implements proxy.UserKicker.KickUser().
+func (s *Server) KickUser(ctx context.Context, e string) int {
+	n := s.sessions.CloseUser(e)
+	if s.http != nil {
+		n += s.http.KickUser(ctx, e)
+	}
+	return n
+}
+
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (s *Server) GetUserByEmail(ctx context.Context, e string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(e)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (s *Server) GetUsersCount(ctx context.Context) int64 {
+	return int64(s.validator.Count())
+}
+
+func (s *Server) policy(level uint32) policy.Session {
 	config := s.config
-	p := s.policyManager.ForLevel(config.UserLevel)
+	p := s.policyManager.ForLevel(level)
 	if config.Timeout > 0 {
 		features.PrintDeprecatedFeatureWarning("Socks timeout")
 	}
-	if config.Timeout > 0 && config.UserLevel == 0 {
+	if config.Timeout > 0 && level == 0 {
 		p.Timeouts.ConnectionIdle = time.Duration(config.Timeout) * time.Second
 	}
 	return p
@@ -71,6 +163,16 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 
 	switch network {
 	case net.Network_TCP:
+		if s.http != nil {
+			mixedConn, isSocks, err := s.processMixed(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if !isSocks {
+				return s.http.Process(ctx, network, mixedConn, dispatcher)
+			}
+			conn = mixedConn
+		}
 		return s.processTCP(ctx, conn, dispatcher)
 	case net.Network_UDP:
 		return s.handleUDPPayload(ctx, conn, dispatcher)
@@ -80,7 +182,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 }
 
 func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
-	plcy := s.policy()
+	plcy := s.policy(s.config.UserLevel)
 	if err := conn.SetReadDeadline(time.Now().Add(plcy.Timeouts.Handshake)); err != nil {
 		newError("failed to set deadline").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
@@ -92,6 +194,7 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 
 	svrSession := &ServerSession{
 		config:       s.config,
+		validator:    s.validator,
 		address:      inbound.Gateway.Address,
 		port:         inbound.Gateway.Port,
 		localAddress: net.IPAddress(conn.LocalAddr().(*net.TCPAddr).IP),
@@ -111,7 +214,18 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 		return newError("failed to read request").Base(err)
 	}
 	if request.User != nil {
-		inbound.User.Email = request.User.Email
+		if err := proxy.CheckUser(s.statsManager, request.User); err != nil {
+			log.Record(&log.AccessMessage{
+				From:   inbound.Source,
+				To:     "",
+				Status: log.AccessRejected,
+				Reason: err,
+				Email:  request.User.Email,
+			})
+			return newError("user rejected from ", inbound.Source).Base(err).AtInfo()
+		}
+		inbound.User = request.User
+		defer s.sessions.Add(request.User.Email, conn)()
 	}
 
 	if err := conn.SetReadDeadline(time.Time{}); err != nil {
@@ -147,14 +261,18 @@ func (*Server) handleUDP(c io.Reader) error {
 }
 
 func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher, inbound *session.Inbound) error {
+	level := s.config.UserLevel
+	if inbound != nil && inbound.User != nil {
+		level = inbound.User.Level
+	}
+	plcy := s.policy(level)
 	ctx, cancel := context.WithCancel(ctx)
-	timer := signal.CancelAfterInactivity(ctx, cancel, s.policy().Timeouts.ConnectionIdle)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
 
 	if inbound != nil {
 		inbound.Timer = timer
 	}
 
-	plcy := s.policy()
 	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
 	link, err := dispatcher.Dispatch(ctx, dest)
 	if err != nil {
@@ -0,0 +1,9 @@
+package ssh
+
+import "github.com/eagleql/xray-core/common/errors"
+
+type errPathObjHolder struct{}
+
+func newError(values ...interface{}) *errors.Error {
+	return errors.New(values...).WithPathObj(errPathObjHolder{})
+}
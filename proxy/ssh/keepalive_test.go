@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/features/policy"
+	"github.com/eagleql/xray-core/testing/servers/ssh"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+// halfOpenConn drops everything written once the peer is gone, as a connection whose peer vanished silently.
+type halfOpenConn struct {
+	net.Conn
+	gone *int32
+}
+
+func (c *halfOpenConn) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(c.gone) == 1 {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+type halfOpenDialer struct {
+	gone int32
+}
+
+func (d *halfOpenDialer) Dial(ctx context.Context, dest net.Destination) (internet.Connection, error) {
+	conn, err := net.Dial("tcp", dest.NetAddr())
+	if err != nil {
+		return nil, err
+	}
+	return &halfOpenConn{Conn: conn, gone: &d.gone}, nil
+}
+
+func (d *halfOpenDialer) Address() net.Address {
+	return nil
+}
+
+func TestSSHKeepAlive(t *testing.T) {
+	interval, timeout := keepAliveInterval, keepAliveTimeout
+	keepAliveInterval, keepAliveTimeout = time.Millisecond*100, time.Millisecond*200
+	defer func() {
+		keepAliveInterval, keepAliveTimeout = interval, timeout
+	}()
+
+	sshServer := &ssh.Server{
+		User:     "user",
+		Password: "password",
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+	defer sshServer.Close()
+
+	h := new(Handler)
+	common.Must(h.Init(&Config{
+		Address:  net.NewIPOrDomain(dest.Address),
+		Port:     uint32(dest.Port),
+		User:     "user",
+		Password: "password",
+		HostKey:  []string{string(gossh.MarshalAuthorizedKey(sshServer.HostKey.PublicKey()))},
+	}, policy.DefaultManager{}))
+	defer h.Close()
+
+	dialer := new(halfOpenDialer)
+	client, err := h.getClient(dialer)
+	common.Must(err)
+
+	// Keepalive replies keep the connection while the server is there.
+	time.Sleep(keepAliveInterval * 5)
+	if h.currentClient() != client {
+		t.Fatal("expect the SSH connection to be kept while the server replies")
+	}
+
+	atomic.StoreInt32(&dialer.gone, 1)
+	time.Sleep(keepAliveInterval + keepAliveTimeout*2)
+	if h.currentClient() == client {
+		t.Error("expect the half-open SSH connection to be dropped")
+	}
+}
@@ -0,0 +1,329 @@
+// Package ssh contains an outbound that tunnels TCP connections through an SSH server, as in ssh -W.
+//
+// Connections are carried by direct-tcpip channels, multiplexed over one SSH connection shared by the handler.
+//
+// RSA keys are only used with ssh-rsa signatures, as golang.org/x/crypto/ssh at the version in use doesn't support
+// rsa-sha2-256 or rsa-sha2-512. OpenSSH 8.8 and later disable ssh-rsa by default, so use ed25519 or ECDSA keys for
+// both the user and the host with them.
+package ssh
+
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/retry"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/features/policy"
+	"github.com/eagleql/xray-core/transport"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+var (
+	// keepAliveInterval is the interval of keepalive requests on idle and busy SSH connections alike.
+	keepAliveInterval = time.Second * 30
+	// keepAliveTimeout is how long to wait for the reply to a keepalive request before dropping the SSH connection
+	// as half-open.
+	keepAliveTimeout = time.Second * 15
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		if err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			return h.Init(config.(*Config), pm)
+		}); err != nil {
+			return nil, err
+		}
+		return h, nil
+	}))
+}
+
+// Handler is an outbound connection handler that tunnels connections through an SSH server.
+type Handler struct {
+	config        *Config
+	server        net.Destination
+	clientConfig  *ssh.ClientConfig
+	policyManager policy.Manager
+
+	access sync.Mutex
+	client *ssh.Client
+	// dialAccess makes requests wait for the SSH connection being established instead of dialing their own.
+	dialAccess sync.Mutex
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager) error {
+	if config.Address == nil || config.Port == 0 {
+		return newError("SSH server is not specified")
+	}
+	clientConfig, err := config.clientConfig()
+	if err != nil {
+		return err
+	}
+
+	h.config = config
+	h.server = net.TCPDestination(config.Address.AsAddress(), net.Port(config.Port))
+	h.clientConfig = clientConfig
+	h.policyManager = pm
+	return nil
+}
+
+// clientConfig builds the configuration of the SSH client, with the authentication methods and the host keys.
+func (c *Config) clientConfig() (*ssh.ClientConfig, error) {
+	if c.User == "" {
+		return nil, newError("SSH user is not specified")
+	}
+
+	var auth []ssh.AuthMethod
+	if len(c.PrivateKey) > 0 {
+		var signer ssh.Signer
+		var err error
+		if len(c.PrivateKeyPassphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(c.PrivateKey, c.PrivateKeyPassphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(c.PrivateKey)
+		}
+		if err != nil {
+			return nil, newError("failed to parse SSH private key").Base(err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		auth = append(auth, ssh.Password(c.Password))
+	}
+	if len(auth) == 0 {
+		return nil, newError("neither SSH password nor private key is specified")
+	}
+
+	var hostKeys []ssh.PublicKey
+	for _, k := range c.HostKey {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return nil, newError("failed to parse SSH host key: ", k).Base(err)
+		}
+		hostKeys = append(hostKeys, key)
+	}
+	if len(hostKeys) == 0 {
+		return nil, newError("SSH host key is not specified")
+	}
+
+	config := &ssh.ClientConfig{
+		User: c.User,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			for _, k := range hostKeys {
+				if k.Type() == key.Type() && bytes.Equal(k.Marshal(), key.Marshal()) {
+					return nil
+				}
+			}
+			return newError("unknown SSH host key of ", hostname, ": ", ssh.FingerprintSHA256(key))
+		},
+	}
+	for _, k := range hostKeys {
+		config.HostKeyAlgorithms = append(config.HostKeyAlgorithms, k.Type())
+	}
+	return config, nil
+}
+
+// currentClient returns the SSH connection shared by tunnels, nil if there is none.
+func (h *Handler) currentClient() *ssh.Client {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	return h.client
+}
+
+// getClient returns the SSH connection shared by tunnels, connecting to the server with dialer if there is none.
+func (h *Handler) getClient(dialer internet.Dialer) (*ssh.Client, error) {
+	if client := h.currentClient(); client != nil {
+		return client, nil
+	}
+
+	h.dialAccess.Lock()
+	defer h.dialAccess.Unlock()
+
+	if client := h.currentClient(); client != nil {
+		return client, nil
+	}
+
+	client, err := h.dial(dialer)
+	if err != nil {
+		return nil, err
+	}
+	newError("SSH connection established to ", h.server).AtInfo().WriteToLog()
+
+	h.access.Lock()
+	h.client = client
+	h.access.Unlock()
+
+	closed := make(chan struct{})
+	go func() {
+		err := client.Wait()
+		close(closed)
+		newError("SSH connection to ", h.server, " closed").Base(err).AtInfo().WriteToLog()
+		h.removeClient(client)
+	}()
+	go h.keepAlive(client, closed)
+	return client, nil
+}
+
+// dial connects to the SSH server with dialer, and completes the SSH handshake within the handshake timeout.
+func (h *Handler) dial(dialer internet.Dialer) (*ssh.Client, error) {
+	// The connection outlives the request it is dialed for, so it must not be bound to the context of the request.
+	conn, err := dialer.Dial(context.Background(), h.server)
+	if err != nil {
+		return nil, newError("failed to dial SSH server ", h.server).Base(err)
+	}
+
+	plcy := h.policyManager.ForLevel(h.config.UserLevel)
+	if err := conn.SetDeadline(time.Now().Add(plcy.Timeouts.Handshake)); err != nil {
+		conn.Close()
+		return nil, newError("failed to set handshake deadline").Base(err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, h.server.NetAddr(), h.clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, newError("failed to establish SSH connection to ", h.server).Base(err)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		c.Close()
+		return nil, newError("failed to clear handshake deadline").Base(err)
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// keepAlive sends keepalive requests on the SSH connection until it is closed, and drops it if the server doesn't
+// reply in time, as the connection is likely half-open then.
+func (h *Handler) keepAlive(client *ssh.Client, closed <-chan struct{}) {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-closed:
+			return
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			// OpenSSH replies with failure to the request it doesn't know, which is a reply all the same.
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		timeout := time.NewTimer(keepAliveTimeout)
+		select {
+		case err := <-reply:
+			timeout.Stop()
+			if err != nil {
+				newError("failed to send keepalive to ", h.server).Base(err).AtInfo().WriteToLog()
+				h.removeClient(client)
+				return
+			}
+		case <-timeout.C:
+			newError("SSH connection to ", h.server, " timed out").AtInfo().WriteToLog()
+			h.removeClient(client)
+			return
+		case <-closed:
+			timeout.Stop()
+			return
+		}
+	}
+}
+
+// removeClient closes the SSH connection, and stops sharing it if it is the current one.
+func (h *Handler) removeClient(client *ssh.Client) {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.client == client {
+		h.client = nil
+	}
+	client.Close()
+}
+
+// Close implements common.Closable.
+func (h *Handler) Close() error {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.client == nil {
+		return nil
+	}
+	err := h.client.Close()
+	h.client = nil
+	return err
+}
+
+// Process implements proxy.Outbound.
+func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("target not specified.")
+	}
+	destination := outbound.Target
+	if destination.Network != net.Network_TCP {
+		return newError("UDP is not supported by SSH outbound")
+	}
+	newError("tunneling request to ", destination, " via ", h.server).WriteToLog(session.ExportIDToError(ctx))
+
+	var conn net.Conn
+	if err := retry.ExponentialBackoff(3, 100).On(func() error {
+		client, err := h.getClient(dialer)
+		if err != nil {
+			return err
+		}
+		conn, err = client.Dial("tcp", destination.NetAddr())
+		if err != nil {
+			// The server refusing the channel doesn't break the connection.
+			if _, ok := err.(*ssh.OpenChannelError); !ok {
+				h.removeClient(client)
+			}
+			return err
+		}
+		return nil
+	}); err != nil {
+		return newError("failed to open connection to ", destination).Base(err)
+	}
+	defer conn.Close()
+
+	plcy := h.policyManager.ForLevel(h.config.UserLevel)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
+
+	requestDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+
+		if err := buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to process request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
+
+		if err := buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to process response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, requestDone, task.OnSuccess(responseDone, task.Close(link.Writer))); err != nil {
+		return newError("connection ends").Base(err)
+	}
+	return nil
+}
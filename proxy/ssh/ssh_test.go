@@ -0,0 +1,322 @@
+package ssh_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/eagleql/xray-core/app/dispatcher"
+	"github.com/eagleql/xray-core/app/proxyman"
+	_ "github.com/eagleql/xray-core/app/proxyman/outbound"
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol"
+	"github.com/eagleql/xray-core/common/serial"
+	"github.com/eagleql/xray-core/common/session"
+	core "github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/features/outbound"
+	"github.com/eagleql/xray-core/features/policy"
+	"github.com/eagleql/xray-core/proxy/freedom"
+	. "github.com/eagleql/xray-core/proxy/ssh"
+	"github.com/eagleql/xray-core/testing/servers/ssh"
+	"github.com/eagleql/xray-core/testing/servers/tcp"
+	"github.com/eagleql/xray-core/transport"
+	"github.com/eagleql/xray-core/transport/internet"
+	_ "github.com/eagleql/xray-core/transport/internet/tcp"
+	"github.com/eagleql/xray-core/transport/pipe"
+)
+
+type systemDialer struct{}
+
+func (systemDialer) Dial(ctx context.Context, dest net.Destination) (internet.Connection, error) {
+	return net.Dial("tcp", dest.NetAddr())
+}
+
+func (systemDialer) Address() net.Address {
+	return nil
+}
+
+func xor(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, v := range b {
+		r[i] = v ^ 'c'
+	}
+	return r
+}
+
+func newHandler(config *Config) *Handler {
+	h := new(Handler)
+	common.Must(h.Init(config, policy.DefaultManager{}))
+	return h
+}
+
+func newConfig(server *ssh.Server, dest net.Destination) *Config {
+	return &Config{
+		Address: net.NewIPOrDomain(dest.Address),
+		Port:    uint32(dest.Port),
+		User:    "user",
+		HostKey: []string{string(gossh.MarshalAuthorizedKey(server.HostKey.PublicKey()))},
+	}
+}
+
+// tunnel sends payload through the handler to target, and returns the response.
+func tunnel(h *Handler, target net.Destination, payload []byte) ([]byte, error) {
+	return tunnelWith(func(ctx context.Context, link *transport.Link) error {
+		return h.Process(ctx, link, systemDialer{})
+	}, target, payload)
+}
+
+// tunnelWith sends payload to target through process, and returns the response.
+func tunnelWith(process func(context.Context, *transport.Link) error, target net.Destination, payload []byte) ([]byte, error) {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: target})
+	done := make(chan error, 1)
+	go func() {
+		err := process(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+		if err != nil {
+			downlinkWriter.Interrupt()
+		}
+		done <- err
+	}()
+
+	common.Must(uplinkWriter.WriteMultiBuffer(buf.MergeBytes(nil, payload)))
+	response := make([]byte, 0, len(payload))
+	for len(response) < len(payload) {
+		mb, err := downlinkReader.ReadMultiBuffer()
+		if err != nil {
+			return nil, <-done
+		}
+		for _, b := range mb {
+			response = append(response, b.Bytes()...)
+		}
+		buf.ReleaseMulti(mb)
+	}
+	uplinkWriter.Close()
+	return response, nil
+}
+
+func TestSSHPassword(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	target, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	sshServer := &ssh.Server{
+		User:     "user",
+		Password: "password",
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+	defer sshServer.Close()
+
+	config := newConfig(sshServer, dest)
+	config.Password = "password"
+	h := newHandler(config)
+	defer h.Close()
+
+	for i := 0; i < 3; i++ {
+		payload := make([]byte, 1024)
+		common.Must2(rand.Read(payload))
+		response, err := tunnel(h, target, payload)
+		common.Must(err)
+		if !bytes.Equal(response, xor(payload)) {
+			t.Error("unexpected response through SSH tunnel")
+		}
+	}
+	if n := sshServer.Connections(); n != 1 {
+		t.Error("expect 1 SSH connection, but got ", n)
+	}
+}
+
+func TestSSHPrivateKey(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	target, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	common.Must(err)
+	sshPublicKey, err := gossh.NewPublicKey(publicKey)
+	common.Must(err)
+
+	sshServer := &ssh.Server{
+		User:          "user",
+		AuthorizedKey: sshPublicKey,
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+	defer sshServer.Close()
+
+	config := newConfig(sshServer, dest)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	common.Must(err)
+	config.PrivateKey = pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	})
+	h := newHandler(config)
+	defer h.Close()
+
+	payload := []byte("hello")
+	response, err := tunnel(h, target, payload)
+	common.Must(err)
+	if !bytes.Equal(response, xor(payload)) {
+		t.Error("unexpected response through SSH tunnel")
+	}
+}
+
+func TestSSHUnknownHostKey(t *testing.T) {
+	sshServer := &ssh.Server{
+		User:     "user",
+		Password: "password",
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+	defer sshServer.Close()
+
+	otherServer := &ssh.Server{}
+	common.Must2(otherServer.Start())
+	defer otherServer.Close()
+
+	config := newConfig(otherServer, dest)
+	config.Password = "password"
+	h := newHandler(config)
+	defer h.Close()
+
+	if _, err := tunnel(h, net.TCPDestination(net.LocalHostIP, 80), []byte("hello")); err == nil {
+		t.Error("expect error for unknown host key")
+	}
+	if n := sshServer.Connections(); n != 0 {
+		t.Error("expect no SSH connection, but got ", n)
+	}
+}
+
+func TestSSHReconnect(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	target, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	sshServer := &ssh.Server{
+		User:     "user",
+		Password: "password",
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+
+	config := newConfig(sshServer, dest)
+	config.Password = "password"
+	h := newHandler(config)
+	defer h.Close()
+
+	payload := []byte("hello")
+	response, err := tunnel(h, target, payload)
+	common.Must(err)
+	if !bytes.Equal(response, xor(payload)) {
+		t.Error("unexpected response through SSH tunnel")
+	}
+
+	// The server drops the SSH connection, and comes back on the same port.
+	common.Must(sshServer.Close())
+	restartedServer := &ssh.Server{
+		Port:     sshServer.Port,
+		User:     "user",
+		Password: "password",
+		HostKey:  sshServer.HostKey,
+	}
+	common.Must2(restartedServer.Start())
+	defer restartedServer.Close()
+
+	response, err = tunnel(h, target, payload)
+	common.Must(err)
+	if !bytes.Equal(response, xor(payload)) {
+		t.Error("unexpected response through SSH tunnel after reconnecting")
+	}
+	if n := restartedServer.Connections(); n != 1 {
+		t.Error("expect 1 SSH connection after reconnecting, but got ", n)
+	}
+}
+
+func TestSSHDialerProxy(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	target, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	sshServer := &ssh.Server{
+		User:     "user",
+		Password: "password",
+	}
+	dest, err := sshServer.Start()
+	common.Must(err)
+	defer sshServer.Close()
+
+	// Nothing listens on the configured address, which only the dialer proxy redirects to the server.
+	config := newConfig(sshServer, net.TCPDestination(net.LocalHostIP, tcp.PickPort()))
+	config.Password = "password"
+
+	v, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag: "ssh",
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: &internet.StreamConfig{
+						SocketSettings: &internet.SocketConfig{
+							DialerProxy: "redirect",
+						},
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(config),
+			},
+			{
+				Tag: "redirect",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{
+					DestinationOverride: &freedom.DestinationOverride{
+						Server: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(dest.Address),
+							Port:    uint32(dest.Port),
+						},
+					},
+				}),
+			},
+		},
+	})
+	common.Must(err)
+	common.Must(v.Start())
+	defer v.Close()
+
+	h := v.GetFeature(outbound.ManagerType()).(outbound.Manager).GetHandler("ssh")
+	payload := []byte("hello")
+	response, err := tunnelWith(func(ctx context.Context, link *transport.Link) error {
+		h.Dispatch(ctx, link)
+		return nil
+	}, target, payload)
+	common.Must(err)
+	if !bytes.Equal(response, xor(payload)) {
+		t.Error("unexpected response through SSH tunnel")
+	}
+	if n := sshServer.Connections(); n != 1 {
+		t.Error("expect 1 SSH connection through the dialer proxy, but got ", n)
+	}
+}
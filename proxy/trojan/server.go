@@ -25,6 +25,7 @@ import (
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
 	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
 	"github.com/eagleql/xray-core/transport/internet"
 	"github.com/eagleql/xray-core/transport/internet/udp"
 	"github.com/eagleql/xray-core/transport/internet/xtls"
@@ -46,7 +47,9 @@ func init() {
 // Server is an inbound connection handler that handles messages in trojan protocol.
 type Server struct {
 	policyManager policy.Manager
+	statsManager  stats.Manager
 	validator     *Validator
+	sessions      *proxy.UserSessions
 	fallbacks     map[string]map[string]map[string]*Fallback // or nil
 	cone          bool
 }
@@ -68,7 +71,9 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	v := core.MustFromContext(ctx)
 	server := &Server{
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:  v.GetFeature(stats.ManagerType()).(stats.Manager),
 		validator:     validator,
+		sessions:      proxy.NewUserSessions(),
 		cone:          ctx.Value("cone").(bool),
 	}
 
@@ -132,7 +137,31 @@ func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (s *Server) RemoveUser(ctx context.Context, e string) error {
-	return s.validator.Del(e)
+	if err := s.validator.Del(e); err != nil {
+		return err
+	}
+	s.sessions.CloseUser(e)
+	return nil
+}
+
+// KickUser implements proxy.UserKicker.KickUser().
+func (s *Server) KickUser(ctx context.Context, e string) int {
+	return s.sessions.CloseUser(e)
+}
+
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (s *Server) GetUserByEmail(ctx context.Context, e string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(e)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (s *Server) GetUsersCount(ctx context.Context) int64 {
+	return s.validator.GetCount()
 }
 
 // Network implements proxy.Inbound.Network().
@@ -208,6 +237,18 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 		return newError("invalid protocol or invalid user")
 	}
 
+	if err := proxy.CheckUser(s.statsManager, user); err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  user.Email,
+		})
+		return newError("user rejected from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer s.sessions.Add(user.Email, conn)()
+
 	clientReader := &ConnReader{Reader: bufferedReader}
 	if err := clientReader.ParseHeader(); err != nil {
 		log.Record(&log.AccessMessage{
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/eagleql/xray-core/common/protocol"
+)
+
+// PasswordAccount is an account authenticated by a username and a password, such as the ones of HTTP and SOCKS.
+type PasswordAccount interface {
+	protocol.Account
+	GetUsername() string
+	GetPassword() string
+}
+
+// PasswordValidator stores valid users with PasswordAccount.
+type PasswordValidator struct {
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[string]*protocol.MemoryUser
+}
+
+// Add a user. Username must be unique, and Email must be empty or unique.
+func (v *PasswordValidator) Add(u *protocol.MemoryUser) error {
+	account, ok := u.Account.(PasswordAccount)
+	if !ok {
+		return newError("account of user ", u.Email, " has no username and password")
+	}
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[string]*protocol.MemoryUser)
+	}
+	if _, found := v.users[account.GetUsername()]; found {
+		return newError("User ", account.GetUsername(), " already exists.")
+	}
+	if u.Email != "" {
+		email := strings.ToLower(u.Email)
+		if _, found := v.email[email]; found {
+			return newError("User ", u.Email, " already exists.")
+		}
+		v.email[email] = u
+	}
+	v.users[account.GetUsername()] = u
+	return nil
+}
+
+// Del a user with a non-empty Email.
+func (v *PasswordValidator) Del(e string) error {
+	if e == "" {
+		return newError("Email must not be empty.")
+	}
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	email := strings.ToLower(e)
+	u, found := v.email[email]
+	if !found {
+		return newError("User ", e, " not found.")
+	}
+	delete(v.email, email)
+	delete(v.users, u.Account.(PasswordAccount).GetUsername())
+	return nil
+}
+
+// Get returns the user with the given username and password, nil if no user matches.
+func (v *PasswordValidator) Get(username, password string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	u, found := v.users[username]
+	if !found || u.Account.(PasswordAccount).GetPassword() != password {
+		return nil
+	}
+	return u
+}
+
+// GetByEmail gets a user with a non-empty Email, nil if user doesn't exist.
+func (v *PasswordValidator) GetByEmail(e string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	return v.email[strings.ToLower(e)]
+}
+
+// GetAll gets all users.
+func (v *PasswordValidator) GetAll() []*protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	users := make([]*protocol.MemoryUser, 0, len(v.users))
+	for _, u := range v.users {
+		users = append(users, u)
+	}
+	return users
+}
+
+// Count returns the number of users.
+func (v *PasswordValidator) Count() int {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	return len(v.users)
+}
@@ -0,0 +1,42 @@
+package proxy_test
+
+import (
+	"testing"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/protocol"
+	. "github.com/eagleql/xray-core/proxy"
+	"github.com/eagleql/xray-core/proxy/http"
+	"github.com/eagleql/xray-core/proxy/socks"
+)
+
+func TestPasswordValidator(t *testing.T) {
+	v := new(PasswordValidator)
+	user := &protocol.MemoryUser{
+		Email:   "love@example.com",
+		Level:   1,
+		Account: &http.Account{Username: "user", Password: "pass"},
+	}
+	common.Must(v.Add(user))
+
+	if err := v.Add(&protocol.MemoryUser{Account: &socks.Account{Username: "user", Password: "other"}}); err == nil {
+		t.Error("expect duplicated username to be rejected")
+	}
+	if u := v.Get("user", "pass"); u != user {
+		t.Error("expect ", user, " but got ", u)
+	}
+	if u := v.Get("user", "wrong"); u != nil {
+		t.Error("expect wrong password to be rejected, but got ", u)
+	}
+	if u := v.GetByEmail("LOVE@example.com"); u != user {
+		t.Error("expect ", user, " but got ", u)
+	}
+
+	common.Must(v.Del("love@example.com"))
+	if u := v.Get("user", "pass"); u != nil {
+		t.Error("expect removed user to be rejected, but got ", u)
+	}
+	if v.Count() != 0 {
+		t.Error("expect 0 users but got ", v.Count())
+	}
+}
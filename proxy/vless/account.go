@@ -1,6 +1,8 @@
 package vless
 
 import (
+	"github.com/golang/protobuf/proto"
+
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/uuid"
 )
@@ -36,3 +38,12 @@ func (a *MemoryAccount) Equals(account protocol.Account) bool {
 	}
 	return a.ID.Equals(vlessAccount.ID)
 }
+
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *MemoryAccount) ToProto() proto.Message {
+	return &Account{
+		Id:         a.ID.String(),
+		Flow:       a.Flow,
+		Encryption: a.Encryption,
+	}
+}
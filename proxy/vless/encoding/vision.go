@@ -0,0 +1,517 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/dice"
+	"github.com/eagleql/xray-core/common/errors"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/transport/internet"
+	"github.com/eagleql/xray-core/transport/internet/tls"
+	"github.com/eagleql/xray-core/transport/pipe"
+)
+
+const (
+	visionCommandContinue byte = 0
+	visionCommandEnd      byte = 1
+	visionCommandDirect   byte = 2
+
+	visionUUIDSize   = 16
+	visionHeaderSize = visionUUIDSize + 5
+
+	// Number of packets in which the inner TLS handshake is looked for.
+	visionPacketsToFilter = 8
+	// Timeout of the connection while splicing, during which the activity can't be observed.
+	visionSpliceTimeout = 8 * time.Hour
+
+	tlsHandshakeTypeClientHello byte = 1
+	tlsHandshakeTypeServerHello byte = 2
+)
+
+var (
+	tlsClientHandshakeStart = []byte{0x16, 0x03}
+	tlsServerHandshakeStart = []byte{0x16, 0x03, 0x03}
+	tlsApplicationDataStart = []byte{0x17, 0x03, 0x03}
+	tls13SupportedVersions  = []byte{0x00, 0x2b, 0x00, 0x02, 0x03, 0x04}
+)
+
+// TrafficState is the state of the inner TLS traffic of a connection with the vision flow. It is shared by the
+// VisionReader and the VisionWriter of the connection.
+type TrafficState struct {
+	userUUID []byte
+
+	sync.Mutex
+	packetsToFilter      int
+	isTLS                bool
+	isTLS12orAbove       bool
+	enableXtls           bool
+	cipher               uint16
+	remainingServerHello int
+}
+
+// NewTrafficState creates a new TrafficState for the user with the given UUID.
+func NewTrafficState(userUUID []byte) *TrafficState {
+	return &TrafficState{
+		userUUID:        userUUID,
+		packetsToFilter: visionPacketsToFilter,
+	}
+}
+
+// filter looks for the ClientHello and ServerHello of the inner TLS in b, and enables direct copy if the inner TLS is
+// TLS 1.3 with a supported cipher suite.
+func (s *TrafficState) filter(b []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.packetsToFilter <= 0 {
+		return
+	}
+	s.packetsToFilter--
+
+	if len(b) >= 6 {
+		if bytes.Equal(b[:3], tlsServerHandshakeStart) && b[5] == tlsHandshakeTypeServerHello {
+			s.remainingServerHello = (int(b[3])<<8 | int(b[4])) + 5
+			s.isTLS = true
+			s.isTLS12orAbove = true
+			// Record header (5), handshake header (4), version (2) and random (32) precede the session ID.
+			if len(b) >= 79 && s.remainingServerHello >= 79 {
+				sessionIDLen := int(b[43])
+				if len(b) >= 46+sessionIDLen {
+					s.cipher = uint16(b[44+sessionIDLen])<<8 | uint16(b[45+sessionIDLen])
+				}
+			}
+		} else if bytes.Equal(b[:2], tlsClientHandshakeStart) && b[5] == tlsHandshakeTypeClientHello {
+			s.isTLS = true
+		}
+	}
+
+	if s.remainingServerHello > 0 {
+		end := s.remainingServerHello
+		if end > len(b) {
+			end = len(b)
+		}
+		s.remainingServerHello -= len(b)
+		if bytes.Contains(b[:end], tls13SupportedVersions) {
+			// TLS_AES_128_CCM_8_SHA256 (0x1305) is not supported.
+			s.enableXtls = s.cipher >= 0x1301 && s.cipher <= 0x1304
+			s.packetsToFilter = 0
+		} else if s.remainingServerHello <= 0 {
+			s.packetsToFilter = 0
+		}
+	}
+}
+
+// command returns the command of the padding frame carrying b.
+func (s *TrafficState) command(b []byte) byte {
+	s.Lock()
+	defer s.Unlock()
+
+	isApplicationData := len(b) >= 3 && bytes.Equal(b[:3], tlsApplicationDataStart)
+	switch {
+	case s.enableXtls && isApplicationData:
+		return visionCommandDirect
+	case s.isTLS12orAbove:
+		if isApplicationData {
+			return visionCommandEnd
+		}
+		return visionCommandContinue
+	case !s.isTLS || s.packetsToFilter <= 0:
+		return visionCommandEnd
+	default:
+		return visionCommandContinue
+	}
+}
+
+// VisionConn is the connection under the TLS connection of a connection with the vision flow, to which the traffic
+// is copied directly once the inner TLS reaches application data.
+type VisionConn struct {
+	net.Conn
+	input        *bytes.Reader
+	rawInput     *bytes.Buffer
+	readCounter  stats.Counter
+	writeCounter stats.Counter
+	direct       int32
+}
+
+// NewVisionConn returns the VisionConn under conn, which must be a TLS connection.
+func NewVisionConn(conn net.Conn) (*VisionConn, error) {
+	c := &VisionConn{}
+	if statConn, ok := conn.(*internet.StatCouterConnection); ok {
+		conn = statConn.Connection
+		c.readCounter = statConn.ReadCounter
+		c.writeCounter = statConn.WriteCounter
+	}
+
+	var tlsConn reflect.Value
+	switch conn := conn.(type) {
+	case *tls.Conn:
+		tlsConn = reflect.ValueOf(conn.Conn).Elem()
+	case *tls.UConn:
+		tlsConn = reflect.ValueOf(conn.UConn.Conn).Elem()
+	default:
+		return nil, newError("not a TLS connection")
+	}
+
+	// The fields are not exported by crypto/tls and uTLS, so their layout is checked before they are accessed.
+	p := unsafe.Pointer(tlsConn.UnsafeAddr())
+	field := func(name string, t reflect.Type) (unsafe.Pointer, error) {
+		f, found := tlsConn.Type().FieldByName(name)
+		if !found || f.Type != t {
+			return nil, newError("unsupported TLS implementation: field ", name, " of type ", t, " not found")
+		}
+		return unsafe.Pointer(uintptr(p) + f.Offset), nil
+	}
+	inner, err := field("conn", reflect.TypeOf((*net.Conn)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	input, err := field("input", reflect.TypeOf(bytes.Reader{}))
+	if err != nil {
+		return nil, err
+	}
+	rawInput, err := field("rawInput", reflect.TypeOf(bytes.Buffer{}))
+	if err != nil {
+		return nil, err
+	}
+	c.Conn = *(*net.Conn)(inner)
+	c.input = (*bytes.Reader)(input)
+	c.rawInput = (*bytes.Buffer)(rawInput)
+	return c, nil
+}
+
+// leftover returns the data that has been read by the TLS connection from the connection, but not returned yet.
+func (c *VisionConn) leftover() buf.MultiBuffer {
+	var mb buf.MultiBuffer
+	if c.input.Len() > 0 {
+		b := make([]byte, c.input.Len())
+		n, _ := c.input.Read(b)
+		mb = buf.MergeBytes(mb, b[:n])
+	}
+	if c.rawInput.Len() > 0 {
+		mb = buf.MergeBytes(mb, c.rawInput.Next(c.rawInput.Len()))
+	}
+	if c.readCounter != nil {
+		c.readCounter.Add(int64(mb.Len()))
+	}
+	return mb
+}
+
+// CloseDirect closes the connection if data has been written to it directly, so that closing the TLS connection
+// afterwards doesn't send a close_notify alert in the middle of the inner TLS.
+func (c *VisionConn) CloseDirect() error {
+	if atomic.LoadInt32(&c.direct) == 0 {
+		return nil
+	}
+	return c.Conn.Close()
+}
+
+// VisionReader is a reader that removes the padding of the vision flow, and reads from the VisionConn directly once
+// the peer switches to direct copy.
+type VisionReader struct {
+	buf.Reader
+	state  *TrafficState
+	conn   *VisionConn
+	direct buf.Reader
+
+	unpadding        bool
+	uuidRemaining    int
+	header           []byte
+	inFrame          bool
+	command          byte
+	contentRemaining int
+	paddingRemaining int
+}
+
+// NewVisionReader creates a new VisionReader.
+func NewVisionReader(reader buf.Reader, state *TrafficState, conn *VisionConn) *VisionReader {
+	return &VisionReader{
+		Reader:        reader,
+		state:         state,
+		conn:          conn,
+		unpadding:     true,
+		uuidRemaining: visionUUIDSize,
+		header:        make([]byte, 0, 5),
+	}
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *VisionReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	if r.direct != nil {
+		mb, err := r.direct.ReadMultiBuffer()
+		if r.conn.readCounter != nil {
+			r.conn.readCounter.Add(int64(mb.Len()))
+		}
+		return mb, err
+	}
+
+	mb, err := r.Reader.ReadMultiBuffer()
+	if !r.unpadding {
+		return mb, err
+	}
+	mb, perr := r.unpad(mb)
+	if perr != nil {
+		buf.ReleaseMulti(mb)
+		return nil, perr
+	}
+	for _, b := range mb {
+		r.state.filter(b.Bytes())
+	}
+	if !r.unpadding && r.command == visionCommandDirect {
+		mb = append(mb, r.conn.leftover()...)
+		r.direct = buf.NewReader(r.conn.Conn)
+	}
+	return mb, err
+}
+
+func (r *VisionReader) unpad(mb buf.MultiBuffer) (buf.MultiBuffer, error) {
+	var content buf.MultiBuffer
+	defer buf.ReleaseMulti(mb)
+
+	for _, b := range mb {
+		data := b.Bytes()
+		for len(data) > 0 && r.unpadding {
+			switch {
+			case r.uuidRemaining > 0:
+				n := r.uuidRemaining
+				if n > len(data) {
+					n = len(data)
+				}
+				offset := visionUUIDSize - r.uuidRemaining
+				if !bytes.Equal(data[:n], r.state.userUUID[offset:offset+n]) {
+					return content, newError("invalid vision padding")
+				}
+				r.uuidRemaining -= n
+				data = data[n:]
+			case !r.inFrame:
+				r.header = append(r.header, data[0])
+				data = data[1:]
+				if len(r.header) == cap(r.header) {
+					r.inFrame = true
+					r.command = r.header[0]
+					r.contentRemaining = int(r.header[1])<<8 | int(r.header[2])
+					r.paddingRemaining = int(r.header[3])<<8 | int(r.header[4])
+					r.header = r.header[:0]
+				}
+			case r.contentRemaining > 0:
+				n := r.contentRemaining
+				if n > len(data) {
+					n = len(data)
+				}
+				content = buf.MergeBytes(content, data[:n])
+				r.contentRemaining -= n
+				data = data[n:]
+			default:
+				n := r.paddingRemaining
+				if n > len(data) {
+					n = len(data)
+				}
+				r.paddingRemaining -= n
+				data = data[n:]
+			}
+
+			if r.inFrame && r.contentRemaining == 0 && r.paddingRemaining == 0 {
+				r.inFrame = false
+				switch r.command {
+				case visionCommandContinue:
+				case visionCommandEnd, visionCommandDirect:
+					r.unpadding = false
+				default:
+					return content, newError("unknown vision command ", r.command)
+				}
+			}
+		}
+		if len(data) > 0 {
+			content = buf.MergeBytes(content, data)
+		}
+	}
+	return content, nil
+}
+
+// VisionWriter is a writer that pads the inner TLS handshake with the vision flow, and writes to the VisionConn
+// directly once the inner TLS reaches application data.
+type VisionWriter struct {
+	buf.Writer
+	state  *TrafficState
+	conn   *VisionConn
+	direct buf.Writer
+
+	padding   bool
+	writeUUID bool
+}
+
+// NewVisionWriter creates a new VisionWriter.
+func NewVisionWriter(writer buf.Writer, state *TrafficState, conn *VisionConn) *VisionWriter {
+	return &VisionWriter{
+		Writer:    writer,
+		state:     state,
+		conn:      conn,
+		padding:   true,
+		writeUUID: true,
+	}
+}
+
+// WriteMultiBuffer implements buf.Writer. An empty MultiBuffer is written as a padding frame without content.
+func (w *VisionWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if w.direct != nil {
+		if w.conn.writeCounter != nil {
+			w.conn.writeCounter.Add(int64(mb.Len()))
+		}
+		return w.direct.WriteMultiBuffer(mb)
+	}
+	if !w.padding {
+		return w.Writer.WriteMultiBuffer(mb)
+	}
+	if mb.IsEmpty() {
+		return w.Writer.WriteMultiBuffer(buf.MultiBuffer{w.pad(nil, visionCommandContinue)})
+	}
+
+	mb = reshapeMultiBuffer(mb)
+	padded := make(buf.MultiBuffer, 0, len(mb))
+	for i, b := range mb {
+		w.state.filter(b.Bytes())
+		command := w.state.command(b.Bytes())
+		padded = append(padded, w.pad(b, command))
+		if command == visionCommandContinue {
+			continue
+		}
+
+		w.padding = false
+		rest := mb[i+1:]
+		if err := w.Writer.WriteMultiBuffer(padded); err != nil {
+			buf.ReleaseMulti(rest)
+			return err
+		}
+		if command == visionCommandDirect {
+			// The padded frames may still be buffered.
+			if flusher, ok := w.Writer.(interface{ Flush() error }); ok {
+				if err := flusher.Flush(); err != nil {
+					buf.ReleaseMulti(rest)
+					return err
+				}
+			}
+			atomic.StoreInt32(&w.conn.direct, 1)
+			w.direct = buf.NewWriter(w.conn.Conn)
+		}
+		if rest.IsEmpty() {
+			return nil
+		}
+		return w.WriteMultiBuffer(rest)
+	}
+	return w.Writer.WriteMultiBuffer(padded)
+}
+
+// pad returns a padding frame with the content of b, and releases b.
+func (w *VisionWriter) pad(b *buf.Buffer, command byte) *buf.Buffer {
+	var contentLen int
+	if b != nil {
+		contentLen = int(b.Len())
+	}
+	var paddingLen int
+	w.state.Lock()
+	longPadding := w.state.isTLS
+	w.state.Unlock()
+	if longPadding && contentLen < 900 {
+		paddingLen = dice.Roll(500) + 900 - contentLen
+	} else {
+		paddingLen = dice.Roll(256)
+	}
+	if paddingLen > buf.Size-visionHeaderSize-contentLen {
+		paddingLen = buf.Size - visionHeaderSize - contentLen
+	}
+
+	frame := buf.New()
+	if w.writeUUID {
+		frame.Write(w.state.userUUID)
+		w.writeUUID = false
+	}
+	frame.Write([]byte{command, byte(contentLen >> 8), byte(contentLen), byte(paddingLen >> 8), byte(paddingLen)})
+	if b != nil {
+		frame.Write(b.Bytes())
+		b.Release()
+	}
+	padding := frame.Extend(int32(paddingLen))
+	for i := range padding {
+		padding[i] = 0
+	}
+	return frame
+}
+
+// reshapeMultiBuffer splits the buffers in mb, so that each of them fits in a padding frame.
+func reshapeMultiBuffer(mb buf.MultiBuffer) buf.MultiBuffer {
+	const limit = buf.Size - visionHeaderSize
+	reshaped := make(buf.MultiBuffer, 0, len(mb))
+	for _, b := range mb {
+		for b.Len() > limit {
+			part := buf.New()
+			part.Write(b.BytesTo(limit))
+			b.Advance(limit)
+			reshaped = append(reshaped, part)
+		}
+		reshaped = append(reshaped, b)
+	}
+	return reshaped
+}
+
+// CopyVision copies from reader to writer like buf.Copy. On Linux, once the reader switches to direct copy, it splices
+// the VisionConn to the TCP connection of the inbound in sctx, if the inbound relays the traffic as is. Splicing
+// bypasses writer, so it is only done if writer is a bare pipe, i.e. the traffic is not rate limited, counted or
+// checked against a quota by the dispatcher.
+func CopyVision(reader *VisionReader, writer buf.Writer, timer *signal.ActivityTimer, sctx context.Context) error {
+	if _, ok := writer.(*pipe.Writer); !ok {
+		sctx = nil
+	}
+	err := func() error {
+		for {
+			if reader.direct != nil && sctx != nil {
+				if inbound := session.InboundFromContext(sctx); inbound != nil && inbound.Conn != nil && inbound.Timer != nil &&
+					(runtime.GOOS == "linux" || runtime.GOOS == "android") {
+					iConn := inbound.Conn
+					statConn, ok := iConn.(*internet.StatCouterConnection)
+					if ok {
+						iConn = statConn.Connection
+					}
+					if tc, ok := iConn.(*net.TCPConn); ok {
+						timer.SetTimeout(visionSpliceTimeout)
+						inbound.Timer.SetTimeout(visionSpliceTimeout)
+						runtime.Gosched() // necessary
+						w, err := tc.ReadFrom(reader.conn.Conn)
+						if reader.conn.readCounter != nil {
+							reader.conn.readCounter.Add(w)
+						}
+						if statConn != nil && statConn.WriteCounter != nil {
+							statConn.WriteCounter.Add(w)
+						}
+						return err
+					}
+				}
+				sctx = nil
+			}
+			buffer, err := reader.ReadMultiBuffer()
+			if !buffer.IsEmpty() {
+				timer.Update()
+				if werr := writer.WriteMultiBuffer(buffer); werr != nil {
+					return werr
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}()
+	if err != nil && errors.Cause(err) != io.EOF {
+		return err
+	}
+	return nil
+}
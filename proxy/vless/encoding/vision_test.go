@@ -0,0 +1,184 @@
+package encoding_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol/tls/cert"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/common/uuid"
+	. "github.com/eagleql/xray-core/proxy/vless/encoding"
+	"github.com/eagleql/xray-core/transport/internet/tls"
+	"github.com/eagleql/xray-core/transport/pipe"
+)
+
+// recordingConn records the raw bytes it reads.
+type recordingConn struct {
+	net.Conn
+	sync.Mutex
+	recorded []byte
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.Lock()
+	c.recorded = append(c.recorded, b[:n]...)
+	c.Unlock()
+	return n, err
+}
+
+func tlsRecord(contentType byte, payload []byte) []byte {
+	return append([]byte{contentType, 0x03, 0x03, byte(len(payload) >> 8), byte(len(payload))}, payload...)
+}
+
+func tlsServerHello(cipher uint16) []byte {
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 32)
+	body = append(body, make([]byte, 32)...) // session ID
+	body = append(body, byte(cipher>>8), byte(cipher), 0x00)
+	body = append(body, 0x00, 0x06, 0x00, 0x2b, 0x00, 0x02, 0x03, 0x04) // supported_versions: TLS 1.3
+	handshake := append([]byte{0x02, 0x00, byte(len(body) >> 8), byte(len(body))}, body...)
+	return tlsRecord(0x16, handshake)
+}
+
+func readFull(t *testing.T, reader buf.Reader, size int) []byte {
+	var data []byte
+	for len(data) < size {
+		mb, err := reader.ReadMultiBuffer()
+		for _, b := range mb {
+			data = append(data, b.Bytes()...)
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return data
+}
+
+func TestVisionDirectCopy(t *testing.T) {
+	certificate, err := gotls.X509KeyPair(cert.MustGenerate(nil, cert.DNSNames("www.example.com")).ToPEM())
+	common.Must(err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	serverConnCh := make(chan *recordingConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		serverConnCh <- &recordingConn{Conn: conn}
+	}()
+	rawClientConn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	rawClientConn = &recordingConn{Conn: rawClientConn}
+	rawServerConn := <-serverConnCh
+
+	clientConn := tls.Client(rawClientConn, &gotls.Config{InsecureSkipVerify: true})
+	defer clientConn.Close()
+	serverConn := tls.Server(rawServerConn, &gotls.Config{Certificates: []gotls.Certificate{certificate}})
+	defer serverConn.Close()
+
+	id := uuid.New()
+	clientVision, err := NewVisionConn(clientConn)
+	common.Must(err)
+	serverVision, err := NewVisionConn(serverConn)
+	common.Must(err)
+	clientState := NewTrafficState(id.Bytes())
+	serverState := NewTrafficState(id.Bytes())
+	clientWriter := NewVisionWriter(buf.NewWriter(clientConn), clientState, clientVision)
+	clientReader := NewVisionReader(buf.NewReader(clientConn), clientState, clientVision)
+	serverWriter := NewVisionWriter(buf.NewWriter(serverConn), serverState, serverVision)
+	serverReader := NewVisionReader(buf.NewReader(serverConn), serverState, serverVision)
+
+	clientHello := tlsRecord(0x16, append([]byte{0x01, 0x00, 0x00, 0x10}, make([]byte, 16)...))
+	clientHello[2] = 0x01 // TLS 1.0 in the record header
+	serverHello := tlsServerHello(0x1301)
+	clientData := make([]byte, 16*1024)
+	common.Must2(rand.Read(clientData))
+	clientData = tlsRecord(0x17, clientData)
+	serverData := make([]byte, 1024)
+	common.Must2(rand.Read(serverData))
+	serverData = tlsRecord(0x17, serverData)
+	clientDirectData := tlsRecord(0x17, make([]byte, 1024))
+	common.Must2(rand.Read(clientDirectData[5:]))
+	serverDirectData := tlsRecord(0x17, make([]byte, 1024))
+	common.Must2(rand.Read(serverDirectData[5:]))
+
+	for _, test := range []struct {
+		writer *VisionWriter
+		reader *VisionReader
+		data   []byte
+	}{
+		{clientWriter, serverReader, clientHello},
+		{serverWriter, clientReader, serverHello},
+		{clientWriter, serverReader, clientData},
+		{serverWriter, clientReader, serverData},
+		{clientWriter, serverReader, clientDirectData},
+		{serverWriter, clientReader, serverDirectData},
+	} {
+		errCh := make(chan error, 1)
+		go func(writer *VisionWriter, data []byte) {
+			errCh <- writer.WriteMultiBuffer(buf.MergeBytes(nil, data))
+		}(test.writer, test.data)
+		if actual := readFull(t, test.reader, len(test.data)); !bytes.Equal(actual, test.data) {
+			t.Error("expect ", len(test.data), " bytes as written but got ", len(actual))
+		}
+		common.Must(<-errCh)
+	}
+
+	// Application data after the switch is copied directly, so it is not encrypted by the TLS connection.
+	if !bytes.Contains(rawServerConn.recorded, clientDirectData) {
+		t.Error("expect client application data to be copied directly")
+	}
+	if !bytes.Contains(rawClientConn.(*recordingConn).recorded, serverDirectData) {
+		t.Error("expect server application data to be copied directly")
+	}
+	if bytes.Contains(rawServerConn.recorded, clientHello[5:]) {
+		t.Error("expect client hello to be sent through TLS")
+	}
+
+	// Traffic passing through a writer other than a bare pipe, e.g. one counting user traffic, is not spliced.
+	inboundConn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer inboundConn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := signal.CancelAfterInactivity(ctx, cancel, time.Minute)
+	sctx := session.ContextWithInbound(ctx, &session.Inbound{Conn: inboundConn, Timer: timer})
+	_, writer := pipe.New()
+	counter := &countingWriter{Writer: writer}
+	copyErr := make(chan error, 1)
+	go func() {
+		copyErr <- CopyVision(clientReader, counter, timer, sctx)
+	}()
+	common.Must(serverWriter.WriteMultiBuffer(buf.MergeBytes(nil, serverDirectData)))
+	rawServerConn.Close()
+	if err := <-copyErr; err != nil {
+		t.Error(err)
+	}
+	if counter.size != int64(len(serverDirectData)) {
+		t.Error("expect ", len(serverDirectData), " bytes to be counted but got ", counter.size)
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	buf.Writer
+	size int64
+}
+
+func (w *countingWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.size += int64(mb.Len())
+	return w.Writer.WriteMultiBuffer(mb)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
 	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
 	"github.com/eagleql/xray-core/proxy/vless"
 	"github.com/eagleql/xray-core/proxy/vless/encoding"
 	"github.com/eagleql/xray-core/transport/internet"
@@ -62,7 +63,9 @@ func init() {
 type Handler struct {
 	inboundHandlerManager feature_inbound.Manager
 	policyManager         policy.Manager
+	statsManager          stats.Manager
 	validator             *vless.Validator
+	sessions              *proxy.UserSessions
 	dns                   dns.Client
 	fallbacks             map[string]map[string]map[string]*Fallback // or nil
 	// regexps               map[string]*regexp.Regexp       // or nil
@@ -74,7 +77,9 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 	handler := &Handler{
 		inboundHandlerManager: v.GetFeature(feature_inbound.ManagerType()).(feature_inbound.Manager),
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:          v.GetFeature(stats.ManagerType()).(stats.Manager),
 		validator:             new(vless.Validator),
+		sessions:              proxy.NewUserSessions(),
 		dns:                   dc,
 	}
 
@@ -163,7 +168,31 @@ func (h *Handler) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (h *Handler) RemoveUser(ctx context.Context, e string) error {
-	return h.validator.Del(e)
+	if err := h.validator.Del(e); err != nil {
+		return err
+	}
+	h.sessions.CloseUser(e)
+	return nil
+}
+
+// KickUser implements proxy.UserKicker.KickUser().
+func (h *Handler) KickUser(ctx context.Context, e string) int {
+	return h.sessions.CloseUser(e)
+}
+
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (h *Handler) GetUserByEmail(ctx context.Context, e string) *protocol.MemoryUser {
+	return h.validator.GetByEmail(e)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (h *Handler) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return h.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (h *Handler) GetUsersCount(ctx context.Context) int64 {
+	return h.validator.GetCount()
 }
 
 // Network implements proxy.Inbound.Network().
@@ -424,6 +453,18 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 		return err
 	}
 
+	if err := proxy.CheckUser(h.statsManager, request.User); err != nil {
+		log.Record(&log.AccessMessage{
+			From:   connection.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return newError("user rejected from ", connection.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer h.sessions.Add(request.User.Email, connection)()
+
 	if err := connection.SetReadDeadline(time.Time{}); err != nil {
 		newError("unable to set back read deadline").Base(err).AtWarning().WriteToLog(sid)
 	}
@@ -442,6 +483,8 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 	}
 
 	var rawConn syscall.RawConn
+	var visionConn *encoding.VisionConn
+	var trafficState *encoding.TrafficState
 
 	switch requestAddons.Flow {
 	case vless.XRO, vless.XRD:
@@ -469,6 +512,24 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 		} else {
 			return newError(account.ID.String() + " is not able to use " + requestAddons.Flow).AtWarning()
 		}
+	case vless.XRV:
+		if account.Flow == requestAddons.Flow {
+			switch request.Command {
+			case protocol.RequestCommandMux:
+				return newError(requestAddons.Flow + " doesn't support Mux").AtWarning()
+			case protocol.RequestCommandUDP:
+				return newError(requestAddons.Flow + " doesn't support UDP").AtWarning()
+			case protocol.RequestCommandTCP:
+				var err error
+				if visionConn, err = encoding.NewVisionConn(connection); err != nil {
+					return newError(`failed to use ` + requestAddons.Flow + `, maybe "security" is not "tls" or "reality"`).Base(err).AtWarning()
+				}
+				defer visionConn.CloseDirect()
+				trafficState = encoding.NewTrafficState(account.ID.Bytes())
+			}
+		} else {
+			return newError(account.ID.String() + " is not able to use " + requestAddons.Flow).AtWarning()
+		}
 	case "":
 	default:
 		return newError("unknown request flow " + requestAddons.Flow).AtWarning()
@@ -502,6 +563,9 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 
 		// default: clientReader := reader
 		clientReader := encoding.DecodeBodyAddons(reader, request, requestAddons)
+		if visionConn != nil {
+			clientReader = encoding.NewVisionReader(clientReader, trafficState, visionConn)
+		}
 
 		var err error
 
@@ -533,6 +597,9 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 
 		// default: clientWriter := bufferWriter
 		clientWriter := encoding.EncodeBodyAddons(bufferWriter, request, responseAddons)
+		if visionConn != nil {
+			clientWriter = encoding.NewVisionWriter(clientWriter, trafficState, visionConn)
+		}
 		{
 			multiBuffer, err := serverReader.ReadMultiBuffer()
 			if err != nil {
@@ -130,6 +130,8 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 
 	var rawConn syscall.RawConn
 	var sctx context.Context
+	var visionConn *encoding.VisionConn
+	var trafficState *encoding.TrafficState
 
 	allowUDP443 := false
 	switch requestAddons.Flow {
@@ -165,6 +167,28 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 				return newError(`failed to use ` + requestAddons.Flow + `, maybe "security" is not "xtls"`).AtWarning()
 			}
 		}
+	case vless.XRV + "-udp443":
+		allowUDP443 = true
+		requestAddons.Flow = requestAddons.Flow[:16]
+		fallthrough
+	case vless.XRV:
+		switch request.Command {
+		case protocol.RequestCommandMux:
+			return newError(requestAddons.Flow + " doesn't support Mux").AtWarning()
+		case protocol.RequestCommandUDP:
+			if !allowUDP443 && request.Port == 443 {
+				return newError(requestAddons.Flow + " stopped UDP/443").AtInfo()
+			}
+			requestAddons.Flow = ""
+		case protocol.RequestCommandTCP:
+			var err error
+			if visionConn, err = encoding.NewVisionConn(conn); err != nil {
+				return newError(`failed to use ` + requestAddons.Flow + `, maybe "security" is not "tls" or "reality"`).Base(err).AtWarning()
+			}
+			defer visionConn.CloseDirect()
+			trafficState = encoding.NewTrafficState(account.ID.Bytes())
+			sctx = ctx
+		}
 	default:
 		if _, ok := iConn.(*xtls.Conn); ok {
 			panic(`To avoid misunderstanding, you must fill in VLESS "flow" when using XTLS.`)
@@ -197,8 +221,16 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		if request.Command == protocol.RequestCommandMux && request.Port == 666 {
 			serverWriter = xudp.NewPacketWriter(serverWriter, target)
 		}
+		if visionConn != nil {
+			serverWriter = encoding.NewVisionWriter(serverWriter, trafficState, visionConn)
+		}
 		if err := buf.CopyOnceTimeout(clientReader, serverWriter, time.Millisecond*100); err != nil && err != buf.ErrNotTimeoutReader && err != buf.ErrReadTimeout {
 			return err // ...
+		} else if err == buf.ErrReadTimeout && visionConn != nil {
+			// Pads the request header.
+			if err := serverWriter.WriteMultiBuffer(buf.MultiBuffer{}); err != nil {
+				return newError("failed to write A request payload").Base(err).AtWarning()
+			}
 		}
 
 		// Flush; bufferWriter.WriteMultiBufer now is bufferWriter.writer.WriteMultiBuffer
@@ -232,7 +264,9 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 			serverReader = xudp.NewPacketReader(conn)
 		}
 
-		if rawConn != nil {
+		if visionConn != nil {
+			err = encoding.CopyVision(encoding.NewVisionReader(serverReader, trafficState, visionConn), clientWriter, timer, sctx)
+		} else if rawConn != nil {
 			var counter stats.Counter
 			if statConn != nil {
 				counter = statConn.ReadCounter
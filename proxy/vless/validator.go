@@ -27,6 +27,35 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 	return nil
 }
 
+// GetByEmail gets a VLESS user with a non-empty Email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(e string) *protocol.MemoryUser {
+	u, _ := v.email.Load(strings.ToLower(e))
+	if u != nil {
+		return u.(*protocol.MemoryUser)
+	}
+	return nil
+}
+
+// GetAll gets all VLESS users.
+func (v *Validator) GetAll() []*protocol.MemoryUser {
+	var users []*protocol.MemoryUser
+	v.users.Range(func(_, u interface{}) bool {
+		users = append(users, u.(*protocol.MemoryUser))
+		return true
+	})
+	return users
+}
+
+// GetCount gets the number of VLESS users.
+func (v *Validator) GetCount() int64 {
+	var count int64
+	v.users.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
 // Del a VLESS user with a non-empty Email.
 func (v *Validator) Del(e string) error {
 	if e == "" {
@@ -1,6 +1,8 @@
 package vmess
 
 import (
+	"github.com/golang/protobuf/proto"
+
 	"github.com/eagleql/xray-core/common/dice"
 	"github.com/eagleql/xray-core/common/protocol"
 	"github.com/eagleql/xray-core/common/uuid"
@@ -34,6 +36,17 @@ func (a *MemoryAccount) Equals(account protocol.Account) bool {
 	return a.ID.Equals(vmessAccount.ID)
 }
 
+// ToProto implements protocol.ProtoAccount.ToProto().
+func (a *MemoryAccount) ToProto() proto.Message {
+	return &Account{
+		Id:      a.ID.String(),
+		AlterId: uint32(len(a.AlterIDs)),
+		SecuritySettings: &protocol.SecurityConfig{
+			Type: a.Security,
+		},
+	}
+}
+
 // AsAccount implements protocol.Account.
 func (a *Account) AsAccount() (protocol.Account, error) {
 	id, err := uuid.ParseString(a.Id)
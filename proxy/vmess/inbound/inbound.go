@@ -24,6 +24,8 @@ import (
 	feature_inbound "github.com/eagleql/xray-core/features/inbound"
 	"github.com/eagleql/xray-core/features/policy"
 	"github.com/eagleql/xray-core/features/routing"
+	"github.com/eagleql/xray-core/features/stats"
+	"github.com/eagleql/xray-core/proxy"
 	"github.com/eagleql/xray-core/proxy/vmess"
 	"github.com/eagleql/xray-core/proxy/vmess/encoding"
 	"github.com/eagleql/xray-core/transport/internet"
@@ -90,6 +92,15 @@ func (v *userByEmail) Get(email string) (*protocol.MemoryUser, bool) {
 	return user, found
 }
 
+func (v *userByEmail) Find(email string) *protocol.MemoryUser {
+	email = strings.ToLower(email)
+
+	v.Lock()
+	defer v.Unlock()
+
+	return v.cache[email]
+}
+
 func (v *userByEmail) Remove(email string) bool {
 	email = strings.ToLower(email)
 
@@ -106,9 +117,11 @@ func (v *userByEmail) Remove(email string) bool {
 // Handler is an inbound connection handler that handles messages in VMess protocol.
 type Handler struct {
 	policyManager         policy.Manager
+	statsManager          stats.Manager
 	inboundHandlerManager feature_inbound.Manager
 	clients               *vmess.TimedUserValidator
 	usersByEmail          *userByEmail
+	sessions              *proxy.UserSessions
 	detours               *DetourConfig
 	sessionHistory        *encoding.SessionHistory
 	secure                bool
@@ -119,10 +132,12 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 	v := core.MustFromContext(ctx)
 	handler := &Handler{
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:          v.GetFeature(stats.ManagerType()).(stats.Manager),
 		inboundHandlerManager: v.GetFeature(feature_inbound.ManagerType()).(feature_inbound.Manager),
 		clients:               vmess.NewTimedUserValidator(protocol.DefaultIDHash),
 		detours:               config.Detour,
 		usersByEmail:          newUserByEmail(config.GetDefaultValue()),
+		sessions:              proxy.NewUserSessions(),
 		sessionHistory:        encoding.NewSessionHistory(),
 		secure:                config.SecureEncryptionOnly,
 	}
@@ -169,6 +184,21 @@ func (h *Handler) AddUser(ctx context.Context, user *protocol.MemoryUser) error
 	return h.clients.Add(user)
 }
 
+// GetUserByEmail implements proxy.UserLister.GetUserByEmail().
+func (h *Handler) GetUserByEmail(ctx context.Context, email string) *protocol.MemoryUser {
+	return h.usersByEmail.Find(email)
+}
+
+// GetUsers implements proxy.UserLister.GetUsers().
+func (h *Handler) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return h.clients.GetUsers()
+}
+
+// GetUsersCount implements proxy.UserLister.GetUsersCount().
+func (h *Handler) GetUsersCount(ctx context.Context) int64 {
+	return h.clients.GetCount()
+}
+
 func (h *Handler) RemoveUser(ctx context.Context, email string) error {
 	if email == "" {
 		return newError("Email must not be empty.")
@@ -177,9 +207,15 @@ func (h *Handler) RemoveUser(ctx context.Context, email string) error {
 		return newError("User ", email, " not found.")
 	}
 	h.clients.Remove(email)
+	h.sessions.CloseUser(email)
 	return nil
 }
 
+// KickUser implements proxy.UserKicker.KickUser().
+func (h *Handler) KickUser(ctx context.Context, email string) int {
+	return h.sessions.CloseUser(email)
+}
+
 func transferResponse(timer signal.ActivityUpdater, session *encoding.ServerSession, request *protocol.RequestHeader, response *protocol.ResponseHeader, input buf.Reader, output *buf.BufferedWriter) error {
 	session.EncodeResponseHeader(response, output)
 
@@ -262,6 +298,18 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 		return newError("client is using insecure encryption: ", request.Security)
 	}
 
+	if err := proxy.CheckUser(h.statsManager, request.User); err != nil {
+		log.Record(&log.AccessMessage{
+			From:   connection.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return newError("user rejected from ", connection.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer h.sessions.Add(request.User.Email, connection)()
+
 	if request.Command != protocol.RequestCommandMux {
 		ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 			From:   connection.RemoteAddr(),
@@ -212,6 +212,27 @@ func (v *TimedUserValidator) Remove(email string) bool {
 	return true
 }
 
+// GetUsers returns all users in the validator.
+func (v *TimedUserValidator) GetUsers() []*protocol.MemoryUser {
+	v.RLock()
+	defer v.RUnlock()
+
+	users := make([]*protocol.MemoryUser, 0, len(v.users))
+	for _, u := range v.users {
+		user := u.user
+		users = append(users, &user)
+	}
+	return users
+}
+
+// GetCount returns the number of users in the validator.
+func (v *TimedUserValidator) GetCount() int64 {
+	v.RLock()
+	defer v.RUnlock()
+
+	return int64(len(v.users))
+}
+
 // Close implements common.Closable.
 func (v *TimedUserValidator) Close() error {
 	return v.task.Close()
@@ -0,0 +1,176 @@
+package wireguard
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/dice"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/session"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/core"
+	"github.com/eagleql/xray-core/features/dns"
+	"github.com/eagleql/xray-core/features/policy"
+	"github.com/eagleql/xray-core/transport"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		if err := core.RequireFeatures(ctx, func(pm policy.Manager, d dns.Client) error {
+			return h.Init(config.(*Config), pm, d)
+		}); err != nil {
+			return nil, err
+		}
+		return h, nil
+	}))
+}
+
+// Handler is an outbound connection handler that sends traffic through a WireGuard tunnel.
+type Handler struct {
+	config        *Config
+	policyManager policy.Manager
+	dns           dns.Client
+
+	access sync.Mutex
+	device *Device
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager, d dns.Client) error {
+	h.config = config
+	h.policyManager = pm
+	h.dns = d
+
+	return nil
+}
+
+// getDevice returns the WireGuard device, creating it with dialer on first use.
+func (h *Handler) getDevice(dialer internet.Dialer) (*Device, error) {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.device != nil {
+		return h.device, nil
+	}
+	device, err := NewDevice(h.config, func(dest net.Destination) (net.Conn, error) {
+		return dialer.Dial(context.Background(), dest)
+	})
+	if err != nil {
+		return nil, newError("failed to create WireGuard device").Base(err)
+	}
+	h.device = device
+	return device, nil
+}
+
+// Close implements common.Closable.
+func (h *Handler) Close() error {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.device == nil {
+		return nil
+	}
+	err := h.device.Close()
+	h.device = nil
+	return err
+}
+
+func (h *Handler) resolveIP(ctx context.Context, domain string, device *Device) net.Address {
+	option := dns.IPOption{
+		IPv4Enable: h.config.DomainStrategy != Config_USE_IP6,
+		IPv6Enable: h.config.DomainStrategy != Config_USE_IP4,
+	}
+	ips, err := h.dns.LookupIP(domain, option)
+	if err != nil {
+		newError("failed to get IP address for domain ", domain).Base(err).WriteToLog(session.ExportIDToError(ctx))
+	}
+
+	// Only addresses in a family the tunnel has an address of are reachable.
+	reachable := ips[:0]
+	for _, ip := range ips {
+		if _, err := device.stack.localAddress(ip); err == nil {
+			reachable = append(reachable, ip)
+		}
+	}
+	if len(reachable) == 0 {
+		return nil
+	}
+	return net.IPAddress(reachable[dice.Roll(len(reachable))])
+}
+
+// Process implements proxy.Outbound.
+func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("target not specified.")
+	}
+	destination := outbound.Target
+
+	device, err := h.getDevice(dialer)
+	if err != nil {
+		return err
+	}
+	if destination.Address.Family().IsDomain() {
+		ip := h.resolveIP(ctx, destination.Address.Domain(), device)
+		if ip == nil {
+			return newError("failed to resolve ", destination.Address.Domain(), " for the tunnel")
+		}
+		destination.Address = ip
+	}
+	newError("tunneling request to ", destination).WriteToLog(session.ExportIDToError(ctx))
+
+	var conn net.Conn
+	if destination.Network == net.Network_TCP {
+		conn, err = device.stack.dialTCP(ctx, destination)
+	} else {
+		conn, err = device.stack.dialUDP(destination)
+	}
+	if err != nil {
+		return newError("failed to open connection to ", destination).Base(err)
+	}
+	defer conn.Close()
+
+	plcy := h.policyManager.ForLevel(h.config.UserLevel)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
+
+	requestDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+
+		var writer buf.Writer
+		if destination.Network == net.Network_TCP {
+			writer = buf.NewWriter(conn)
+		} else {
+			writer = &buf.SequentialWriter{Writer: conn}
+		}
+		if err := buf.Copy(link.Reader, writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to process request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
+
+		var reader buf.Reader
+		if destination.Network == net.Network_TCP {
+			reader = buf.NewReader(conn)
+		} else {
+			reader = &buf.PacketReader{Reader: conn}
+		}
+		if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to process response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, requestDone, task.OnSuccess(responseDone, task.Close(link.Writer))); err != nil {
+		return newError("connection ends").Base(err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package wireguard
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/signal/done"
+)
+
+const (
+	rekeyAfterMessages          = 1 << 60
+	rejectAfterMessages         = 1<<64 - 1<<13 - 1
+	rekeyAfterTime              = 120 * time.Second
+	rejectAfterTime             = 180 * time.Second
+	rekeyAttemptTime            = 90 * time.Second
+	rekeyTimeout                = 5 * time.Second
+	keepaliveTimeout            = 10 * time.Second
+	cookieRefreshTime           = 120 * time.Second
+	handshakeInitiationInterval = time.Second / 50
+
+	defaultMTU       = 1420
+	maxQueuedPackets = 1024
+)
+
+// Device is a userspace WireGuard interface. IP packets from its network stack are sent to the peer whose allowed IPs
+// contain their destination, and packets from peers are delivered to the stack.
+type Device struct {
+	privateKey [keySize]byte
+	publicKey  [keySize]byte
+	mac1Key    [blake2s.Size]byte
+	reserved   []byte
+	mtu        int
+	peers      []*peer
+	indexes    indexTable
+	stack      *netStack
+	dial       func(net.Destination) (net.Conn, error)
+	done       *done.Instance
+}
+
+// NewDevice creates a Device from config. dial is used to open a UDP connection to the endpoint of each peer.
+func NewDevice(config *Config, dial func(net.Destination) (net.Conn, error)) (*Device, error) {
+	if len(config.SecretKey) != keySize {
+		return nil, newError("invalid secret key")
+	}
+	if len(config.Reserved) != 0 && len(config.Reserved) != 3 {
+		return nil, newError("reserved must be 3 bytes")
+	}
+	if len(config.Peers) == 0 {
+		return nil, newError("no peer")
+	}
+
+	d := &Device{
+		reserved: config.Reserved,
+		mtu:      int(config.Mtu),
+		dial:     dial,
+		done:     done.New(),
+	}
+	if d.mtu == 0 {
+		d.mtu = defaultMTU
+	}
+	copy(d.privateKey[:], config.SecretKey)
+	d.publicKey = publicKey(&d.privateKey)
+	d.mac1Key = labelHash(labelMAC1, d.publicKey[:])
+
+	addresses, err := parseAddresses(config.Address)
+	if err != nil {
+		return nil, err
+	}
+	d.stack = newNetStack(addresses, d.mtu, d.writePacket)
+
+	for _, pc := range config.Peers {
+		p, err := newPeer(d, pc)
+		if err != nil {
+			return nil, err
+		}
+		d.peers = append(d.peers, p)
+	}
+	return d, nil
+}
+
+// Close implements common.Closable.
+func (d *Device) Close() error {
+	if err := d.done.Close(); err != nil {
+		return err
+	}
+	for _, p := range d.peers {
+		p.close()
+	}
+	d.stack.close()
+	return nil
+}
+
+func (d *Device) lookupPeer(pk []byte) *peer {
+	for _, p := range d.peers {
+		if bytes.Equal(p.publicKey[:], pk) {
+			return p
+		}
+	}
+	return nil
+}
+
+// route returns the peer with the longest allowed IP prefix containing ip.
+func (d *Device) route(ip net.IP) *peer {
+	var result *peer
+	longest := -1
+	for _, p := range d.peers {
+		for _, ipNet := range p.allowedIPs {
+			if !ipNet.Contains(ip) {
+				continue
+			}
+			if ones, _ := ipNet.Mask.Size(); ones > longest {
+				result, longest = p, ones
+			}
+		}
+	}
+	return result
+}
+
+// writePacket sends an IP packet from the network stack to the peer responsible for its destination.
+func (d *Device) writePacket(packet []byte) error {
+	if d.done.Done() {
+		return newError("device closed")
+	}
+	p := d.route(destinationIP(packet))
+	if p == nil {
+		return newError("no route to ", destinationIP(packet))
+	}
+	return p.send(packet)
+}
+
+// receive handles a message from a peer.
+func (d *Device) receive(msg []byte) {
+	if len(msg) < 4 {
+		return
+	}
+	// The reserved bytes may be set by the peer, but must be zero in the protocol.
+	msg[1], msg[2], msg[3] = 0, 0, 0
+
+	switch msg[0] {
+	case messageInitiationType:
+		if len(msg) != messageInitiationSize || !d.checkMAC1(msg) {
+			return
+		}
+		p, err := d.consumeInitiation(msg)
+		if err != nil {
+			newError("failed to handle handshake initiation").Base(err).AtDebug().WriteToLog()
+			return
+		}
+		p.handleInitiation()
+	case messageResponseType:
+		if len(msg) != messageResponseSize || !d.checkMAC1(msg) {
+			return
+		}
+		p, err := d.consumeResponse(msg)
+		if err != nil {
+			newError("failed to handle handshake response").Base(err).AtDebug().WriteToLog()
+			return
+		}
+		p.handleResponse()
+	case messageCookieReplyType:
+		if len(msg) != messageCookieReplySize {
+			return
+		}
+		d.consumeCookieReply(msg)
+	case messageTransportType:
+		if len(msg) < messageKeepaliveSize {
+			return
+		}
+		d.receiveTransport(msg)
+	}
+}
+
+func (d *Device) receiveTransport(msg []byte) {
+	p, kp := d.indexes.lookup(binary.LittleEndian.Uint32(msg[4:]))
+	if kp == nil || time.Since(kp.created) > rejectAfterTime {
+		return
+	}
+	counter := binary.LittleEndian.Uint64(msg[8:])
+	var nonce [12]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	packet, err := kp.receive.Open(msg[messageTransportHeaderSize:messageTransportHeaderSize], nonce[:], msg[messageTransportHeaderSize:], nil)
+	if err != nil {
+		return
+	}
+	kp.access.Lock()
+	valid := kp.replay.validate(counter, rejectAfterMessages)
+	kp.access.Unlock()
+	if !valid {
+		return
+	}
+
+	p.receivedTransport(kp, len(packet) > 0)
+	if len(packet) == 0 {
+		return
+	}
+	if !p.allows(sourceIP(packet)) {
+		return
+	}
+	d.stack.deliver(packet)
+}
+
+type indexEntry struct {
+	peer    *peer
+	keypair *keypair
+}
+
+// indexTable maps the local indexes of handshakes and sessions to their peers.
+type indexTable struct {
+	access  sync.RWMutex
+	entries map[uint32]indexEntry
+}
+
+func (t *indexTable) add(p *peer) uint32 {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	if t.entries == nil {
+		t.entries = make(map[uint32]indexEntry)
+	}
+	var b [4]byte
+	for {
+		rand.Read(b[:])
+		index := binary.LittleEndian.Uint32(b[:])
+		if _, found := t.entries[index]; index != 0 && !found {
+			t.entries[index] = indexEntry{peer: p}
+			return index
+		}
+	}
+}
+
+func (t *indexTable) setKeypair(index uint32, kp *keypair) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	if entry, found := t.entries[index]; found {
+		entry.keypair = kp
+		t.entries[index] = entry
+	}
+}
+
+func (t *indexTable) lookup(index uint32) (*peer, *keypair) {
+	t.access.RLock()
+	defer t.access.RUnlock()
+
+	entry := t.entries[index]
+	return entry.peer, entry.keypair
+}
+
+func (t *indexTable) remove(index uint32) {
+	if index == 0 {
+		return
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	delete(t.entries, index)
+}
+
+const (
+	replayBlockBits  = 64
+	replayRingBlocks = 1 << 5
+	replayWindowSize = (replayRingBlocks - 1) * replayBlockBits
+)
+
+// replayFilter rejects counters that were seen or are too old, with a sliding window as in RFC 6479.
+type replayFilter struct {
+	last uint64
+	ring [replayRingBlocks]uint64
+}
+
+func (f *replayFilter) validate(counter uint64, limit uint64) bool {
+	if counter >= limit {
+		return false
+	}
+	index := counter / replayBlockBits
+	if counter > f.last {
+		current := f.last / replayBlockBits
+		diff := index - current
+		if diff > replayRingBlocks {
+			diff = replayRingBlocks
+		}
+		for i := current + 1; i <= current+diff; i++ {
+			f.ring[i%replayRingBlocks] = 0
+		}
+		f.last = counter
+	} else if f.last-counter > replayWindowSize {
+		return false
+	}
+	index %= replayRingBlocks
+	bit := uint64(1) << (counter % replayBlockBits)
+	if f.ring[index]&bit != 0 {
+		return false
+	}
+	f.ring[index] |= bit
+	return true
+}
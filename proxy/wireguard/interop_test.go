@@ -0,0 +1,148 @@
+package wireguard
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// testTUN is a TUN device of wireguard-go whose packets are exchanged through channels.
+type testTUN struct {
+	received chan []byte
+	sent     chan []byte
+	events   chan tun.Event
+}
+
+func newTestTUN() *testTUN {
+	t := &testTUN{
+		received: make(chan []byte, 16),
+		sent:     make(chan []byte, 16),
+		events:   make(chan tun.Event, 1),
+	}
+	t.events <- tun.EventUp
+	return t
+}
+
+func (t *testTUN) File() *os.File { return nil }
+
+// Read returns the packets to be sent to the peers.
+func (t *testTUN) Read(b []byte, offset int) (int, error) {
+	packet, ok := <-t.sent
+	if !ok {
+		return 0, os.ErrClosed
+	}
+	return copy(b[offset:], packet), nil
+}
+
+// Write passes the packets received from the peers to received.
+func (t *testTUN) Write(b []byte, offset int) (int, error) {
+	select {
+	case t.received <- append([]byte(nil), b[offset:]...):
+	default:
+	}
+	return len(b) - offset, nil
+}
+
+func (t *testTUN) Flush() error           { return nil }
+func (t *testTUN) MTU() (int, error)      { return defaultMTU, nil }
+func (t *testTUN) Name() (string, error)  { return "test", nil }
+func (t *testTUN) Events() chan tun.Event { return t.events }
+func (t *testTUN) Close() error           { close(t.sent); close(t.events); return nil }
+
+// udpReply returns an IPv4 UDP packet from the destination to the source of the given one, with the same payload.
+func udpReply(packet []byte) []byte {
+	headerSize := int(packet[0]&0x0f) * 4
+	src, dst := net.IP(packet[12:16]), net.IP(packet[16:20])
+	datagram := packet[headerSize:binary.BigEndian.Uint16(packet[2:])]
+
+	reply := make([]byte, ipv4HeaderSize+len(datagram))
+	reply[0] = 0x45
+	binary.BigEndian.PutUint16(reply[2:], uint16(len(reply)))
+	reply[8] = defaultTTL
+	reply[9] = protocolUDP
+	copy(reply[12:16], dst)
+	copy(reply[16:20], src)
+	binary.BigEndian.PutUint16(reply[10:], checksum(reply[:ipv4HeaderSize], 0))
+	udp := reply[ipv4HeaderSize:]
+	copy(udp, datagram)
+	copy(udp[0:2], datagram[2:4])
+	copy(udp[2:4], datagram[0:2])
+	binary.BigEndian.PutUint16(udp[6:], 0)
+	binary.BigEndian.PutUint16(udp[6:], checksum(udp, pseudoHeaderSum(dst, src, protocolUDP, len(udp))))
+	return reply
+}
+
+// TestInterop checks a tunnel with wireguard-go as the peer.
+func TestInterop(t *testing.T) {
+	skA, err := newPrivateKey()
+	common.Must(err)
+	skB, err := newPrivateKey()
+	common.Must(err)
+	pkA, pkB := publicKey(&skA), publicKey(&skB)
+	psk := make([]byte, keySize)
+	common.Must2(rand.Read(psk))
+	portB := pickUDPPort()
+
+	tunB := newTestTUN()
+	deviceB := device.NewDevice(tunB, device.NewLogger(device.LogLevelError, "wireguard-go: "))
+	defer deviceB.Close()
+	if err := deviceB.IpcSetOperation(bufio.NewReader(strings.NewReader(strings.Join([]string{
+		"private_key=" + hex.EncodeToString(skB[:]),
+		"listen_port=" + portB.String(),
+		"public_key=" + hex.EncodeToString(pkA[:]),
+		"preshared_key=" + hex.EncodeToString(psk),
+		"allowed_ip=10.0.0.1/32",
+		"", "",
+	}, "\n")))); err != nil {
+		t.Fatal(err)
+	}
+	deviceB.Up()
+
+	deviceA, err := NewDevice(&Config{
+		SecretKey: skA[:],
+		Address:   []string{"10.0.0.1"},
+		Peers: []*PeerConfig{{
+			PublicKey:    pkB[:],
+			PreSharedKey: psk,
+			Endpoint:     "127.0.0.1:" + portB.String(),
+		}},
+	}, func(dest net.Destination) (net.Conn, error) {
+		return net.Dial("udp", dest.NetAddr())
+	})
+	common.Must(err)
+	defer deviceA.Close()
+
+	conn, err := deviceA.stack.dialUDP(net.UDPDestination(net.ParseAddress("10.0.0.2"), 53))
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write([]byte("hello")))
+
+	select {
+	case packet := <-tunB.received:
+		if string(packet[ipv4HeaderSize+8:]) != "hello" {
+			t.Fatal("unexpected packet ", packet)
+		}
+		tunB.sent <- udpReply(packet)
+	case <-time.After(10 * time.Second):
+		t.Fatal("expect packet to be received by wireguard-go")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	b := make([]byte, 16)
+	n, err := conn.Read(b)
+	common.Must(err)
+	if string(b[:n]) != "hello" {
+		t.Error("expect hello from wireguard-go, but got ", string(b[:n]))
+	}
+}
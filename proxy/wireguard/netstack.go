@@ -0,0 +1,373 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+)
+
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	defaultTTL     = 64
+)
+
+// connKey identifies a TCP or UDP endpoint pair in the network stack. A listener has a zero remote address and port.
+type connKey struct {
+	localIP    [16]byte
+	remoteIP   [16]byte
+	localPort  uint16
+	remotePort uint16
+}
+
+func newConnKey(localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16) connKey {
+	key := connKey{localPort: localPort, remotePort: remotePort}
+	copy(key.localIP[:], localIP.To16())
+	if remoteIP != nil {
+		copy(key.remoteIP[:], remoteIP.To16())
+	}
+	return key
+}
+
+// netStack is a minimal IPv4 and IPv6 network stack with TCP and UDP, which exchanges IP packets with a Device.
+type netStack struct {
+	addresses []net.IP
+	mtu       int
+	output    func([]byte) error
+
+	access       sync.Mutex
+	closed       bool
+	ipID         uint16
+	tcpConns     map[connKey]*tcpConn
+	tcpListeners map[uint16]*tcpListener
+	udpConns     map[connKey]*udpConn
+}
+
+func newNetStack(addresses []net.IP, mtu int, output func([]byte) error) *netStack {
+	return &netStack{
+		addresses:    addresses,
+		mtu:          mtu,
+		output:       output,
+		tcpConns:     make(map[connKey]*tcpConn),
+		tcpListeners: make(map[uint16]*tcpListener),
+		udpConns:     make(map[connKey]*udpConn),
+	}
+}
+
+func parseAddresses(addresses []string) ([]net.IP, error) {
+	if len(addresses) == 0 {
+		return nil, newError("no address")
+	}
+	ips := make([]net.IP, 0, len(addresses))
+	for _, s := range addresses {
+		ipNet, err := parseCIDR(s)
+		if err != nil {
+			return nil, newError("invalid address: ", s).Base(err)
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips, nil
+}
+
+// parseCIDR parses an IP address with an optional prefix length.
+func parseCIDR(s string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(s)
+	if err == nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		return &net.IPNet{IP: ip, Mask: ipNet.Mask}, nil
+	}
+	ip = net.ParseIP(s)
+	if ip == nil {
+		return nil, err
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// localAddress returns the address of the stack in the same family as remote.
+func (s *netStack) localAddress(remote net.IP) (net.IP, error) {
+	isIPv4 := remote.To4() != nil
+	for _, ip := range s.addresses {
+		if (ip.To4() != nil) == isIPv4 {
+			return ip, nil
+		}
+	}
+	return nil, newError("no local address for ", remote)
+}
+
+func (s *netStack) isLocal(ip net.IP) bool {
+	for _, local := range s.addresses {
+		if local.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomUint32 returns a random number that can't be predicted by an attacker off the path, as is required of the
+// initial sequence numbers and the ephemeral ports, see RFC 6528 and RFC 6056.
+func randomUint32() uint32 {
+	var b [4]byte
+	common.Must2(rand.Read(b[:]))
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// allocatePort returns an unused ephemeral port toward remote. It must be called with s.access held.
+func (s *netStack) allocatePort(localIP net.IP, remoteIP net.IP, remotePort uint16, udp bool) uint16 {
+	for {
+		port := uint16(32768 + randomUint32()%28232)
+		key := newConnKey(localIP, port, remoteIP, remotePort)
+		if udp {
+			if _, found := s.udpConns[key]; !found {
+				return port
+			}
+		} else if _, found := s.tcpConns[key]; !found {
+			return port
+		}
+	}
+}
+
+func (s *netStack) close() {
+	s.access.Lock()
+	s.closed = true
+	tcpConns := s.tcpConns
+	tcpListeners := s.tcpListeners
+	udpConns := s.udpConns
+	s.tcpConns = make(map[connKey]*tcpConn)
+	s.tcpListeners = make(map[uint16]*tcpListener)
+	s.udpConns = make(map[connKey]*udpConn)
+	s.access.Unlock()
+
+	for _, c := range tcpConns {
+		c.abort(newError("network stack closed"))
+	}
+	for _, l := range tcpListeners {
+		l.Close()
+	}
+	for _, c := range udpConns {
+		c.Close()
+	}
+}
+
+// writeIP sends an IP packet with the given transport payload. The checksum of the payload must be filled already.
+func (s *netStack) writeIP(src, dst net.IP, protocol byte, payload []byte) error {
+	var packet []byte
+	if src4 := src.To4(); src4 != nil {
+		packet = make([]byte, ipv4HeaderSize+len(payload))
+		packet[0] = 0x45
+		binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+		s.access.Lock()
+		s.ipID++
+		binary.BigEndian.PutUint16(packet[4:], s.ipID)
+		s.access.Unlock()
+		packet[6] = 0x40 // Don't fragment
+		packet[8] = defaultTTL
+		packet[9] = protocol
+		copy(packet[12:16], src4)
+		copy(packet[16:20], dst.To4())
+		binary.BigEndian.PutUint16(packet[10:], checksum(packet[:ipv4HeaderSize], 0))
+		copy(packet[ipv4HeaderSize:], payload)
+	} else {
+		packet = make([]byte, ipv6HeaderSize+len(payload))
+		packet[0] = 0x60
+		binary.BigEndian.PutUint16(packet[4:], uint16(len(payload)))
+		packet[6] = protocol
+		packet[7] = defaultTTL
+		copy(packet[8:24], src.To16())
+		copy(packet[24:40], dst.To16())
+		copy(packet[ipv6HeaderSize:], payload)
+	}
+	return s.output(packet)
+}
+
+// deliver handles an IP packet received from the device.
+func (s *netStack) deliver(packet []byte) {
+	var src, dst net.IP
+	var protocol byte
+	var payload []byte
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < ipv4HeaderSize {
+			return
+		}
+		headerSize := int(packet[0]&0x0f) * 4
+		totalSize := int(binary.BigEndian.Uint16(packet[2:]))
+		if headerSize < ipv4HeaderSize || totalSize < headerSize || totalSize > len(packet) {
+			return
+		}
+		if binary.BigEndian.Uint16(packet[6:])&0x3fff != 0 {
+			// Fragments are not supported.
+			return
+		}
+		src, dst = net.IP(packet[12:16]), net.IP(packet[16:20])
+		protocol = packet[9]
+		payload = packet[headerSize:totalSize]
+	case 6:
+		if len(packet) < ipv6HeaderSize {
+			return
+		}
+		payloadSize := int(binary.BigEndian.Uint16(packet[4:]))
+		if ipv6HeaderSize+payloadSize > len(packet) {
+			return
+		}
+		src, dst = net.IP(packet[8:24]), net.IP(packet[24:40])
+		protocol = packet[6]
+		payload = packet[ipv6HeaderSize : ipv6HeaderSize+payloadSize]
+	default:
+		return
+	}
+	if !s.isLocal(dst) {
+		return
+	}
+
+	switch protocol {
+	case protocolTCP:
+		s.deliverTCP(src, dst, payload)
+	case protocolUDP:
+		s.deliverUDP(src, dst, payload)
+	}
+}
+
+func sourceIP(packet []byte) net.IP {
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) >= ipv4HeaderSize {
+			return net.IP(packet[12:16])
+		}
+	case 6:
+		if len(packet) >= ipv6HeaderSize {
+			return net.IP(packet[8:24])
+		}
+	}
+	return nil
+}
+
+func destinationIP(packet []byte) net.IP {
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) >= ipv4HeaderSize {
+			return net.IP(packet[16:20])
+		}
+	case 6:
+		if len(packet) >= ipv6HeaderSize {
+			return net.IP(packet[24:40])
+		}
+	}
+	return nil
+}
+
+// checksum returns the Internet checksum of data, starting from the partial sum initial.
+func checksum(data []byte, initial uint32) uint16 {
+	sum := initial
+	for len(data) >= 2 {
+		sum += uint32(binary.BigEndian.Uint16(data))
+		data = data[2:]
+	}
+	if len(data) > 0 {
+		sum += uint32(data[0]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// pseudoHeaderSum returns the partial sum of the pseudo header for the checksum of TCP and UDP.
+func pseudoHeaderSum(src, dst net.IP, protocol byte, length int) uint32 {
+	if src4 := src.To4(); src4 != nil {
+		src, dst = src4, dst.To4()
+	} else {
+		src, dst = src.To16(), dst.To16()
+	}
+	var sum uint32
+	for i := 0; i < len(src); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(src[i:]))
+		sum += uint32(binary.BigEndian.Uint16(dst[i:]))
+	}
+	sum += uint32(protocol)
+	sum += uint32(length)
+	return sum
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// deadline is a deadline of a read or write, which closes its channel when it expires.
+type deadline struct {
+	access sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{cancel: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel
+	}
+	d.timer = nil
+
+	closed := isClosedChan(d.cancel)
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() {
+			close(cancel)
+		})
+		return
+	}
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+func (d *deadline) wait() chan struct{} {
+	d.access.Lock()
+	defer d.access.Unlock()
+
+	return d.cancel
+}
+
+func isClosedChan(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// notify wakes up a goroutine waiting on c, if any.
+func notify(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
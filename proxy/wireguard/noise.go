@@ -0,0 +1,356 @@
+package wireguard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	messageInitiationType  = 1
+	messageResponseType    = 2
+	messageCookieReplyType = 3
+	messageTransportType   = 4
+
+	messageInitiationSize      = 148
+	messageResponseSize        = 92
+	messageCookieReplySize     = 64
+	messageTransportHeaderSize = 16
+	messageKeepaliveSize       = messageTransportHeaderSize + aeadOverhead
+
+	noiseConstruction = "Noise_IKpsk2_25519_ChaChaPoly_BLAKE2s"
+	noiseIdentifier   = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+	labelMAC1         = "mac1----"
+	labelCookie       = "cookie--"
+
+	keySize       = 32
+	aeadOverhead  = 16
+	macSize       = 16
+	timestampSize = 12
+	// tai64nBase is the TAI64 label of the Unix epoch.
+	tai64nBase = 0x400000000000000a
+)
+
+var (
+	initialChainKey [blake2s.Size]byte
+	initialHash     [blake2s.Size]byte
+	zeroNonce       [chacha20poly1305.NonceSize]byte
+)
+
+func init() {
+	initialChainKey = blake2s.Sum256([]byte(noiseConstruction))
+	mixHash(&initialHash, &initialChainKey, []byte(noiseIdentifier))
+}
+
+type handshakeState int
+
+const (
+	handshakeZeroed handshakeState = iota
+	handshakeInitiationCreated
+	handshakeInitiationConsumed
+	handshakeResponseCreated
+	handshakeResponseConsumed
+)
+
+// handshake is the state of a Noise_IKpsk2 handshake with a peer.
+type handshake struct {
+	state           handshakeState
+	hash            [blake2s.Size]byte
+	chainKey        [blake2s.Size]byte
+	localEphemeral  [keySize]byte
+	localIndex      uint32
+	remoteIndex     uint32
+	remoteEphemeral [keySize]byte
+	// lastTimestamp is the timestamp of the last initiation consumed, to reject replayed initiations.
+	lastTimestamp [timestampSize]byte
+	// lastInitiation is when the last initiation was consumed, to limit the rate of initiations.
+	lastInitiation time.Time
+}
+
+func newPrivateKey() (sk [keySize]byte, err error) {
+	if _, err = rand.Read(sk[:]); err != nil {
+		return
+	}
+	sk[0] &= 248
+	sk[31] = (sk[31] & 127) | 64
+	return
+}
+
+func publicKey(sk *[keySize]byte) (pk [keySize]byte) {
+	curve25519.ScalarBaseMult(&pk, sk)
+	return
+}
+
+func sharedSecret(sk *[keySize]byte, pk []byte) (ss [keySize]byte, err error) {
+	b, err := curve25519.X25519(sk[:], pk)
+	if err != nil {
+		return ss, newError("invalid public key").Base(err)
+	}
+	copy(ss[:], b)
+	return ss, nil
+}
+
+func newBlake2s() hash.Hash {
+	h, _ := blake2s.New256(nil)
+	return h
+}
+
+func hmacBlake2s(sum *[blake2s.Size]byte, key []byte, input ...[]byte) {
+	mac := hmac.New(newBlake2s, key)
+	for _, in := range input {
+		mac.Write(in)
+	}
+	mac.Sum(sum[:0])
+}
+
+// kdf derives len(outputs) keys from key and input with HKDF over HMAC-BLAKE2s.
+func kdf(key []byte, input []byte, outputs ...*[blake2s.Size]byte) {
+	var prk, t [blake2s.Size]byte
+	hmacBlake2s(&prk, key, input)
+	var previous []byte
+	for i, output := range outputs {
+		hmacBlake2s(&t, prk[:], previous, []byte{byte(i + 1)})
+		*output = t
+		previous = t[:]
+	}
+}
+
+func mixHash(dst *[blake2s.Size]byte, h *[blake2s.Size]byte, data []byte) {
+	hash := newBlake2s()
+	hash.Write(h[:])
+	hash.Write(data)
+	hash.Sum(dst[:0])
+}
+
+func mixKey(dst *[blake2s.Size]byte, c *[blake2s.Size]byte, data []byte) {
+	kdf(c[:], data, dst)
+}
+
+func mac(key []byte, data []byte) (sum [macSize]byte) {
+	h, _ := blake2s.New128(key)
+	h.Write(data)
+	h.Sum(sum[:0])
+	return
+}
+
+func labelHash(label string, pk []byte) [blake2s.Size]byte {
+	hash := newBlake2s()
+	hash.Write([]byte(label))
+	hash.Write(pk)
+	var sum [blake2s.Size]byte
+	hash.Sum(sum[:0])
+	return sum
+}
+
+func tai64n(t time.Time) (ts [timestampSize]byte) {
+	binary.BigEndian.PutUint64(ts[:], tai64nBase+uint64(t.Unix()))
+	binary.BigEndian.PutUint32(ts[8:], uint32(t.Nanosecond()))
+	return
+}
+
+func seal(key *[blake2s.Size]byte, dst []byte, plaintext []byte, ad []byte) {
+	aead, _ := chacha20poly1305.New(key[:])
+	aead.Seal(dst[:0], zeroNonce[:], plaintext, ad)
+}
+
+func open(key *[blake2s.Size]byte, ciphertext []byte, ad []byte) ([]byte, error) {
+	aead, _ := chacha20poly1305.New(key[:])
+	return aead.Open(nil, zeroNonce[:], ciphertext, ad)
+}
+
+// createInitiation writes the first handshake message to the peer into msg.
+func (d *Device) createInitiation(p *peer, msg []byte) error {
+	hs := &p.handshake
+	ephemeral, err := newPrivateKey()
+	if err != nil {
+		return err
+	}
+	hs.localEphemeral = ephemeral
+	d.indexes.remove(hs.localIndex)
+	hs.localIndex = d.indexes.add(p)
+	hs.chainKey = initialChainKey
+	mixHash(&hs.hash, &initialHash, p.publicKey[:])
+
+	msg[0] = messageInitiationType
+	binary.LittleEndian.PutUint32(msg[4:], hs.localIndex)
+	ephemeralPublic := publicKey(&hs.localEphemeral)
+	copy(msg[8:40], ephemeralPublic[:])
+	mixKey(&hs.chainKey, &hs.chainKey, msg[8:40])
+	mixHash(&hs.hash, &hs.hash, msg[8:40])
+
+	ss, err := sharedSecret(&hs.localEphemeral, p.publicKey[:])
+	if err != nil {
+		return err
+	}
+	var key [blake2s.Size]byte
+	kdf(hs.chainKey[:], ss[:], &hs.chainKey, &key)
+	seal(&key, msg[40:88], d.publicKey[:], hs.hash[:])
+	mixHash(&hs.hash, &hs.hash, msg[40:88])
+
+	kdf(hs.chainKey[:], p.staticStatic[:], &hs.chainKey, &key)
+	timestamp := tai64n(time.Now())
+	seal(&key, msg[88:116], timestamp[:], hs.hash[:])
+	mixHash(&hs.hash, &hs.hash, msg[88:116])
+
+	hs.state = handshakeInitiationCreated
+	p.addMACs(msg[:messageInitiationSize])
+	return nil
+}
+
+// consumeInitiation processes the first handshake message from a peer, and returns the peer.
+func (d *Device) consumeInitiation(msg []byte) (*peer, error) {
+	var hash, chainKey, key [blake2s.Size]byte
+	chainKey = initialChainKey
+	mixHash(&hash, &initialHash, d.publicKey[:])
+
+	ephemeral := msg[8:40]
+	mixKey(&chainKey, &chainKey, ephemeral)
+	mixHash(&hash, &hash, ephemeral)
+
+	ss, err := sharedSecret(&d.privateKey, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	kdf(chainKey[:], ss[:], &chainKey, &key)
+	static, err := open(&key, msg[40:88], hash[:])
+	if err != nil {
+		return nil, newError("failed to decrypt static key of initiation").Base(err)
+	}
+	mixHash(&hash, &hash, msg[40:88])
+
+	p := d.lookupPeer(static)
+	if p == nil {
+		return nil, newError("initiation from unknown peer")
+	}
+
+	kdf(chainKey[:], p.staticStatic[:], &chainKey, &key)
+	timestamp, err := open(&key, msg[88:116], hash[:])
+	if err != nil {
+		return nil, newError("failed to decrypt timestamp of initiation").Base(err)
+	}
+	mixHash(&hash, &hash, msg[88:116])
+
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	hs := &p.handshake
+	if bytes.Compare(timestamp, hs.lastTimestamp[:]) <= 0 {
+		return nil, newError("replayed initiation")
+	}
+	if time.Since(hs.lastInitiation) < handshakeInitiationInterval {
+		return nil, newError("initiation flood")
+	}
+	d.indexes.remove(hs.localIndex)
+	hs.hash = hash
+	hs.chainKey = chainKey
+	hs.remoteIndex = binary.LittleEndian.Uint32(msg[4:])
+	copy(hs.remoteEphemeral[:], ephemeral)
+	copy(hs.lastTimestamp[:], timestamp)
+	hs.lastInitiation = time.Now()
+	hs.state = handshakeInitiationConsumed
+	return p, nil
+}
+
+// createResponse writes the second handshake message to the peer into msg.
+func (d *Device) createResponse(p *peer, msg []byte) error {
+	hs := &p.handshake
+	if hs.state != handshakeInitiationConsumed {
+		return newError("handshake initiation must be consumed first")
+	}
+	ephemeral, err := newPrivateKey()
+	if err != nil {
+		return err
+	}
+	hs.localEphemeral = ephemeral
+	hs.localIndex = d.indexes.add(p)
+
+	msg[0] = messageResponseType
+	binary.LittleEndian.PutUint32(msg[4:], hs.localIndex)
+	binary.LittleEndian.PutUint32(msg[8:], hs.remoteIndex)
+	ephemeralPublic := publicKey(&hs.localEphemeral)
+	copy(msg[12:44], ephemeralPublic[:])
+	mixHash(&hs.hash, &hs.hash, msg[12:44])
+	mixKey(&hs.chainKey, &hs.chainKey, msg[12:44])
+
+	ss, err := sharedSecret(&hs.localEphemeral, hs.remoteEphemeral[:])
+	if err != nil {
+		return err
+	}
+	mixKey(&hs.chainKey, &hs.chainKey, ss[:])
+	ss, err = sharedSecret(&hs.localEphemeral, p.publicKey[:])
+	if err != nil {
+		return err
+	}
+	mixKey(&hs.chainKey, &hs.chainKey, ss[:])
+
+	var tau, key [blake2s.Size]byte
+	kdf(hs.chainKey[:], p.presharedKey[:], &hs.chainKey, &tau, &key)
+	mixHash(&hs.hash, &hs.hash, tau[:])
+	seal(&key, msg[44:60], nil, hs.hash[:])
+	mixHash(&hs.hash, &hs.hash, msg[44:60])
+
+	hs.state = handshakeResponseCreated
+	p.addMACs(msg[:messageResponseSize])
+	return nil
+}
+
+// consumeResponse processes the second handshake message from a peer, and returns the peer.
+func (d *Device) consumeResponse(msg []byte) (*peer, error) {
+	p, _ := d.indexes.lookup(binary.LittleEndian.Uint32(msg[8:]))
+	if p == nil {
+		return nil, newError("response to unknown initiation")
+	}
+
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	hs := &p.handshake
+	if hs.state != handshakeInitiationCreated || hs.localIndex != binary.LittleEndian.Uint32(msg[8:]) {
+		return nil, newError("unexpected handshake response")
+	}
+
+	hash, chainKey := hs.hash, hs.chainKey
+	ephemeral := msg[12:44]
+	mixHash(&hash, &hash, ephemeral)
+	mixKey(&chainKey, &chainKey, ephemeral)
+
+	ss, err := sharedSecret(&hs.localEphemeral, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	mixKey(&chainKey, &chainKey, ss[:])
+	ss, err = sharedSecret(&d.privateKey, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	mixKey(&chainKey, &chainKey, ss[:])
+
+	var tau, key [blake2s.Size]byte
+	kdf(chainKey[:], p.presharedKey[:], &chainKey, &tau, &key)
+	mixHash(&hash, &hash, tau[:])
+	if _, err := open(&key, msg[44:60], hash[:]); err != nil {
+		return nil, newError("failed to decrypt response").Base(err)
+	}
+	mixHash(&hash, &hash, msg[44:60])
+
+	hs.hash = hash
+	hs.chainKey = chainKey
+	hs.remoteIndex = binary.LittleEndian.Uint32(msg[4:])
+	hs.state = handshakeResponseConsumed
+	return p, nil
+}
+
+// checkMAC1 returns true if msg carries a valid MAC1 for this device.
+func (d *Device) checkMAC1(msg []byte) bool {
+	offset := len(msg) - 2*macSize
+	sum := mac(d.mac1Key[:], msg[:offset])
+	return subtle.ConstantTimeCompare(sum[:], msg[offset:offset+macSize]) == 1
+}
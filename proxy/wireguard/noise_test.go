@@ -0,0 +1,87 @@
+package wireguard
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/blake2s"
+
+	"github.com/eagleql/xray-core/common"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	common.Must(err)
+	return b
+}
+
+// TestKDF checks kdf with the test vectors of wireguard-go.
+func TestKDF(t *testing.T) {
+	for _, test := range []struct {
+		key   string
+		input string
+		t0    string
+		t1    string
+		t2    string
+	}{
+		{
+			key:   "746573742d6b6579",
+			input: "746573742d696e707574",
+			t0:    "6f0e5ad38daba1bea8a0d213688736f19763239305e0f58aba697f9ffc41c633",
+			t1:    "df1194df20802a4fe594cde27e92991c8cae66c366e8106aaa937a55fa371e8a",
+			t2:    "fac6e2745a325f5dc5d11a5b165aad08b0ada28e7b4e666b7c077934a4d76c24",
+		},
+		{
+			key:   "776972656775617264",
+			input: "776972656775617264",
+			t0:    "491d43bbfdaa8750aaf535e334ecbfe5129967cd64635101c566d4caefda96e8",
+			t1:    "1e71a379baefd8a79aa4662212fcafe19a23e2b609a3db7d6bcba8f560e3d25f",
+			t2:    "31e1ae48bddfbe5de38f295e5452b1909a1b4e38e183926af3780b0c1e1f0160",
+		},
+		{
+			key:   "",
+			input: "",
+			t0:    "8387b46bf43eccfcf349552a095d8315c4055beb90208fb1be23b894bc2ed5d0",
+			t1:    "58a0e5f6faefccf4807bff1f05fa8a9217945762040bcec2f4b4a62bdfe0e86e",
+			t2:    "0ce6ea98ec548f8e281e93e32db65621c45eb18dc6f0a7ad94178610a2f7338e",
+		},
+	} {
+		var t0, t1, t2 [blake2s.Size]byte
+		kdf(decodeHex(test.key), decodeHex(test.input), &t0, &t1, &t2)
+		for i, r := range []struct {
+			actual   []byte
+			expected string
+		}{{t0[:], test.t0}, {t1[:], test.t1}, {t2[:], test.t2}} {
+			if s := hex.EncodeToString(r.actual); s != r.expected {
+				t.Error("expect output ", i, " of key ", test.key, " to be ", r.expected, " but got ", s)
+			}
+		}
+	}
+}
+
+// TestCurve25519 checks the key exchange with the test vectors of RFC 7748.
+func TestCurve25519(t *testing.T) {
+	var skA, skB [keySize]byte
+	copy(skA[:], decodeHex("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"))
+	copy(skB[:], decodeHex("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb"))
+	pkA, pkB := publicKey(&skA), publicKey(&skB)
+	if s := hex.EncodeToString(pkA[:]); s != "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a" {
+		t.Error("unexpected public key ", s)
+	}
+	if s := hex.EncodeToString(pkB[:]); s != "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f" {
+		t.Error("unexpected public key ", s)
+	}
+
+	for _, ss := range [][keySize]byte{
+		common.Must2(sharedSecret(&skA, pkB[:])).([keySize]byte),
+		common.Must2(sharedSecret(&skB, pkA[:])).([keySize]byte),
+	} {
+		if s := hex.EncodeToString(ss[:]); s != "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742" {
+			t.Error("unexpected shared secret ", s)
+		}
+	}
+
+	if _, err := sharedSecret(&skA, make([]byte, keySize)); err == nil {
+		t.Error("expect low order public key to be rejected")
+	}
+}
@@ -0,0 +1,495 @@
+package wireguard
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// keypair is the session keys derived from a handshake.
+type keypair struct {
+	sendCounter uint64 // atomic
+	send        cipher.AEAD
+	receive     cipher.AEAD
+	isInitiator bool
+	created     time.Time
+	localIndex  uint32
+	remoteIndex uint32
+
+	access sync.Mutex
+	replay replayFilter
+}
+
+func (kp *keypair) canSend() bool {
+	return kp != nil && time.Since(kp.created) < rejectAfterTime && atomic.LoadUint64(&kp.sendCounter) < rejectAfterMessages
+}
+
+type peer struct {
+	device       *Device
+	publicKey    [keySize]byte
+	presharedKey [keySize]byte
+	staticStatic [keySize]byte
+	mac1Key      [blake2s.Size]byte
+	cookieKey    [blake2s.Size]byte
+	endpoint     net.Destination
+	keepAlive    time.Duration
+	allowedIPs   []*net.IPNet
+
+	access    sync.Mutex
+	handshake handshake
+	current   *keypair
+	previous  *keypair
+	next      *keypair
+	// queue holds packets to send once a handshake completes.
+	queue [][]byte
+	// handshakeStarted is when the first initiation of the current handshake was sent, zero if no handshake is in progress.
+	handshakeStarted   time.Time
+	lastInitiationSent time.Time
+	lastMAC1           [macSize]byte
+	cookie             [macSize]byte
+	cookieTime         time.Time
+
+	retransmitTimer   *time.Timer
+	keepaliveTimer    *time.Timer
+	keepalivePending  bool
+	handshakeTimer    *time.Timer
+	handshakePending  bool
+	persistentTimer   *time.Timer
+	sentLastHandshake bool
+
+	connAccess sync.Mutex
+	conn       net.Conn
+}
+
+func newPeer(d *Device, config *PeerConfig) (*peer, error) {
+	if len(config.PublicKey) != keySize {
+		return nil, newError("invalid public key of peer")
+	}
+	if len(config.PreSharedKey) != 0 && len(config.PreSharedKey) != keySize {
+		return nil, newError("invalid pre-shared key of peer")
+	}
+	endpoint, err := net.ParseDestination("udp:" + config.Endpoint)
+	if err != nil {
+		return nil, newError("invalid endpoint of peer: ", config.Endpoint).Base(err)
+	}
+
+	p := &peer{
+		device:    d,
+		endpoint:  endpoint,
+		keepAlive: time.Duration(config.KeepAlive) * time.Second,
+	}
+	copy(p.publicKey[:], config.PublicKey)
+	copy(p.presharedKey[:], config.PreSharedKey)
+	if p.staticStatic, err = sharedSecret(&d.privateKey, p.publicKey[:]); err != nil {
+		return nil, err
+	}
+	p.mac1Key = labelHash(labelMAC1, p.publicKey[:])
+	p.cookieKey = labelHash(labelCookie, p.publicKey[:])
+
+	allowedIPs := config.AllowedIps
+	if len(allowedIPs) == 0 {
+		allowedIPs = []string{"0.0.0.0/0", "::/0"}
+	}
+	for _, s := range allowedIPs {
+		ipNet, err := parseCIDR(s)
+		if err != nil {
+			return nil, newError("invalid allowed IP of peer: ", s).Base(err)
+		}
+		p.allowedIPs = append(p.allowedIPs, ipNet)
+	}
+
+	p.retransmitTimer = newStoppedTimer(func() { p.startHandshake(true) })
+	p.keepaliveTimer = newStoppedTimer(func() {
+		p.access.Lock()
+		p.keepalivePending = false
+		p.access.Unlock()
+		p.sendKeepalive()
+	})
+	p.handshakeTimer = newStoppedTimer(func() {
+		p.access.Lock()
+		p.handshakePending = false
+		p.access.Unlock()
+		p.startHandshake(false)
+	})
+	p.persistentTimer = newStoppedTimer(p.sendKeepalive)
+	return p, nil
+}
+
+func newStoppedTimer(f func()) *time.Timer {
+	t := time.AfterFunc(time.Hour, f)
+	t.Stop()
+	return t
+}
+
+// allows returns true if packets from ip may come from the peer.
+func (p *peer) allows(ip net.IP) bool {
+	for _, ipNet := range p.allowedIPs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *peer) addMACs(msg []byte) {
+	offset := len(msg) - 2*macSize
+	mac1 := mac(p.mac1Key[:], msg[:offset])
+	copy(msg[offset:], mac1[:])
+	p.lastMAC1 = mac1
+
+	if !p.cookieTime.IsZero() && time.Since(p.cookieTime) < cookieRefreshTime {
+		mac2 := mac(p.cookie[:], msg[:offset+macSize])
+		copy(msg[offset+macSize:], mac2[:])
+	} else {
+		for i := offset + macSize; i < len(msg); i++ {
+			msg[i] = 0
+		}
+	}
+}
+
+// consumeCookieReply stores the cookie the peer sends when it is under load, so that it is used in the MAC2 of later
+// handshake messages.
+func (d *Device) consumeCookieReply(msg []byte) {
+	p, _ := d.indexes.lookup(binary.LittleEndian.Uint32(msg[4:]))
+	if p == nil {
+		return
+	}
+
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	aead, _ := chacha20poly1305.NewX(p.cookieKey[:])
+	cookie, err := aead.Open(nil, msg[8:32], msg[32:64], p.lastMAC1[:])
+	if err != nil {
+		return
+	}
+	copy(p.cookie[:], cookie)
+	p.cookieTime = time.Now()
+}
+
+// startHandshake sends a handshake initiation to the peer, unless one was sent recently. If retry is true, the
+// initiation is a retransmission of a handshake in progress.
+func (p *peer) startHandshake(retry bool) {
+	p.access.Lock()
+	if retry {
+		if p.handshakeStarted.IsZero() {
+			p.access.Unlock()
+			return
+		}
+		if time.Since(p.handshakeStarted) > rekeyAttemptTime {
+			newError("handshake with ", p.endpoint, " did not complete after ", rekeyAttemptTime).AtWarning().WriteToLog()
+			p.handshakeStarted = time.Time{}
+			p.queue = nil
+			p.access.Unlock()
+			return
+		}
+	} else {
+		if time.Since(p.lastInitiationSent) < rekeyTimeout {
+			p.access.Unlock()
+			return
+		}
+		if p.handshakeStarted.IsZero() {
+			p.handshakeStarted = time.Now()
+		}
+	}
+
+	msg := make([]byte, messageInitiationSize)
+	if err := p.device.createInitiation(p, msg); err != nil {
+		p.access.Unlock()
+		newError("failed to create handshake initiation").Base(err).WriteToLog()
+		return
+	}
+	p.lastInitiationSent = time.Now()
+	p.retransmitTimer.Reset(rekeyTimeout + time.Duration(rand.Int63n(int64(time.Second/3))))
+	p.access.Unlock()
+
+	newError("sending handshake initiation to ", p.endpoint).AtDebug().WriteToLog()
+	p.write(msg)
+}
+
+// handleInitiation responds to a consumed handshake initiation.
+func (p *peer) handleInitiation() {
+	p.access.Lock()
+	msg := make([]byte, messageResponseSize)
+	if err := p.device.createResponse(p, msg); err != nil {
+		p.access.Unlock()
+		newError("failed to create handshake response").Base(err).WriteToLog()
+		return
+	}
+	p.beginSession()
+	p.access.Unlock()
+
+	p.write(msg)
+}
+
+// handleResponse starts a session after a consumed handshake response, and sends the queued packets in it.
+func (p *peer) handleResponse() {
+	p.access.Lock()
+	kp := p.beginSession()
+	p.handshakeStarted = time.Time{}
+	p.sentLastHandshake = false
+	p.retransmitTimer.Stop()
+	queue := p.queue
+	p.queue = nil
+	p.access.Unlock()
+
+	p.receivedAuthenticated()
+	if len(queue) == 0 {
+		p.sendTransport(kp, nil)
+		return
+	}
+	for _, packet := range queue {
+		p.sendTransport(kp, packet)
+	}
+}
+
+// beginSession derives a keypair from the completed handshake and rotates the keypairs of the peer.
+func (p *peer) beginSession() *keypair {
+	hs := &p.handshake
+	var sendKey, receiveKey [blake2s.Size]byte
+	isInitiator := hs.state == handshakeResponseConsumed
+	if isInitiator {
+		kdf(hs.chainKey[:], nil, &sendKey, &receiveKey)
+	} else {
+		kdf(hs.chainKey[:], nil, &receiveKey, &sendKey)
+	}
+	kp := &keypair{
+		isInitiator: isInitiator,
+		created:     time.Now(),
+		localIndex:  hs.localIndex,
+		remoteIndex: hs.remoteIndex,
+	}
+	kp.send, _ = chacha20poly1305.New(sendKey[:])
+	kp.receive, _ = chacha20poly1305.New(receiveKey[:])
+	p.device.indexes.setKeypair(kp.localIndex, kp)
+	// The index now belongs to the keypair.
+	p.handshake = handshake{
+		lastTimestamp:  hs.lastTimestamp,
+		lastInitiation: hs.lastInitiation,
+	}
+
+	if isInitiator {
+		p.removeKeypair(p.previous)
+		if p.next != nil {
+			p.previous, p.next = p.next, nil
+			p.removeKeypair(p.current)
+		} else {
+			p.previous = p.current
+		}
+		p.current = kp
+	} else {
+		p.removeKeypair(p.next)
+		p.next = kp
+		p.removeKeypair(p.previous)
+		p.previous = nil
+	}
+	return kp
+}
+
+func (p *peer) removeKeypair(kp *keypair) {
+	if kp != nil {
+		p.device.indexes.remove(kp.localIndex)
+	}
+}
+
+// send encrypts and sends an IP packet to the peer, or queues it until a handshake completes.
+func (p *peer) send(packet []byte) error {
+	p.access.Lock()
+	kp := p.current
+	if !kp.canSend() {
+		if len(p.queue) < maxQueuedPackets {
+			p.queue = append(p.queue, append([]byte(nil), packet...))
+		}
+		p.access.Unlock()
+		p.startHandshake(false)
+		return nil
+	}
+	p.access.Unlock()
+
+	if err := p.sendTransport(kp, packet); err != nil {
+		return err
+	}
+	if kp.isInitiator && (time.Since(kp.created) > rekeyAfterTime || atomic.LoadUint64(&kp.sendCounter) > rekeyAfterMessages) {
+		p.startHandshake(false)
+	}
+
+	p.access.Lock()
+	if !p.handshakePending {
+		p.handshakePending = true
+		p.handshakeTimer.Reset(keepaliveTimeout + rekeyTimeout)
+	}
+	p.access.Unlock()
+	return nil
+}
+
+func (p *peer) sendKeepalive() {
+	p.access.Lock()
+	kp := p.current
+	p.access.Unlock()
+	if !kp.canSend() {
+		p.startHandshake(false)
+		return
+	}
+	p.sendTransport(kp, nil)
+}
+
+func (p *peer) sendTransport(kp *keypair, packet []byte) error {
+	counter := atomic.AddUint64(&kp.sendCounter, 1) - 1
+	if counter >= rejectAfterMessages {
+		return newError("too many messages in session")
+	}
+
+	size := len(packet)
+	if size > 0 {
+		size = (size + 15) &^ 15
+		if size > p.device.mtu {
+			size = p.device.mtu
+		}
+		if size < len(packet) {
+			size = len(packet)
+		}
+	}
+	msg := make([]byte, messageTransportHeaderSize+size, messageTransportHeaderSize+size+aeadOverhead)
+	msg[0] = messageTransportType
+	binary.LittleEndian.PutUint32(msg[4:], kp.remoteIndex)
+	binary.LittleEndian.PutUint64(msg[8:], counter)
+	copy(msg[messageTransportHeaderSize:], packet)
+	var nonce [12]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	msg = kp.send.Seal(msg[:messageTransportHeaderSize], nonce[:], msg[messageTransportHeaderSize:], nil)
+	return p.write(msg)
+}
+
+// receivedTransport updates the keypairs and timers of the peer after a valid transport message in kp.
+func (p *peer) receivedTransport(kp *keypair, hasData bool) {
+	p.access.Lock()
+	var queue [][]byte
+	if kp == p.next {
+		p.removeKeypair(p.previous)
+		p.previous, p.current, p.next = p.current, p.next, nil
+		queue = p.queue
+		p.queue = nil
+		p.handshakeStarted = time.Time{}
+		p.retransmitTimer.Stop()
+	}
+	rekey := kp == p.current && kp.isInitiator && !p.sentLastHandshake &&
+		time.Since(kp.created) > rejectAfterTime-keepaliveTimeout-rekeyTimeout
+	if rekey {
+		p.sentLastHandshake = true
+	}
+	if hasData && !p.keepalivePending {
+		p.keepalivePending = true
+		p.keepaliveTimer.Reset(keepaliveTimeout)
+	}
+	p.access.Unlock()
+
+	p.receivedAuthenticated()
+	for _, packet := range queue {
+		p.sendTransport(kp, packet)
+	}
+	if rekey {
+		p.startHandshake(false)
+	}
+}
+
+func (p *peer) receivedAuthenticated() {
+	p.access.Lock()
+	p.handshakePending = false
+	p.handshakeTimer.Stop()
+	p.access.Unlock()
+}
+
+// write sends a message to the endpoint of the peer, dialing it if necessary.
+func (p *peer) write(msg []byte) error {
+	if len(p.device.reserved) == 3 {
+		copy(msg[1:4], p.device.reserved)
+	}
+	conn, err := p.getConn()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		p.resetConn(conn)
+		return newError("failed to write to ", p.endpoint).Base(err)
+	}
+
+	p.access.Lock()
+	if p.keepalivePending {
+		p.keepalivePending = false
+		p.keepaliveTimer.Stop()
+	}
+	if p.keepAlive > 0 {
+		p.persistentTimer.Reset(p.keepAlive)
+	}
+	p.access.Unlock()
+	return nil
+}
+
+func (p *peer) getConn() (net.Conn, error) {
+	p.connAccess.Lock()
+	defer p.connAccess.Unlock()
+
+	if p.conn != nil {
+		return p.conn, nil
+	}
+	if p.device.done.Done() {
+		return nil, newError("device closed")
+	}
+	conn, err := p.device.dial(p.endpoint)
+	if err != nil {
+		return nil, newError("failed to dial ", p.endpoint).Base(err)
+	}
+	p.conn = conn
+	go p.readLoop(conn)
+	return conn, nil
+}
+
+func (p *peer) resetConn(conn net.Conn) {
+	p.connAccess.Lock()
+	defer p.connAccess.Unlock()
+
+	if p.conn == conn {
+		p.conn = nil
+	}
+	conn.Close()
+}
+
+func (p *peer) readLoop(conn net.Conn) {
+	b := make([]byte, 65535)
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			if !p.device.done.Done() {
+				newError("failed to read from ", p.endpoint).Base(err).AtDebug().WriteToLog()
+			}
+			p.resetConn(conn)
+			return
+		}
+		p.device.receive(b[:n])
+	}
+}
+
+func (p *peer) close() {
+	p.access.Lock()
+	p.retransmitTimer.Stop()
+	p.keepaliveTimer.Stop()
+	p.handshakeTimer.Stop()
+	p.persistentTimer.Stop()
+	p.queue = nil
+	p.access.Unlock()
+
+	p.connAccess.Lock()
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	p.connAccess.Unlock()
+}
@@ -0,0 +1,972 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/signal/done"
+)
+
+const (
+	tcpHeaderSize = 20
+
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+	tcpFlagPSH = 0x08
+	tcpFlagACK = 0x10
+
+	tcpOptionEnd = 0
+	tcpOptionNOP = 1
+	tcpOptionMSS = 2
+
+	// tcpReceiveBufferSize is the largest window that can be advertised without the window scale option, which is
+	// not supported.
+	tcpReceiveBufferSize = 0xffff
+	tcpSendBufferSize    = 1 << 20
+	tcpInitialWindow     = 10
+	tcpMaxOutOfOrder     = 256
+	tcpMaxRetries        = 10
+	tcpAcceptQueueSize   = 64
+	// tcpChallengeACKLimit is the number of challenge ACKs of RFC 5961 a connection sends in a second at most.
+	tcpChallengeACKLimit = 10
+
+	// tcpInitialRTO is the retransmission timeout of RFC 6298 before any backoff. The round-trip time is not
+	// measured, so it is not adjusted otherwise.
+	tcpInitialRTO      = time.Second
+	tcpMaxRTO          = 60 * time.Second
+	tcpTimeWaitTimeout = 30 * time.Second
+	tcpFinWait2Timeout = 60 * time.Second
+)
+
+type tcpState int
+
+const (
+	tcpListen tcpState = iota
+	tcpSynSent
+	tcpSynReceived
+	tcpEstablished
+	tcpFinWait1
+	tcpFinWait2
+	tcpCloseWait
+	tcpClosing
+	tcpLastAck
+	tcpTimeWait
+	tcpClosed
+)
+
+func seqLT(a, b uint32) bool { return int32(a-b) < 0 }
+func seqGT(a, b uint32) bool { return int32(a-b) > 0 }
+
+type tcpSegment struct {
+	seq     uint32
+	ack     uint32
+	flags   byte
+	window  uint16
+	mss     int
+	payload []byte
+}
+
+func parseTCPSegment(b []byte) (seg *tcpSegment, srcPort uint16, dstPort uint16, ok bool) {
+	if len(b) < tcpHeaderSize {
+		return nil, 0, 0, false
+	}
+	headerSize := int(b[12]>>4) * 4
+	if headerSize < tcpHeaderSize || headerSize > len(b) {
+		return nil, 0, 0, false
+	}
+	seg = &tcpSegment{
+		seq:     binary.BigEndian.Uint32(b[4:]),
+		ack:     binary.BigEndian.Uint32(b[8:]),
+		flags:   b[13],
+		window:  binary.BigEndian.Uint16(b[14:]),
+		payload: b[headerSize:],
+	}
+	options := b[tcpHeaderSize:headerSize]
+	for len(options) > 0 {
+		kind := options[0]
+		if kind == tcpOptionEnd {
+			break
+		}
+		if kind == tcpOptionNOP {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || int(options[1]) < 2 || int(options[1]) > len(options) {
+			break
+		}
+		if kind == tcpOptionMSS && options[1] == 4 {
+			seg.mss = int(binary.BigEndian.Uint16(options[2:]))
+		}
+		options = options[options[1]:]
+	}
+	return seg, binary.BigEndian.Uint16(b), binary.BigEndian.Uint16(b[2:]), true
+}
+
+// writeTCP sends a TCP segment.
+func (s *netStack) writeTCP(localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16, seq, ack uint32, flags byte, window uint16, options []byte, payload []byte) error {
+	headerSize := tcpHeaderSize + len(options)
+	segment := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint16(segment, localPort)
+	binary.BigEndian.PutUint16(segment[2:], remotePort)
+	binary.BigEndian.PutUint32(segment[4:], seq)
+	binary.BigEndian.PutUint32(segment[8:], ack)
+	segment[12] = byte(headerSize/4) << 4
+	segment[13] = flags
+	binary.BigEndian.PutUint16(segment[14:], window)
+	copy(segment[tcpHeaderSize:], options)
+	copy(segment[headerSize:], payload)
+	binary.BigEndian.PutUint16(segment[16:], checksum(segment, pseudoHeaderSum(localIP, remoteIP, protocolTCP, len(segment))))
+	return s.writeIP(localIP, remoteIP, protocolTCP, segment)
+}
+
+func (s *netStack) deliverTCP(src, dst net.IP, segment []byte) {
+	seg, srcPort, dstPort, ok := parseTCPSegment(segment)
+	if !ok {
+		return
+	}
+
+	key := newConnKey(dst, dstPort, src, srcPort)
+	s.access.Lock()
+	c := s.tcpConns[key]
+	if c == nil && !s.closed && seg.flags&(tcpFlagSYN|tcpFlagACK|tcpFlagRST) == tcpFlagSYN {
+		if l := s.tcpListeners[dstPort]; l != nil {
+			c = newTCPConn(s, append(net.IP(nil), dst...), dstPort, append(net.IP(nil), src...), srcPort)
+			c.listener = l
+			s.tcpConns[key] = c
+		}
+	}
+	s.access.Unlock()
+
+	if c == nil {
+		if seg.flags&tcpFlagRST == 0 {
+			s.sendReset(dst, dstPort, src, srcPort, seg)
+		}
+		return
+	}
+	c.handleSegment(seg)
+}
+
+// sendReset responds to a segment that belongs to no connection.
+func (s *netStack) sendReset(localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16, seg *tcpSegment) {
+	if seg.flags&tcpFlagACK != 0 {
+		s.writeTCP(localIP, localPort, remoteIP, remotePort, seg.ack, 0, tcpFlagRST, 0, nil, nil)
+		return
+	}
+	ack := seg.seq + uint32(len(seg.payload))
+	if seg.flags&tcpFlagSYN != 0 {
+		ack++
+	}
+	if seg.flags&tcpFlagFIN != 0 {
+		ack++
+	}
+	s.writeTCP(localIP, localPort, remoteIP, remotePort, 0, ack, tcpFlagRST|tcpFlagACK, 0, nil, nil)
+}
+
+// tcpListener accepts TCP connections to a port of the network stack.
+type tcpListener struct {
+	stack *netStack
+	port  uint16
+	conns chan *tcpConn
+	done  *done.Instance
+}
+
+func (s *netStack) listenTCP(port uint16) (*tcpListener, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if _, found := s.tcpListeners[port]; found {
+		return nil, newError("port ", port, " in use")
+	}
+	l := &tcpListener{
+		stack: s,
+		port:  port,
+		conns: make(chan *tcpConn, tcpAcceptQueueSize),
+		done:  done.New(),
+	}
+	s.tcpListeners[port] = l
+	return l, nil
+}
+
+// Accept implements net.Listener.
+func (l *tcpListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done.Wait():
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Close implements net.Listener.
+func (l *tcpListener) Close() error {
+	l.stack.access.Lock()
+	if l.stack.tcpListeners[l.port] == l {
+		delete(l.stack.tcpListeners, l.port)
+	}
+	l.stack.access.Unlock()
+	return l.done.Close()
+}
+
+// Addr implements net.Listener.
+func (l *tcpListener) Addr() net.Addr {
+	return &net.TCPAddr{Port: int(l.port)}
+}
+
+type tcpReceivedSegment struct {
+	payload []byte
+	fin     bool
+}
+
+// tcpConn is a TCP connection in the network stack.
+type tcpConn struct {
+	stack      *netStack
+	key        connKey
+	localIP    net.IP
+	localPort  uint16
+	remoteIP   net.IP
+	remotePort uint16
+	listener   *tcpListener
+
+	access      sync.Mutex
+	state       tcpState
+	err         error
+	closed      bool
+	readSignal  chan struct{}
+	writeSignal chan struct{}
+
+	iss        uint32
+	sndUna     uint32
+	sndNxt     uint32
+	sndMax     uint32
+	sndWnd     uint32
+	sndWndMax  uint32
+	mss        int
+	sendBuf    []byte
+	finQueued  bool
+	finSent    bool
+	cwnd       int
+	ssthresh   int
+	dupAcks    int
+	recovering bool
+
+	rto     time.Duration
+	retries int
+
+	challengeACKs    int
+	challengeACKTime time.Time
+
+	retransmitTimer *time.Timer
+	retransmitArmed bool
+	stateTimer      *time.Timer
+
+	rcvNxt        uint32
+	recvBuf       bytes.Buffer
+	outOfOrder    map[uint32]tcpReceivedSegment
+	finReceived   bool
+	rcvAdvertised int
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+}
+
+func newTCPConn(s *netStack, localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16) *tcpConn {
+	c := &tcpConn{
+		stack:         s,
+		key:           newConnKey(localIP, localPort, remoteIP, remotePort),
+		localIP:       localIP,
+		localPort:     localPort,
+		remoteIP:      remoteIP,
+		remotePort:    remotePort,
+		state:         tcpListen,
+		readSignal:    make(chan struct{}, 1),
+		writeSignal:   make(chan struct{}, 1),
+		iss:           randomUint32(),
+		mss:           s.maxSegmentSize(remoteIP),
+		ssthresh:      1 << 30,
+		rto:           tcpInitialRTO,
+		outOfOrder:    make(map[uint32]tcpReceivedSegment),
+		readDeadline:  newDeadline(),
+		writeDeadline: newDeadline(),
+	}
+	c.cwnd = tcpInitialWindow * c.mss
+	c.retransmitTimer = newStoppedTimer(c.onRetransmitTimeout)
+	c.stateTimer = newStoppedTimer(func() {
+		c.access.Lock()
+		defer c.access.Unlock()
+		c.closeLocked(nil)
+	})
+	return c
+}
+
+func (s *netStack) maxSegmentSize(remote net.IP) int {
+	if remote.To4() != nil {
+		return s.mtu - ipv4HeaderSize - tcpHeaderSize
+	}
+	return s.mtu - ipv6HeaderSize - tcpHeaderSize
+}
+
+func (s *netStack) dialTCP(ctx context.Context, dest net.Destination) (*tcpConn, error) {
+	remoteIP := dest.Address.IP()
+	localIP, err := s.localAddress(remoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	s.access.Lock()
+	if s.closed {
+		s.access.Unlock()
+		return nil, newError("network stack closed")
+	}
+	localPort := s.allocatePort(localIP, remoteIP, uint16(dest.Port), false)
+	c := newTCPConn(s, localIP, localPort, remoteIP, uint16(dest.Port))
+	s.tcpConns[c.key] = c
+	s.access.Unlock()
+
+	c.access.Lock()
+	c.state = tcpSynSent
+	c.sendSYN()
+	c.access.Unlock()
+
+	for {
+		c.access.Lock()
+		state, err := c.state, c.err
+		c.access.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if state != tcpSynSent {
+			return c, nil
+		}
+		select {
+		case <-c.writeSignal:
+		case <-ctx.Done():
+			c.abort(ctx.Err())
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// sendSYN sends or retransmits the SYN of an active open, or the SYN-ACK of a passive open.
+func (c *tcpConn) sendSYN() {
+	flags := byte(tcpFlagSYN)
+	if c.state == tcpSynReceived {
+		flags |= tcpFlagACK
+	}
+	options := []byte{tcpOptionMSS, 4, 0, 0}
+	binary.BigEndian.PutUint16(options[2:], uint16(c.stack.maxSegmentSize(c.remoteIP)))
+	c.sndUna = c.iss
+	c.sndNxt = c.iss + 1
+	c.sndMax = c.sndNxt
+	c.writeSegment(c.iss, flags, options, nil)
+	c.armRetransmit()
+}
+
+// applySYNOptions negotiates the MSS with the options of the SYN from the peer.
+func (c *tcpConn) applySYNOptions(seg *tcpSegment) {
+	mss := seg.mss
+	if mss == 0 {
+		if c.remoteIP.To4() != nil {
+			mss = 536
+		} else {
+			mss = 1220
+		}
+	}
+	if mss < c.mss {
+		c.mss = mss
+	}
+	c.cwnd = tcpInitialWindow * c.mss
+	c.sndWnd = uint32(seg.window)
+	c.sndWndMax = c.sndWnd
+}
+
+func (c *tcpConn) writeSegment(seq uint32, flags byte, options []byte, payload []byte) {
+	window := tcpReceiveBufferSize - c.recvBuf.Len()
+	c.rcvAdvertised = window
+	var ack uint32
+	if flags&tcpFlagACK != 0 {
+		ack = c.rcvNxt
+	}
+	if err := c.stack.writeTCP(c.localIP, c.localPort, c.remoteIP, c.remotePort, seq, ack, flags, uint16(window), options, payload); err != nil {
+		newError("failed to write TCP segment").Base(err).AtDebug().WriteToLog()
+	}
+}
+
+func (c *tcpConn) sendACK() {
+	c.writeSegment(c.sndNxt, tcpFlagACK, nil, nil)
+}
+
+// sendChallengeACK sends an ACK in response to a suspicious segment as in RFC 5961, at most tcpChallengeACKLimit
+// times a second, so that the peer resets the connection if it has really lost it, while a blind attacker can't.
+func (c *tcpConn) sendChallengeACK() {
+	if now := time.Now(); now.Sub(c.challengeACKTime) > time.Second {
+		c.challengeACKTime = now
+		c.challengeACKs = 0
+	}
+	if c.challengeACKs >= tcpChallengeACKLimit {
+		return
+	}
+	c.challengeACKs++
+	c.sendACK()
+}
+
+func (c *tcpConn) armRetransmit() {
+	c.retransmitArmed = true
+	c.retransmitTimer.Reset(c.rto)
+}
+
+func (c *tcpConn) stopRetransmit() {
+	c.retransmitArmed = false
+	c.retransmitTimer.Stop()
+}
+
+// output sends as much of the send buffer as the windows allow, and the FIN once the buffer is sent.
+func (c *tcpConn) output() {
+	switch c.state {
+	case tcpEstablished, tcpCloseWait, tcpFinWait1, tcpClosing, tcpLastAck:
+	default:
+		return
+	}
+	for {
+		inFlight := int(c.sndNxt - c.sndUna)
+		unsent := len(c.sendBuf) - inFlight
+		if unsent <= 0 {
+			break
+		}
+		window := int(c.sndWnd)
+		if c.cwnd < window {
+			window = c.cwnd
+		}
+		if window == 0 && inFlight == 0 {
+			// Probe the zero window of the peer.
+			window = 1
+		}
+		n := window - inFlight
+		if n <= 0 {
+			break
+		}
+		if n > unsent {
+			n = unsent
+		}
+		if n > c.mss {
+			n = c.mss
+		}
+		c.writeSegment(c.sndNxt, tcpFlagACK|tcpFlagPSH, nil, c.sendBuf[inFlight:inFlight+n])
+		c.sndNxt += uint32(n)
+		if seqGT(c.sndNxt, c.sndMax) {
+			c.sndMax = c.sndNxt
+		}
+	}
+	if c.finQueued && !c.finSent && int(c.sndNxt-c.sndUna) == len(c.sendBuf) {
+		c.writeSegment(c.sndNxt, tcpFlagFIN|tcpFlagACK, nil, nil)
+		c.sndNxt++
+		if seqGT(c.sndNxt, c.sndMax) {
+			c.sndMax = c.sndNxt
+		}
+		c.finSent = true
+		switch c.state {
+		case tcpEstablished:
+			c.state = tcpFinWait1
+		case tcpCloseWait:
+			c.state = tcpLastAck
+		}
+	}
+	if c.sndMax != c.sndUna && !c.retransmitArmed {
+		c.armRetransmit()
+	}
+}
+
+func (c *tcpConn) onRetransmitTimeout() {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	if !c.retransmitArmed || c.state == tcpClosed || c.sndMax == c.sndUna {
+		return
+	}
+	c.retransmitArmed = false
+	c.retries++
+	if c.retries > tcpMaxRetries {
+		c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+		c.closeLocked(newError("connection timed out"))
+		return
+	}
+	c.rto *= 2
+	if c.rto > tcpMaxRTO {
+		c.rto = tcpMaxRTO
+	}
+
+	switch c.state {
+	case tcpSynSent, tcpSynReceived:
+		c.sendSYN()
+		return
+	}
+	c.ssthresh = int(c.sndMax-c.sndUna) / 2
+	if c.ssthresh < 2*c.mss {
+		c.ssthresh = 2 * c.mss
+	}
+	c.cwnd = c.mss
+	c.dupAcks = 0
+	c.recovering = false
+	// Go back to the first unacknowledged byte.
+	c.sndNxt = c.sndUna
+	c.finSent = false
+	c.output()
+	if !c.retransmitArmed {
+		c.armRetransmit()
+	}
+}
+
+func (c *tcpConn) handleSegment(seg *tcpSegment) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	switch c.state {
+	case tcpClosed:
+		return
+	case tcpListen:
+		c.rcvNxt = seg.seq + 1
+		c.applySYNOptions(seg)
+		c.state = tcpSynReceived
+		c.sendSYN()
+		return
+	case tcpSynSent:
+		if seg.flags&tcpFlagACK != 0 && seg.ack != c.iss+1 {
+			if seg.flags&tcpFlagRST == 0 {
+				c.stack.writeTCP(c.localIP, c.localPort, c.remoteIP, c.remotePort, seg.ack, 0, tcpFlagRST, 0, nil, nil)
+			}
+			return
+		}
+		if seg.flags&tcpFlagRST != 0 {
+			if seg.flags&tcpFlagACK != 0 {
+				c.closeLocked(newError("connection refused by ", c.remoteIP, ":", c.remotePort))
+			}
+			return
+		}
+		if seg.flags&(tcpFlagSYN|tcpFlagACK) != tcpFlagSYN|tcpFlagACK {
+			return
+		}
+		c.rcvNxt = seg.seq + 1
+		c.applySYNOptions(seg)
+		c.sndUna = seg.ack
+		c.state = tcpEstablished
+		c.stopRetransmit()
+		c.rto = tcpInitialRTO
+		c.retries = 0
+		c.sendACK()
+		notify(c.writeSignal)
+		return
+	}
+
+	if seg.flags&tcpFlagRST != 0 {
+		// Only a reset at the exact sequence number is accepted, and one elsewhere in the window is challenged,
+		// as in RFC 5961.
+		if seg.seq == c.rcvNxt {
+			c.closeLocked(newError("connection reset by ", c.remoteIP, ":", c.remotePort))
+		} else if seqGT(seg.seq, c.rcvNxt) && seqLT(seg.seq, c.rcvNxt+tcpReceiveBufferSize) {
+			c.sendChallengeACK()
+		}
+		return
+	}
+	if seg.flags&tcpFlagSYN != 0 {
+		if c.state == tcpSynReceived && seg.seq+1 == c.rcvNxt {
+			// A retransmitted SYN, as our reply was lost.
+			c.sendSYN()
+		} else {
+			c.sendChallengeACK()
+		}
+		return
+	}
+	if seg.flags&tcpFlagACK == 0 {
+		return
+	}
+	if c.state == tcpSynReceived {
+		if seg.ack != c.iss+1 {
+			c.stack.writeTCP(c.localIP, c.localPort, c.remoteIP, c.remotePort, seg.ack, 0, tcpFlagRST, 0, nil, nil)
+			return
+		}
+		c.sndUna = seg.ack
+		c.state = tcpEstablished
+		c.stopRetransmit()
+		c.rto = tcpInitialRTO
+		c.retries = 0
+		select {
+		case c.listener.conns <- c:
+		default:
+			c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+			c.closeLocked(newError("accept queue full"))
+			return
+		}
+		c.listener = nil
+	}
+
+	if !c.handleACK(seg) || c.state == tcpClosed {
+		return
+	}
+	c.handleData(seg)
+}
+
+// handleACK processes the acknowledgement of the segment, and returns false if the segment must be dropped.
+func (c *tcpConn) handleACK(seg *tcpSegment) bool {
+	ack := seg.ack
+	if seqGT(ack, c.sndMax) {
+		c.sendACK()
+		return false
+	}
+	if seqLT(ack, c.sndUna-c.sndWndMax) {
+		// Acknowledging data older than any window, which is likely blind injection, see RFC 5961.
+		c.sendChallengeACK()
+		return false
+	}
+	if seqLT(ack, c.sndUna) {
+		return true
+	}
+	window := uint32(seg.window)
+	windowUpdated := window != c.sndWnd
+	c.sndWnd = window
+	if window > c.sndWndMax {
+		c.sndWndMax = window
+	}
+
+	if seqGT(ack, c.sndUna) {
+		acked := int(ack - c.sndUna)
+		finAcked := c.finQueued && acked > len(c.sendBuf)
+		if acked > len(c.sendBuf) {
+			acked = len(c.sendBuf)
+		}
+		c.sendBuf = c.sendBuf[acked:]
+		c.sndUna = ack
+		if seqLT(c.sndNxt, ack) {
+			c.sndNxt = ack
+		}
+		c.rto = tcpInitialRTO
+		c.retries = 0
+
+		if c.recovering {
+			c.recovering = false
+			c.cwnd = c.ssthresh
+		} else if c.cwnd < c.ssthresh {
+			if acked > c.mss {
+				c.cwnd += c.mss
+			} else {
+				c.cwnd += acked
+			}
+		} else {
+			c.cwnd += c.mss * c.mss / c.cwnd
+		}
+		c.dupAcks = 0
+
+		if c.sndUna == c.sndMax {
+			c.stopRetransmit()
+		} else {
+			c.armRetransmit()
+		}
+
+		if finAcked {
+			switch c.state {
+			case tcpFinWait1:
+				c.state = tcpFinWait2
+				c.stateTimer.Reset(tcpFinWait2Timeout)
+			case tcpClosing:
+				c.enterTimeWait()
+			case tcpLastAck:
+				c.closeLocked(nil)
+				return false
+			}
+		}
+		notify(c.writeSignal)
+	} else if len(seg.payload) == 0 && seg.flags&tcpFlagFIN == 0 && c.sndMax != c.sndUna && !windowUpdated {
+		// A duplicate acknowledgement as defined by RFC 5681, which is not a window update.
+		c.dupAcks++
+		if c.dupAcks == 3 && !c.recovering {
+			// Fast retransmit and fast recovery as in RFC 5681.
+			c.ssthresh = int(c.sndMax-c.sndUna) / 2
+			if c.ssthresh < 2*c.mss {
+				c.ssthresh = 2 * c.mss
+			}
+			c.cwnd = c.ssthresh + 3*c.mss
+			c.recovering = true
+			c.retransmitFirst()
+		} else if c.recovering {
+			c.cwnd += c.mss
+		}
+	}
+	c.output()
+	return true
+}
+
+// retransmitFirst retransmits the first unacknowledged segment.
+func (c *tcpConn) retransmitFirst() {
+	if n := len(c.sendBuf); n > 0 {
+		if n > c.mss {
+			n = c.mss
+		}
+		c.writeSegment(c.sndUna, tcpFlagACK|tcpFlagPSH, nil, c.sendBuf[:n])
+	} else if c.finSent {
+		c.writeSegment(c.sndUna, tcpFlagFIN|tcpFlagACK, nil, nil)
+	}
+}
+
+func (c *tcpConn) handleData(seg *tcpSegment) {
+	fin := seg.flags&tcpFlagFIN != 0
+	payload := seg.payload
+	if len(payload) == 0 && !fin {
+		return
+	}
+	if c.finReceived {
+		c.sendACK()
+		return
+	}
+
+	seq := seg.seq
+	if seqLT(seq, c.rcvNxt) {
+		skip := int(c.rcvNxt - seq)
+		if skip > len(payload) || (skip == len(payload) && !fin) {
+			c.sendACK()
+			return
+		}
+		payload = payload[skip:]
+		seq = c.rcvNxt
+	}
+	if seq != c.rcvNxt {
+		if seqLT(seq, c.rcvNxt+tcpReceiveBufferSize) && len(c.outOfOrder) < tcpMaxOutOfOrder {
+			c.outOfOrder[seq] = tcpReceivedSegment{
+				payload: append([]byte(nil), payload...),
+				fin:     fin,
+			}
+		}
+		c.sendACK()
+		return
+	}
+	if c.closed && len(payload) > 0 {
+		// Nobody will read the data.
+		c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+		c.closeLocked(nil)
+		return
+	}
+
+	c.receive(payload, fin)
+	for len(c.outOfOrder) > 0 && !c.finReceived {
+		progress := false
+		for seq, s := range c.outOfOrder {
+			if seqGT(seq, c.rcvNxt) {
+				continue
+			}
+			// The segment is either received now, or covered by the data received already.
+			delete(c.outOfOrder, seq)
+			progress = true
+			skip := int(c.rcvNxt - seq)
+			if skip < len(s.payload) || (skip == len(s.payload) && s.fin) {
+				c.receive(s.payload[skip:], s.fin)
+				break
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+	c.sendACK()
+	notify(c.readSignal)
+}
+
+func (c *tcpConn) receive(payload []byte, fin bool) {
+	if available := tcpReceiveBufferSize - c.recvBuf.Len(); len(payload) > available {
+		payload = payload[:available]
+		fin = false
+	}
+	c.recvBuf.Write(payload)
+	c.rcvNxt += uint32(len(payload))
+	if !fin {
+		return
+	}
+	c.rcvNxt++
+	c.finReceived = true
+	c.outOfOrder = nil
+	switch c.state {
+	case tcpEstablished:
+		c.state = tcpCloseWait
+	case tcpFinWait1:
+		c.state = tcpClosing
+	case tcpFinWait2:
+		c.enterTimeWait()
+	}
+}
+
+func (c *tcpConn) enterTimeWait() {
+	c.state = tcpTimeWait
+	c.stopRetransmit()
+	c.stateTimer.Reset(tcpTimeWaitTimeout)
+}
+
+// closeLocked releases the connection. It must be called with c.access held.
+func (c *tcpConn) closeLocked(err error) {
+	if c.state == tcpClosed {
+		return
+	}
+	c.state = tcpClosed
+	if c.err == nil {
+		c.err = err
+	}
+	c.stopRetransmit()
+	c.stateTimer.Stop()
+
+	c.stack.access.Lock()
+	if c.stack.tcpConns[c.key] == c {
+		delete(c.stack.tcpConns, c.key)
+	}
+	c.stack.access.Unlock()
+
+	notify(c.readSignal)
+	notify(c.writeSignal)
+}
+
+// abort resets the connection.
+func (c *tcpConn) abort(err error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	if c.state != tcpClosed && c.state != tcpListen {
+		c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+	}
+	c.closeLocked(err)
+}
+
+// Read implements net.Conn.
+func (c *tcpConn) Read(b []byte) (int, error) {
+	for {
+		c.access.Lock()
+		if c.closed {
+			c.access.Unlock()
+			return 0, io.ErrClosedPipe
+		}
+		if c.recvBuf.Len() > 0 {
+			n, _ := c.recvBuf.Read(b)
+			// Tell the peer once the window opens up again.
+			if c.rcvAdvertised < tcpReceiveBufferSize/2 && tcpReceiveBufferSize-c.recvBuf.Len() >= tcpReceiveBufferSize/2 && c.state != tcpClosed {
+				c.sendACK()
+			}
+			c.access.Unlock()
+			return n, nil
+		}
+		if c.finReceived {
+			c.access.Unlock()
+			return 0, io.EOF
+		}
+		if c.err != nil {
+			err := c.err
+			c.access.Unlock()
+			return 0, err
+		}
+		if c.state == tcpClosed {
+			c.access.Unlock()
+			return 0, io.EOF
+		}
+		c.access.Unlock()
+
+		select {
+		case <-c.readSignal:
+		case <-c.readDeadline.wait():
+			return 0, timeoutError{}
+		}
+	}
+}
+
+// Write implements net.Conn.
+func (c *tcpConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		c.access.Lock()
+		if c.closed {
+			c.access.Unlock()
+			return written, io.ErrClosedPipe
+		}
+		if c.err != nil {
+			err := c.err
+			c.access.Unlock()
+			return written, err
+		}
+		if c.state != tcpEstablished && c.state != tcpCloseWait {
+			c.access.Unlock()
+			return written, io.ErrClosedPipe
+		}
+		if space := tcpSendBufferSize - len(c.sendBuf); space > 0 {
+			if space > len(b) {
+				space = len(b)
+			}
+			c.sendBuf = append(c.sendBuf, b[:space]...)
+			b = b[space:]
+			written += space
+			c.output()
+			c.access.Unlock()
+			continue
+		}
+		c.access.Unlock()
+
+		select {
+		case <-c.writeSignal:
+		case <-c.writeDeadline.wait():
+			return written, timeoutError{}
+		}
+	}
+	return written, nil
+}
+
+// Close implements net.Conn. The connection is closed gracefully, unless there is unread data.
+func (c *tcpConn) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	switch c.state {
+	case tcpEstablished, tcpCloseWait:
+		if c.recvBuf.Len() > 0 {
+			c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+			c.closeLocked(nil)
+		} else {
+			c.finQueued = true
+			c.output()
+		}
+	case tcpSynSent, tcpSynReceived:
+		c.writeSegment(c.sndNxt, tcpFlagRST|tcpFlagACK, nil, nil)
+		c.closeLocked(nil)
+	}
+	notify(c.readSignal)
+	notify(c.writeSignal)
+	return nil
+}
+
+// LocalAddr implements net.Conn.
+func (c *tcpConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: c.localIP, Port: int(c.localPort)}
+}
+
+// RemoteAddr implements net.Conn.
+func (c *tcpConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: c.remoteIP, Port: int(c.remotePort)}
+}
+
+// SetDeadline implements net.Conn.
+func (c *tcpConn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *tcpConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.
+func (c *tcpConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
@@ -0,0 +1,124 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// newTestTCPConn creates an established connection, whose segments sent are appended to the returned slice.
+func newTestTCPConn() (*tcpConn, *[]*tcpSegment) {
+	var sent []*tcpSegment
+	stack := newNetStack([]net.IP{{10, 0, 0, 1}}, 1420, func(packet []byte) error {
+		seg, _, _, ok := parseTCPSegment(packet[ipv4HeaderSize:])
+		if ok {
+			sent = append(sent, seg)
+		}
+		return nil
+	})
+	c := newTCPConn(stack, net.IP{10, 0, 0, 1}, 1024, net.IP{10, 0, 0, 2}, 80)
+	c.state = tcpEstablished
+	c.sndUna, c.sndNxt, c.sndMax = c.iss+1, c.iss+1, c.iss+1
+	c.sndWnd, c.sndWndMax = 1<<16, 1<<16
+	c.rcvNxt = 1000
+	return c, &sent
+}
+
+func TestTCPDuplicateACK(t *testing.T) {
+	c, _ := newTestTCPConn()
+	defer c.stopRetransmit()
+	c.sendBuf = make([]byte, 4*c.mss)
+	c.output()
+	if c.sndNxt == c.sndUna {
+		t.Fatal("expect data to be sent")
+	}
+
+	for i := 0; i < 3; i++ {
+		c.handleSegment(&tcpSegment{seq: c.rcvNxt, ack: c.sndUna, flags: tcpFlagACK, window: uint16(1000 + i)})
+	}
+	if c.dupAcks != 0 || c.recovering {
+		t.Error("expect window updates not to be duplicate ACKs, but got ", c.dupAcks)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.handleSegment(&tcpSegment{seq: c.rcvNxt, ack: c.sndUna, flags: tcpFlagACK, window: 1002})
+	}
+	if !c.recovering {
+		t.Error("expect fast retransmit after 3 duplicate ACKs, but got ", c.dupAcks)
+	}
+}
+
+func TestTCPRetransmit(t *testing.T) {
+	c, sent := newTestTCPConn()
+	defer c.stopRetransmit()
+	c.access.Lock()
+	c.rto = 50 * time.Millisecond
+	c.sendBuf = make([]byte, 2*c.mss)
+	c.output()
+	c.access.Unlock()
+
+	time.Sleep(80 * time.Millisecond)
+	c.access.Lock()
+	if len(*sent) != 3 || (*sent)[2].seq != c.sndUna || c.rto != 100*time.Millisecond {
+		t.Error("expect the first segment to be retransmitted once with the timeout doubled, but got ", len(*sent), " segments")
+	}
+	ack := &tcpSegment{seq: c.rcvNxt, ack: c.sndMax, flags: tcpFlagACK, window: 1000}
+	c.access.Unlock()
+
+	c.handleSegment(ack)
+	c.access.Lock()
+	if c.rto != tcpInitialRTO || c.retransmitArmed {
+		t.Error("expect the timeout to be reset once all data is acknowledged")
+	}
+	c.access.Unlock()
+}
+
+func TestTCPOutOfOrder(t *testing.T) {
+	c, _ := newTestTCPConn()
+	c.outOfOrder[1100] = tcpReceivedSegment{payload: make([]byte, 50)}
+	c.outOfOrder[1200] = tcpReceivedSegment{payload: make([]byte, 100)}
+	c.outOfOrder[1400] = tcpReceivedSegment{payload: make([]byte, 100)}
+
+	c.handleSegment(&tcpSegment{seq: 1000, ack: c.sndUna, flags: tcpFlagACK, window: 1000, payload: make([]byte, 200)})
+	if c.rcvNxt != 1300 || c.recvBuf.Len() != 300 {
+		t.Error("expect data up to 1300, but got ", c.rcvNxt, " with ", c.recvBuf.Len(), " bytes")
+	}
+	if _, found := c.outOfOrder[1400]; len(c.outOfOrder) != 1 || !found {
+		t.Error("expect only the segment after the gap to be kept, but got ", len(c.outOfOrder))
+	}
+}
+
+func TestTCPChallengeACK(t *testing.T) {
+	c, sent := newTestTCPConn()
+
+	c.handleSegment(&tcpSegment{seq: c.rcvNxt + 100, flags: tcpFlagRST})
+	if c.state != tcpEstablished {
+		t.Fatal("expect reset in the window not to be accepted")
+	}
+	if len(*sent) != 1 || (*sent)[0].flags != tcpFlagACK || (*sent)[0].ack != c.rcvNxt {
+		t.Error("expect a challenge ACK")
+	}
+
+	c.handleSegment(&tcpSegment{seq: c.rcvNxt, flags: tcpFlagSYN})
+	if c.state != tcpEstablished || len(*sent) != 2 {
+		t.Error("expect SYN to be challenged")
+	}
+
+	c.handleSegment(&tcpSegment{seq: c.rcvNxt, ack: c.sndUna - c.sndWndMax - 1, flags: tcpFlagACK, window: 1000, payload: []byte("data")})
+	if c.rcvNxt != 1000 || len(*sent) != 3 {
+		t.Error("expect segment acknowledging too old data to be challenged and dropped")
+	}
+
+	for i := 0; i < 2*tcpChallengeACKLimit; i++ {
+		c.handleSegment(&tcpSegment{seq: c.rcvNxt + 100, flags: tcpFlagRST})
+	}
+	if len(*sent) != tcpChallengeACKLimit {
+		t.Error("expect ", tcpChallengeACKLimit, " challenge ACKs in a second, but got ", len(*sent))
+	}
+
+	c.handleSegment(&tcpSegment{seq: c.rcvNxt, flags: tcpFlagRST})
+	if c.state != tcpClosed {
+		t.Error("expect reset at the next sequence number to be accepted")
+	}
+}
@@ -0,0 +1,213 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/signal/done"
+)
+
+const (
+	udpHeaderSize = 8
+	udpQueueSize  = 128
+)
+
+type udpPacket struct {
+	payload []byte
+	from    *net.UDPAddr
+}
+
+// udpConn is a UDP socket in the network stack. It is connected to a remote address, or receives from any address if
+// it is a listener.
+type udpConn struct {
+	stack     *netStack
+	key       connKey
+	localIP   net.IP
+	localPort uint16
+	remote    *net.UDPAddr
+
+	packets      chan udpPacket
+	done         *done.Instance
+	readDeadline *deadline
+	closeOnce    sync.Once
+}
+
+func (s *netStack) dialUDP(dest net.Destination) (*udpConn, error) {
+	remoteIP := dest.Address.IP()
+	localIP, err := s.localAddress(remoteIP)
+	if err != nil {
+		return nil, err
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if s.closed {
+		return nil, newError("network stack closed")
+	}
+	localPort := s.allocatePort(localIP, remoteIP, uint16(dest.Port), true)
+	c := newUDPConn(s, localIP, localPort, &net.UDPAddr{IP: remoteIP, Port: int(dest.Port)})
+	s.udpConns[c.key] = c
+	return c, nil
+}
+
+func (s *netStack) listenUDP(localIP net.IP, port uint16) (*udpConn, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	c := newUDPConn(s, localIP, port, nil)
+	if _, found := s.udpConns[c.key]; found {
+		return nil, newError("port ", port, " in use")
+	}
+	s.udpConns[c.key] = c
+	return c, nil
+}
+
+func newUDPConn(s *netStack, localIP net.IP, localPort uint16, remote *net.UDPAddr) *udpConn {
+	c := &udpConn{
+		stack:        s,
+		localIP:      localIP,
+		localPort:    localPort,
+		remote:       remote,
+		packets:      make(chan udpPacket, udpQueueSize),
+		done:         done.New(),
+		readDeadline: newDeadline(),
+	}
+	if remote != nil {
+		c.key = newConnKey(localIP, localPort, remote.IP, uint16(remote.Port))
+	} else {
+		c.key = newConnKey(localIP, localPort, nil, 0)
+	}
+	return c
+}
+
+func (s *netStack) deliverUDP(src, dst net.IP, segment []byte) {
+	if len(segment) < udpHeaderSize {
+		return
+	}
+	srcPort := binary.BigEndian.Uint16(segment)
+	dstPort := binary.BigEndian.Uint16(segment[2:])
+	length := int(binary.BigEndian.Uint16(segment[4:]))
+	if length < udpHeaderSize || length > len(segment) {
+		return
+	}
+
+	s.access.Lock()
+	c, found := s.udpConns[newConnKey(dst, dstPort, src, srcPort)]
+	if !found {
+		c, found = s.udpConns[newConnKey(dst, dstPort, nil, 0)]
+	}
+	s.access.Unlock()
+	if !found {
+		return
+	}
+
+	packet := udpPacket{
+		payload: append([]byte(nil), segment[udpHeaderSize:length]...),
+		from:    &net.UDPAddr{IP: append(net.IP(nil), src...), Port: int(srcPort)},
+	}
+	select {
+	case c.packets <- packet:
+	default:
+	}
+}
+
+// ReadFrom reads a packet and the address it comes from.
+func (c *udpConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(b, packet.payload), packet.from, nil
+	case <-c.done.Wait():
+		return 0, nil, io.EOF
+	case <-c.readDeadline.wait():
+		return 0, nil, timeoutError{}
+	}
+}
+
+// WriteTo writes a packet to addr.
+func (c *udpConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if c.done.Done() {
+		return 0, io.ErrClosedPipe
+	}
+	to, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, newError("not a UDP address: ", addr)
+	}
+	if udpHeaderSize+len(b) > 0xffff {
+		return 0, newError("packet too large")
+	}
+
+	segment := make([]byte, udpHeaderSize+len(b))
+	binary.BigEndian.PutUint16(segment, c.localPort)
+	binary.BigEndian.PutUint16(segment[2:], uint16(to.Port))
+	binary.BigEndian.PutUint16(segment[4:], uint16(len(segment)))
+	copy(segment[udpHeaderSize:], b)
+	sum := checksum(segment, pseudoHeaderSum(c.localIP, to.IP, protocolUDP, len(segment)))
+	if sum == 0 {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(segment[6:], sum)
+	if err := c.stack.writeIP(c.localIP, to.IP, protocolUDP, segment); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read implements net.Conn.
+func (c *udpConn) Read(b []byte) (int, error) {
+	n, _, err := c.ReadFrom(b)
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *udpConn) Write(b []byte) (int, error) {
+	if c.remote == nil {
+		return 0, newError("UDP connection is not connected")
+	}
+	return c.WriteTo(b, c.remote)
+}
+
+// Close implements net.Conn.
+func (c *udpConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stack.access.Lock()
+		if c.stack.udpConns[c.key] == c {
+			delete(c.stack.udpConns, c.key)
+		}
+		c.stack.access.Unlock()
+		c.done.Close()
+	})
+	return nil
+}
+
+// LocalAddr implements net.Conn.
+func (c *udpConn) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: c.localIP, Port: int(c.localPort)}
+}
+
+// RemoteAddr implements net.Conn.
+func (c *udpConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return nil
+	}
+	return c.remote
+}
+
+// SetDeadline implements net.Conn.
+func (c *udpConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *udpConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn. Writes never block.
+func (c *udpConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,7 @@
+// Package wireguard contains an outbound that sends traffic through a WireGuard tunnel.
+//
+// The tunnel runs in userspace: TCP and UDP connections are carried by a minimal network stack over
+// the tunnel interface, so no privilege or TUN device is needed.
+package wireguard
+
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
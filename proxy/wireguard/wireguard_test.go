@@ -0,0 +1,190 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// lossyConn drops every 20th transport message it writes, and checks the reserved bytes of messages it reads.
+type lossyConn struct {
+	net.Conn
+	reserved []byte
+	writes   int32
+	invalid  int32
+}
+
+func (c *lossyConn) Write(b []byte) (int, error) {
+	if b[0] == messageTransportType && atomic.AddInt32(&c.writes, 1)%20 == 0 {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *lossyConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n >= 4 && !bytes.Equal(b[1:4], c.reserved) {
+		atomic.AddInt32(&c.invalid, 1)
+	}
+	return n, err
+}
+
+func pickUDPPort() net.Port {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	common.Must(err)
+	defer conn.Close()
+	return net.Port(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func newTestDevice(t *testing.T, config *Config, local, remote net.Port) (*Device, *lossyConn) {
+	conn := &lossyConn{reserved: config.Reserved}
+	device, err := NewDevice(config, func(net.Destination) (net.Conn, error) {
+		udpConn, err := net.DialUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(local)}, &net.UDPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(remote)})
+		if err != nil {
+			return nil, err
+		}
+		conn.Conn = udpConn
+		return conn, nil
+	})
+	common.Must(err)
+	return device, conn
+}
+
+func TestTunnel(t *testing.T) {
+	skA, err := newPrivateKey()
+	common.Must(err)
+	skB, err := newPrivateKey()
+	common.Must(err)
+	pkA, pkB := publicKey(&skA), publicKey(&skB)
+	psk := make([]byte, keySize)
+	common.Must2(rand.Read(psk))
+	portA, portB := pickUDPPort(), pickUDPPort()
+	reserved := []byte{1, 2, 3}
+
+	deviceA, connA := newTestDevice(t, &Config{
+		SecretKey: skA[:],
+		Address:   []string{"10.0.0.1", "fd00::1/128"},
+		Peers: []*PeerConfig{{
+			PublicKey:    pkB[:],
+			PreSharedKey: psk,
+			Endpoint:     "127.0.0.1:" + portB.String(),
+		}},
+		Reserved: reserved,
+	}, portA, portB)
+	defer deviceA.Close()
+	deviceB, connB := newTestDevice(t, &Config{
+		SecretKey: skB[:],
+		Address:   []string{"10.0.0.2/32", "fd00::2"},
+		Peers: []*PeerConfig{{
+			PublicKey:    pkA[:],
+			PreSharedKey: psk,
+			Endpoint:     "127.0.0.1:" + portA.String(),
+			AllowedIps:   []string{"10.0.0.1/32", "fd00::1/128"},
+		}},
+		Reserved: reserved,
+	}, portB, portA)
+	defer deviceB.Close()
+	// B only responds, so it has to listen before A sends anything.
+	common.Must2(deviceB.peers[0].getConn())
+
+	listener, err := deviceB.stack.listenTCP(80)
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	udpListener, err := deviceB.stack.listenUDP(net.ParseIP("10.0.0.2"), 53)
+	common.Must(err)
+	defer udpListener.Close()
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, addr, err := udpListener.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			udpListener.WriteTo(b[:n], addr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, test := range []struct {
+		address string
+		size    int
+	}{
+		{address: "10.0.0.2", size: 1024 * 1024},
+		{address: "fd00::2", size: 64 * 1024},
+	} {
+		conn, err := deviceA.stack.dialTCP(ctx, net.TCPDestination(net.ParseAddress(test.address), 80))
+		common.Must(err)
+		payload := make([]byte, test.size)
+		common.Must2(rand.Read(payload))
+		go func() {
+			common.Must2(conn.Write(payload))
+		}()
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+		response := make([]byte, len(payload))
+		common.Must2(io.ReadFull(conn, response))
+		if !bytes.Equal(payload, response) {
+			t.Error("expect echoed payload over ", test.address)
+		}
+		conn.Close()
+	}
+
+	conn, err := deviceA.stack.dialUDP(net.UDPDestination(net.ParseAddress("10.0.0.2"), 53))
+	common.Must(err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	common.Must2(conn.Write([]byte("hello")))
+	b := make([]byte, 16)
+	n, err := conn.Read(b)
+	common.Must(err)
+	if string(b[:n]) != "hello" {
+		t.Error("expect hello but got ", string(b[:n]))
+	}
+
+	if connA.invalid != 0 || connB.invalid != 0 {
+		t.Error("expect reserved bytes in all messages, but got ", connA.invalid+connB.invalid, " without")
+	}
+}
+
+func TestReplayFilter(t *testing.T) {
+	var filter replayFilter
+	for _, test := range []struct {
+		counter uint64
+		valid   bool
+	}{
+		{0, true},
+		{0, false},
+		{2, true},
+		{1, true},
+		{2, false},
+		{replayWindowSize + 10, true},
+		{5, false},
+		{replayWindowSize + 9, true},
+		{rejectAfterMessages, false},
+	} {
+		if valid := filter.validate(test.counter, rejectAfterMessages); valid != test.valid {
+			t.Error("expect counter ", test.counter, " to be valid: ", test.valid, " but got ", valid)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package scenarios
 
 import (
+	"bytes"
+	"crypto/rand"
 	"testing"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/eagleql/xray-core/app/reverse"
 	"github.com/eagleql/xray-core/app/router"
 	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/errors"
 	clog "github.com/eagleql/xray-core/common/log"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/protocol"
@@ -21,6 +25,7 @@ import (
 	"github.com/eagleql/xray-core/proxy/blackhole"
 	"github.com/eagleql/xray-core/proxy/dokodemo"
 	"github.com/eagleql/xray-core/proxy/freedom"
+	"github.com/eagleql/xray-core/proxy/socks"
 	"github.com/eagleql/xray-core/proxy/vmess"
 	"github.com/eagleql/xray-core/proxy/vmess/inbound"
 	"github.com/eagleql/xray-core/proxy/vmess/outbound"
@@ -198,6 +203,266 @@ func TestReverseProxy(t *testing.T) {
 	}
 }
 
+// startFullConeServer starts a UDP server that replies from another port, which only comes back through full-cone
+// NATs. It returns the address of the server and the port it replies from.
+func startFullConeServer() (net.Destination, net.Port, func()) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	replyConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+
+	go func() {
+		b := make([]byte, 2*1024)
+		for {
+			n, addr, err := conn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			if _, err := replyConn.WriteToUDP(xor(b[:n]), addr); err != nil {
+				return
+			}
+		}
+	}()
+
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	dest := net.UDPDestination(net.IPAddress(localAddr.IP), net.Port(localAddr.Port))
+	replyPort := net.Port(replyConn.LocalAddr().(*net.UDPAddr).Port)
+	return dest, replyPort, func() {
+		conn.Close()
+		replyConn.Close()
+	}
+}
+
+// testSocksUDPFullCone sends a packet to dest through the Socks server, and expects the reply to come back from
+// replyPort.
+func testSocksUDPFullCone(port net.Port, dest net.Destination, replyPort net.Port, timeout time.Duration) func() error {
+	return func() error {
+		conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{
+			IP:   []byte{127, 0, 0, 1},
+			Port: int(port),
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		udpRequest, err := socks.ClientHandshake(&protocol.RequestHeader{
+			Version: 5,
+			Command: protocol.RequestCommandUDP,
+		}, conn, conn)
+		if err != nil {
+			return err
+		}
+
+		udpConn, err := net.DialUDP("udp", nil, &net.UDPAddr{
+			IP:   udpRequest.Address.IP(),
+			Port: int(udpRequest.Port),
+		})
+		if err != nil {
+			return err
+		}
+		defer udpConn.Close()
+
+		payload := make([]byte, 1024)
+		common.Must2(rand.Read(payload))
+		packet, err := socks.EncodeUDPPacket(&protocol.RequestHeader{
+			Address: dest.Address,
+			Port:    dest.Port,
+		}, payload)
+		if err != nil {
+			return err
+		}
+		_, err = udpConn.Write(packet.Bytes())
+		packet.Release()
+		if err != nil {
+			return err
+		}
+
+		response := buf.New()
+		defer response.Release()
+		common.Must(udpConn.SetReadDeadline(time.Now().Add(timeout)))
+		if _, err := response.ReadFrom(udpConn); err != nil {
+			return err
+		}
+		header, err := socks.DecodeUDPPacket(response)
+		if err != nil {
+			return err
+		}
+		if header.Port != replyPort {
+			return errors.New("expect reply from port ", replyPort, " but got ", header.Port)
+		}
+		if !bytes.Equal(response.Bytes(), xor(payload)) {
+			return errors.New("unexpected reply payload")
+		}
+		return nil
+	}
+}
+
+func TestReverseProxyUDP(t *testing.T) {
+	dest, replyPort, closeServer := startFullConeServer()
+	defer closeServer()
+
+	userID := protocol.NewID(uuid.New())
+	externalPort := tcp.PickPort()
+	reversePort := tcp.PickPort()
+
+	serverConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&reverse.Config{
+				PortalConfig: []*reverse.PortalConfig{
+					{
+						Tag:    "portal",
+						Domain: "test.example.com",
+						Xudp:   true,
+					},
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						Domain: []*router.Domain{
+							{Type: router.Domain_Full, Value: "test.example.com"},
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "portal",
+						},
+					},
+					{
+						InboundTag: []string{"external"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "portal",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "external",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(externalPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{
+					AuthType:   socks.AuthType_NO_AUTH,
+					Address:    net.NewIPOrDomain(net.LocalHostIP),
+					UdpEnabled: true,
+				}),
+			},
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(reversePort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id:      userID.String(),
+								AlterId: 64,
+							}),
+						},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&reverse.Config{
+				BridgeConfig: []*reverse.BridgeConfig{
+					{
+						Tag:    "bridge",
+						Domain: "test.example.com",
+					},
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						Domain: []*router.Domain{
+							{Type: router.Domain_Full, Value: "test.example.com"},
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "reverse",
+						},
+					},
+					{
+						InboundTag: []string{"bridge"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "freedom",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(clientPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "freedom",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+			{
+				Tag: "reverse",
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Receiver: []*protocol.ServerEndpoint{
+						{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(reversePort),
+							User: []*protocol.User{
+								{
+									Account: serial.ToTypedMessage(&vmess.Account{
+										Id:      userID.String(),
+										AlterId: 64,
+										SecuritySettings: &protocol.SecurityConfig{
+											Type: protocol.SecurityType_AES128_GCM,
+										},
+									}),
+								},
+							},
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+
+	defer CloseAllServers(servers)
+
+	var errg errgroup.Group
+	for i := 0; i < 8; i++ {
+		errg.Go(testSocksUDPFullCone(externalPort, dest, replyPort, time.Second*10))
+	}
+
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestReverseProxyLongRunning(t *testing.T) {
 	tcpServer := tcp.Server{
 		MsgProcessor: xor,
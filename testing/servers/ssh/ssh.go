@@ -0,0 +1,164 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/eagleql/xray-core/common/net"
+)
+
+// Server is an SSH server that only serves direct-tcpip channels, by connecting to the requested addresses.
+type Server struct {
+	Port     net.Port
+	User     string
+	Password string
+	// AuthorizedKey is the public key the user may authenticate with.
+	AuthorizedKey ssh.PublicKey
+	// HostKey is the host key of the server. A new ed25519 key is generated if nil.
+	HostKey  ssh.Signer
+	Listen   net.Address
+	listener net.Listener
+
+	access sync.Mutex
+	conns  []*ssh.ServerConn
+}
+
+func (server *Server) Start() (net.Destination, error) {
+	if server.HostKey == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return net.Destination{}, err
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return net.Destination{}, err
+		}
+		server.HostKey = signer
+	}
+
+	config := &ssh.ServerConfig{}
+	if server.Password != "" {
+		config.PasswordCallback = func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == server.User && string(password) == server.Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid password for %s", meta.User())
+		}
+	}
+	if server.AuthorizedKey != nil {
+		config.PublicKeyCallback = func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == server.User && bytes.Equal(key.Marshal(), server.AuthorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid public key for %s", meta.User())
+		}
+	}
+	config.AddHostKey(server.HostKey)
+
+	listenerAddr := server.Listen
+	if listenerAddr == nil {
+		listenerAddr = net.LocalHostIP
+	}
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{
+		IP:   listenerAddr.IP(),
+		Port: int(server.Port),
+	})
+	if err != nil {
+		return net.Destination{}, err
+	}
+
+	localAddr := listener.Addr().(*net.TCPAddr)
+	server.Port = net.Port(localAddr.Port)
+	server.listener = listener
+	go server.acceptConnections(listener, config)
+
+	return net.TCPDestination(net.IPAddress(localAddr.IP), net.Port(localAddr.Port)), nil
+}
+
+// Connections returns the number of SSH connections accepted so far.
+func (server *Server) Connections() int {
+	server.access.Lock()
+	defer server.access.Unlock()
+
+	return len(server.conns)
+}
+
+func (server *Server) acceptConnections(listener net.Listener, config *ssh.ServerConfig) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			fmt.Printf("Failed accept TCP connection: %v\n", err)
+			return
+		}
+
+		go server.handleConnection(conn, config)
+	}
+}
+
+func (server *Server) handleConnection(conn net.Conn, config *ssh.ServerConfig) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		fmt.Println("failed to establish SSH connection: ", err.Error())
+		conn.Close()
+		return
+	}
+	server.access.Lock()
+	server.conns = append(server.conns, sshConn)
+	server.access.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		go handleDirectTCPIP(newChannel)
+	}
+}
+
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	// RFC 4254 7.2
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid payload")
+		return
+	}
+	target, err := net.Dial("tcp", net.TCPDestination(net.ParseAddress(payload.Host), net.Port(payload.Port)).NetAddr())
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(channel, target)
+		channel.Close()
+	}()
+	io.Copy(target, channel)
+	target.Close()
+}
+
+func (server *Server) Close() error {
+	server.access.Lock()
+	for _, conn := range server.conns {
+		conn.Close()
+	}
+	server.access.Unlock()
+	return server.listener.Close()
+}
@@ -0,0 +1,103 @@
+package reality
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/eagleql/xray-core/common/antireplay"
+	"github.com/eagleql/xray-core/transport/internet"
+)
+
+// certificateKey derives the key of the temporary certificate of a connection from its authentication key, so that
+// the certificates of different connections can't be linked to the same server.
+func certificateKey(key []byte) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	// 64 bits more than the order, so that the scalar is close to uniform after the reduction.
+	seed := make([]byte, 40)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte("REALITY certificate")), seed); err != nil {
+		return nil, err
+	}
+	one := big.NewInt(1)
+	d := new(big.Int).SetBytes(seed)
+	d.Mod(d, new(big.Int).Sub(curve.Params().N, one))
+	d.Add(d, one)
+
+	privateKey := &ecdsa.PrivateKey{D: d}
+	privateKey.Curve = curve
+	privateKey.X, privateKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+	return privateKey, nil
+}
+
+// temporaryCertificate creates a certificate for a connection with the authentication key, whose serial number is
+// the MAC of its public key.
+func temporaryCertificate(key []byte) (*ecdsa.PrivateKey, []byte, error) {
+	privateKey, err := certificateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKeyInfo, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: new(big.Int).SetBytes(certificateMAC(key, publicKeyInfo)),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	return privateKey, certificate, err
+}
+
+// maxTimeDiff returns the maximum time difference between clients and the server.
+func (c *Config) maxTimeDiff() time.Duration {
+	if c.MaxTimeDiff == 0 {
+		return defaultMaxTimeDiff
+	}
+	return time.Duration(c.MaxTimeDiff) * time.Millisecond
+}
+
+// NewReplayFilter returns a filter of the session IDs accepted by a server with the config. A session ID is remembered
+// for twice the maximum time difference, after which the time in it is rejected anyway.
+func (c *Config) NewReplayFilter() *antireplay.ReplayFilter {
+	return antireplay.NewReplayFilter(int64((2*c.maxTimeDiff() + time.Second - 1) / time.Second))
+}
+
+func (c *Config) isServerNameAllowed(serverName string) bool {
+	for _, name := range c.ServerNames {
+		if name == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) isShortIDAllowed(shortID []byte) bool {
+	for _, id := range c.ShortIds {
+		var padded [8]byte
+		copy(padded[:], id)
+		if string(padded[:]) == string(shortID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigFromStreamSettings returns the REALITY config in the stream settings, or nil if it doesn't exist.
+func ConfigFromStreamSettings(settings *internet.MemoryStreamConfig) *Config {
+	if settings == nil {
+		return nil
+	}
+	config, ok := settings.SecuritySettings.(*Config)
+	if !ok {
+		return nil
+	}
+	return config
+}
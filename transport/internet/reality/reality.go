@@ -0,0 +1,369 @@
+// Package reality implements a TLS security layer which presents the handshake of another site to anyone but the
+// clients holding the key of the server, so that the server doesn't need a certificate of its own.
+package reality
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	gotls "crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/eagleql/xray-core/common/antireplay"
+	"github.com/eagleql/xray-core/common/buf"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/signal"
+	"github.com/eagleql/xray-core/common/task"
+	"github.com/eagleql/xray-core/transport/internet"
+	"github.com/eagleql/xray-core/transport/internet/tls"
+)
+
+//go:generate go run github.com/eagleql/xray-core/common/errors/errorgen
+
+const (
+	// Version of the authentication in the session ID.
+	version = 1
+
+	handshakeTimeout = 8 * time.Second
+	// Maximum time difference between clients and the server, if it is not set in the config.
+	defaultMaxTimeDiff = 2 * time.Minute
+	// Timeout of a forwarded connection without traffic.
+	forwardIdleTimeout = 5 * time.Minute
+
+	recordHeaderSize        = 5
+	recordTypeHandshake     = 22
+	typeClientHello         = 1
+	extensionServerName     = 0
+	extensionKeyShare       = 51
+	sessionIDSize           = 32
+	sessionIDOffset         = 39 // Handshake header (4), version (2), random (32) and session ID length (1).
+	shortIDOffset           = 8  // Version (1), reserved (3) and time (4).
+	maxClientHelloSize      = 16384
+	curveX25519             = 29
+	curveX25519PublicKeyLen = 32
+)
+
+// clientHello is the part of a ClientHello used by the authentication.
+type clientHello struct {
+	raw        []byte
+	random     []byte
+	sessionID  []byte
+	serverName string
+	keyShare   []byte
+}
+
+// parseClientHello parses the handshake message of a ClientHello.
+func parseClientHello(raw []byte) (*clientHello, error) {
+	hello := &clientHello{raw: raw}
+	s := cryptobyte.String(raw)
+	var messageType uint8
+	var body, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&messageType) || messageType != typeClientHello || !s.ReadUint24LengthPrefixed(&body) ||
+		!body.Skip(2) || !body.ReadBytes(&hello.random, 32) ||
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) || !body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, newError("invalid ClientHello")
+	}
+	hello.sessionID = []byte(sessionID)
+
+	for !extensions.Empty() {
+		var extension uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&extension) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, newError("invalid ClientHello extensions")
+		}
+		switch extension {
+		case extensionServerName:
+			var names cryptobyte.String
+			if !data.ReadUint16LengthPrefixed(&names) {
+				return nil, newError("invalid server name extension")
+			}
+			for !names.Empty() {
+				var nameType uint8
+				var name cryptobyte.String
+				if !names.ReadUint8(&nameType) || !names.ReadUint16LengthPrefixed(&name) {
+					return nil, newError("invalid server name extension")
+				}
+				if nameType == 0 {
+					hello.serverName = string(name)
+				}
+			}
+		case extensionKeyShare:
+			var shares cryptobyte.String
+			if !data.ReadUint16LengthPrefixed(&shares) {
+				return nil, newError("invalid key share extension")
+			}
+			for !shares.Empty() {
+				var group uint16
+				var key cryptobyte.String
+				if !shares.ReadUint16(&group) || !shares.ReadUint16LengthPrefixed(&key) {
+					return nil, newError("invalid key share extension")
+				}
+				if group == curveX25519 && len(key) == curveX25519PublicKeyLen {
+					hello.keyShare = []byte(key)
+				}
+			}
+		}
+	}
+	return hello, nil
+}
+
+// authKey derives the key shared by the client and the server from the ECDH shared secret.
+func authKey(sharedSecret []byte, random []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, random[:20], []byte("REALITY")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+// certificateMAC returns the MAC of the public key of a temporary certificate, which is its serial number.
+func certificateMAC(key []byte, publicKeyInfo []byte) []byte {
+	h := hmac.New(sha512.New, key)
+	h.Write(publicKeyInfo)
+	return h.Sum(nil)[:16]
+}
+
+// authenticate returns the authentication key if the ClientHello is from an authenticated client, whose session ID
+// isn't in replayFilter.
+func (c *Config) authenticate(raw []byte, replayFilter *antireplay.ReplayFilter) ([]byte, error) {
+	hello, err := parseClientHello(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !c.isServerNameAllowed(hello.serverName) {
+		return nil, newError("server name not allowed: ", hello.serverName)
+	}
+	if len(hello.sessionID) != sessionIDSize || hello.keyShare == nil {
+		return nil, newError("no session ID or X25519 key share")
+	}
+
+	sharedSecret, err := curve25519.X25519(c.PrivateKey, hello.keyShare)
+	if err != nil {
+		return nil, err
+	}
+	key, err := authKey(sharedSecret, hello.random)
+	if err != nil {
+		return nil, err
+	}
+	aad := append([]byte(nil), raw...)
+	copy(aad[sessionIDOffset:sessionIDOffset+sessionIDSize], make([]byte, sessionIDSize))
+	plaintext, err := newAEAD(key).Open(nil, hello.random[20:], hello.sessionID, aad)
+	if err != nil {
+		return nil, newError("failed to decrypt session ID").Base(err)
+	}
+
+	if plaintext[0] != version {
+		return nil, newError("unsupported version ", plaintext[0])
+	}
+	diff := time.Since(time.Unix(int64(binary.BigEndian.Uint32(plaintext[4:])), 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > c.maxTimeDiff() {
+		return nil, newError("time difference too large: ", diff)
+	}
+	if !c.isShortIDAllowed(plaintext[shortIDOffset:]) {
+		return nil, newError("short ID not allowed")
+	}
+	// A replayed ClientHello would get the temporary certificate, which tells the server apart from the destination.
+	if !replayFilter.Check(hello.sessionID) {
+		return nil, newError("replayed session ID")
+	}
+	return key, nil
+}
+
+// readClientHello reads the first TLS record, which is expected to contain a ClientHello. It returns all the data read,
+// and the handshake message.
+func readClientHello(conn net.Conn) ([]byte, []byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if n, err := io.ReadFull(conn, header); err != nil {
+		return header[:n], nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[3:]))
+	if header[0] != recordTypeHandshake || length > maxClientHelloSize {
+		return header, nil, newError("not a TLS handshake")
+	}
+	record := make([]byte, recordHeaderSize+length)
+	copy(record, header)
+	if n, err := io.ReadFull(conn, record[recordHeaderSize:]); err != nil {
+		return record[:recordHeaderSize+n], nil, err
+	}
+	return record, record[recordHeaderSize:], nil
+}
+
+// prefixConn is a connection which returns the data read from it already before the rest.
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// forward relays the connection to the destination of the config, as if the server were the destination.
+func (c *Config) forward(conn net.Conn, read []byte) error {
+	defer conn.Close()
+
+	dest, err := net.ParseDestination("tcp:" + c.Dest)
+	if err != nil {
+		return newError("invalid destination ", c.Dest).Base(err)
+	}
+	dialCtx, cancelDial := context.WithTimeout(context.Background(), handshakeTimeout)
+	target, err := internet.DialSystem(dialCtx, dest, nil)
+	cancelDial()
+	if err != nil {
+		return newError("failed to dial ", dest).Base(err)
+	}
+	defer target.Close()
+
+	target.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	if _, err := target.Write(read); err != nil {
+		return err
+	}
+	target.SetWriteDeadline(time.Time{})
+
+	// The connections are closed once they are idle for too long, which interrupts the copying.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := signal.CancelAfterInactivity(ctx, cancel, forwardIdleTimeout)
+	request := func() error {
+		return buf.Copy(buf.NewReader(conn), buf.NewWriter(target), buf.UpdateActivity(timer))
+	}
+	response := func() error {
+		return buf.Copy(buf.NewReader(target), buf.NewWriter(conn), buf.UpdateActivity(timer))
+	}
+	// Like the connections between the client and the destination, they end when either side closes.
+	task.Run(ctx, task.OnSuccess(request, task.Close(target)), task.OnSuccess(response, task.Close(conn)))
+	return nil
+}
+
+// Server performs the server handshake on conn. Handshakes not from authenticated clients, or replayed as told by
+// replayFilter, are forwarded to the destination in the config, in which case an error is returned once the forwarding
+// ends. The filter is shared by the connections of a listener, and created by Config.NewReplayFilter.
+func Server(conn net.Conn, config *Config, replayFilter *antireplay.ReplayFilter) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	read, raw, err := readClientHello(conn)
+	var key []byte
+	if err == nil {
+		key, err = config.authenticate(raw, replayFilter)
+	}
+	if err != nil {
+		conn.SetReadDeadline(time.Time{})
+		if ferr := config.forward(conn, read); ferr != nil {
+			return nil, newError("failed to forward handshake").Base(ferr)
+		}
+		return nil, newError("forwarded handshake from ", conn.RemoteAddr()).Base(err)
+	}
+
+	privateKey, certificate, err := temporaryCertificate(key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConn := gotls.Server(&prefixConn{Conn: conn, prefix: read}, &gotls.Config{
+		Certificates: []gotls.Certificate{{
+			Certificate: [][]byte{certificate},
+			PrivateKey:  privateKey,
+		}},
+		MinVersion:             gotls.VersionTLS13,
+		SessionTicketsDisabled: true,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, newError("failed to handshake with ", conn.RemoteAddr()).Base(err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	return &tls.Conn{Conn: tlsConn}, nil
+}
+
+// UClient performs the client handshake on conn, with the authentication in the session ID of the ClientHello.
+func UClient(conn net.Conn, config *Config, dest net.Destination) (net.Conn, error) {
+	fingerprint, ok := tls.Fingerprints[config.Fingerprint]
+	if !ok {
+		fingerprint = tls.Fingerprints["chrome"]
+	}
+	serverName := config.ServerName
+	if serverName == "" && dest.Address.Family().IsDomain() {
+		serverName = dest.Address.Domain()
+	}
+
+	var key []byte
+	uConn := utls.UClient(conn, &utls.Config{
+		ServerName:             serverName,
+		InsecureSkipVerify:     true,
+		SessionTicketsDisabled: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return newError("no certificate")
+			}
+			certificate, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			mac := certificateMAC(key, certificate.RawSubjectPublicKeyInfo)
+			if certificate.SerialNumber.Cmp(new(big.Int).SetBytes(mac)) != 0 {
+				return newError("certificate not from the server")
+			}
+			return nil
+		},
+	}, *fingerprint)
+	if err := uConn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	ecdheParams := uConn.HandshakeState.State13.EcdheParams
+	if ecdheParams == nil || ecdheParams.CurveID() != utls.X25519 {
+		return nil, newError("fingerprint ", config.Fingerprint, " has no X25519 key share")
+	}
+	sharedSecret := ecdheParams.SharedKey(config.PublicKey)
+	if sharedSecret == nil {
+		return nil, newError("invalid public key")
+	}
+
+	hello := uConn.HandshakeState.Hello
+	hello.SessionId = make([]byte, sessionIDSize)
+	if err := uConn.MarshalClientHello(); err != nil {
+		return nil, err
+	}
+	var err error
+	if key, err = authKey(sharedSecret, hello.Random); err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, 16)
+	plaintext[0] = version
+	binary.BigEndian.PutUint32(plaintext[4:], uint32(time.Now().Unix()))
+	copy(plaintext[shortIDOffset:], config.ShortId)
+	// The handshake marshals the ClientHello again with the encrypted session ID.
+	newAEAD(key).Seal(hello.SessionId[:0], hello.Random[20:], plaintext, hello.Raw)
+
+	if err := uConn.Handshake(); err != nil {
+		return nil, newError("failed to handshake with ", dest).Base(err)
+	}
+	return &tls.UConn{UConn: uConn}, nil
+}
@@ -0,0 +1,176 @@
+package reality_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/net"
+	"github.com/eagleql/xray-core/common/protocol/tls/cert"
+	. "github.com/eagleql/xray-core/transport/internet/reality"
+)
+
+func echo(conn net.Conn) {
+	defer conn.Close()
+	io.Copy(conn, conn)
+}
+
+func listen(t *testing.T, handle func(net.Conn)) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return listener
+}
+
+func TestReality(t *testing.T) {
+	destCert, err := gotls.X509KeyPair(cert.MustGenerate(nil, cert.CommonName("dest.example.com")).ToPEM())
+	common.Must(err)
+	dest := listen(t, func(conn net.Conn) {
+		echo(gotls.Server(conn, &gotls.Config{Certificates: []gotls.Certificate{destCert}}))
+	})
+	defer dest.Close()
+
+	privateKey := make([]byte, 32)
+	common.Must2(rand.Read(privateKey))
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	common.Must(err)
+	serverConfig := &Config{
+		Dest:        dest.Addr().String(),
+		ServerNames: []string{"www.example.com"},
+		PrivateKey:  privateKey,
+		MaxTimeDiff: 60000,
+		ShortIds:    [][]byte{{0x12, 0x34}},
+	}
+	replayFilter := serverConfig.NewReplayFilter()
+	server := listen(t, func(conn net.Conn) {
+		if conn, err := Server(conn, serverConfig, replayFilter); err == nil {
+			echo(conn)
+		}
+	})
+	defer server.Close()
+	serverDest := net.DestinationFromAddr(server.Addr())
+
+	dial := func(shortID []byte) (net.Conn, error) {
+		conn, err := net.Dial("tcp", server.Addr().String())
+		common.Must(err)
+		return UClient(conn, &Config{
+			ServerName: "www.example.com",
+			PublicKey:  publicKey,
+			ShortId:    shortID,
+		}, serverDest)
+	}
+
+	conn, err := dial([]byte{0x12, 0x34})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, 1024)
+	common.Must2(rand.Read(payload))
+	common.Must2(conn.Write(payload))
+	response := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, response))
+	if !bytes.Equal(payload, response) {
+		t.Error("expect echoed payload")
+	}
+	conn.Close()
+
+	if conn, err := dial([]byte{0x56}); err == nil {
+		conn.Close()
+		t.Error("expect handshake with unknown short ID to fail")
+	}
+
+	// A probe gets the handshake of the destination.
+	rawConn, err := net.Dial("tcp", server.Addr().String())
+	common.Must(err)
+	probe := gotls.Client(rawConn, &gotls.Config{ServerName: "www.example.com", InsecureSkipVerify: true})
+	defer probe.Close()
+	common.Must(probe.Handshake())
+	if name := probe.ConnectionState().PeerCertificates[0].Subject.CommonName; name != "dest.example.com" {
+		t.Error("expect certificate of dest.example.com but got ", name)
+	}
+}
+
+// recordingConn records the data written to it.
+type recordingConn struct {
+	net.Conn
+	written []byte
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.written = append(c.written, b...)
+	return c.Conn.Write(b)
+}
+
+func TestRealityReplay(t *testing.T) {
+	forwarded := make(chan []byte, 1)
+	dest := listen(t, func(conn net.Conn) {
+		defer conn.Close()
+		header := make([]byte, 5)
+		common.Must2(io.ReadFull(conn, header))
+		record := make([]byte, 5+int(header[3])<<8|int(header[4]))
+		copy(record, header)
+		common.Must2(io.ReadFull(conn, record[5:]))
+		forwarded <- record
+	})
+	defer dest.Close()
+
+	privateKey := make([]byte, 32)
+	common.Must2(rand.Read(privateKey))
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	common.Must(err)
+	serverConfig := &Config{
+		Dest:        dest.Addr().String(),
+		ServerNames: []string{"www.example.com"},
+		PrivateKey:  privateKey,
+		ShortIds:    [][]byte{{0x12, 0x34}},
+	}
+	replayFilter := serverConfig.NewReplayFilter()
+	server := listen(t, func(conn net.Conn) {
+		if conn, err := Server(conn, serverConfig, replayFilter); err == nil {
+			echo(conn)
+		}
+	})
+	defer server.Close()
+
+	rawConn, err := net.Dial("tcp", server.Addr().String())
+	common.Must(err)
+	recorder := &recordingConn{Conn: rawConn}
+	conn, err := UClient(recorder, &Config{
+		ServerName: "www.example.com",
+		PublicKey:  publicKey,
+		ShortId:    []byte{0x12, 0x34},
+	}, net.DestinationFromAddr(server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	hello := recorder.written[:5+int(recorder.written[3])<<8|int(recorder.written[4])]
+
+	// The replayed ClientHello is forwarded to the destination as if it were from a probe.
+	replay, err := net.Dial("tcp", server.Addr().String())
+	common.Must(err)
+	defer replay.Close()
+	common.Must2(replay.Write(hello))
+	select {
+	case b := <-forwarded:
+		if !bytes.Equal(b, hello) {
+			t.Error("expect the replayed ClientHello to be forwarded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expect the replayed ClientHello to be forwarded")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/transport/internet"
+	"github.com/eagleql/xray-core/transport/internet/reality"
 	"github.com/eagleql/xray-core/transport/internet/tls"
 	"github.com/eagleql/xray-core/transport/internet/xtls"
 )
@@ -32,6 +33,10 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	} else if config := xtls.ConfigFromStreamSettings(streamSettings); config != nil {
 		xtlsConfig := config.GetXTLSConfig(xtls.WithDestination(dest))
 		conn = xtls.Client(conn, xtlsConfig)
+	} else if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
+		if conn, err = reality.UClient(conn, config, dest); err != nil {
+			return nil, err
+		}
 	}
 
 	tcpSettings := streamSettings.ProtocolSettings.(*Config)
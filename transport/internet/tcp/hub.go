@@ -9,22 +9,26 @@ import (
 	goxtls "github.com/xtls/go"
 
 	"github.com/eagleql/xray-core/common"
+	"github.com/eagleql/xray-core/common/antireplay"
 	"github.com/eagleql/xray-core/common/net"
 	"github.com/eagleql/xray-core/common/session"
 	"github.com/eagleql/xray-core/transport/internet"
+	"github.com/eagleql/xray-core/transport/internet/reality"
 	"github.com/eagleql/xray-core/transport/internet/tls"
 	"github.com/eagleql/xray-core/transport/internet/xtls"
 )
 
 // Listener is an internet.Listener that listens for TCP connections.
 type Listener struct {
-	listener   net.Listener
-	tlsConfig  *gotls.Config
-	xtlsConfig *goxtls.Config
-	authConfig internet.ConnectionAuthenticator
-	config     *Config
-	addConn    internet.ConnHandler
-	locker     *internet.FileLocker // for unix domain socket
+	listener            net.Listener
+	tlsConfig           *gotls.Config
+	xtlsConfig          *goxtls.Config
+	reality             *reality.Config
+	realityReplayFilter *antireplay.ReplayFilter
+	authConfig          internet.ConnectionAuthenticator
+	config              *Config
+	addConn             internet.ConnHandler
+	locker              *internet.FileLocker // for unix domain socket
 }
 
 // ListenTCP creates a new Listener based on configurations.
@@ -79,6 +83,10 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 	if config := xtls.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.xtlsConfig = config.GetXTLSConfig()
 	}
+	if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
+		l.reality = config
+		l.realityReplayFilter = config.NewReplayFilter()
+	}
 
 	if tcpSettings.HeaderSettings != nil {
 		headerConfig, err := tcpSettings.HeaderSettings.GetInstance()
@@ -111,6 +119,19 @@ func (v *Listener) keepAccepting() {
 			continue
 		}
 
+		if v.reality != nil {
+			// The handshake may be forwarded for long, so it doesn't block accepting.
+			go func(conn net.Conn) {
+				conn, err := reality.Server(conn, v.reality, v.realityReplayFilter)
+				if err != nil {
+					newError("REALITY handshake not established").Base(err).AtInfo().WriteToLog()
+					return
+				}
+				v.addConn(internet.Connection(conn))
+			}(conn)
+			continue
+		}
+
 		if v.tlsConfig != nil {
 			conn = tls.Server(conn, v.tlsConfig)
 		} else if v.xtlsConfig != nil {
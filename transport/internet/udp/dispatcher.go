@@ -51,6 +51,21 @@ func (v *Dispatcher) RemoveRay(dest net.Destination) {
 	}
 }
 
+// Close closes all connections of the dispatcher.
+func (v *Dispatcher) Close() error {
+	v.RLock()
+	entries := make([]*connEntry, 0, len(v.conns))
+	for _, entry := range v.conns {
+		entries = append(entries, entry)
+	}
+	v.RUnlock()
+
+	for _, entry := range entries {
+		entry.cancel()
+	}
+	return nil
+}
+
 func (v *Dispatcher) getInboundRay(ctx context.Context, dest net.Destination) *connEntry {
 	v.Lock()
 	defer v.Unlock()
@@ -23,3 +23,8 @@ func (w *Writer) Close() error {
 func (w *Writer) Interrupt() {
 	w.pipe.Interrupt()
 }
+
+// Done returns a channel that is closed when the pipe is closed or interrupted.
+func (w *Writer) Done() <-chan struct{} {
+	return w.pipe.done.Wait()
+}